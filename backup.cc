@@ -0,0 +1,17 @@
+// BackupEngine::DeleteBackup through RocksDB's C++ API, which the C API
+// does not expose.
+
+#include <stdlib.h>
+#include <string.h>
+
+#include "cinternal.h"
+
+// gorocks_backup_engine_delete_backup deletes the backup with the given ID,
+// setting *errptr on error.
+extern "C" void gorocks_backup_engine_delete_backup(rocksdb_backup_engine_t* be,
+		uint32_t backup_id, char** errptr) {
+	rocksdb::IOStatus s = be->rep->DeleteBackup(backup_id);
+	if (!s.ok()) {
+		*errptr = strdup(s.ToString().c_str());
+	}
+}
@@ -0,0 +1,324 @@
+package gorocks
+
+/*
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern void gorocks_backup_engine_delete_backup(rocksdb_backup_engine_t* be, uint32_t backup_id, char** errptr);
+*/
+import "C"
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+)
+
+// BackupEngine makes incremental backups of databases into a backup
+// directory, sharing unchanged SST files between backups. It is created by
+// OpenBackupEngine.
+//
+// To prevent memory leaks, Close must be called on a BackupEngine when the
+// program no longer needs it.
+type BackupEngine struct {
+	Engine *C.rocksdb_backup_engine_t
+
+	path string
+	// env is owned by the BackupEngine and freed by Close, if set.
+	env *Env
+}
+
+// BackupInfo describes one backup held by a BackupEngine.
+type BackupInfo struct {
+	ID        uint32
+	Timestamp time.Time
+	// Size is the total size in bytes of the files of the backup,
+	// including those shared with other backups.
+	Size     uint64
+	NumFiles uint32
+}
+
+// OpenBackupEngine opens the backups kept in the directory at path,
+// creating it if needed. o provides the Env and logger used.
+func OpenBackupEngine(o *Options, path string) (*BackupEngine, error) {
+	var errStr *C.char
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	be := C.rocksdb_backup_engine_open(o.Opt, cpath, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	return &BackupEngine{Engine: be, path: path}, nil
+}
+
+// OpenBackupEngineWithOptions is like OpenBackupEngine, but configured with
+// bo, which also names the backup directory. env may be nil to use the
+// default Env; otherwise it must stay open as long as the BackupEngine.
+func OpenBackupEngineWithOptions(bo *BackupEngineOptions, env *Env) (*BackupEngine, error) {
+	var owned *Env
+	if env == nil {
+		owned = NewDefaultEnv()
+		env = owned
+	}
+	var errStr *C.char
+	be := C.rocksdb_backup_engine_open_opts(bo.Opt, env.Env, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		if owned != nil {
+			owned.Close()
+		}
+		return nil, DatabaseError(gs)
+	}
+	return &BackupEngine{Engine: be, path: bo.path, env: owned}, nil
+}
+
+// CreateNewBackup backs up the current state of db. If flushBeforeBackup is
+// true, the memtables are flushed first, so the backup needs no write ahead
+// log files.
+func (be *BackupEngine) CreateNewBackup(db *DB, flushBeforeBackup bool) error {
+	var errStr *C.char
+	C.rocksdb_backup_engine_create_new_backup_flush(be.Engine, db.Ldb, boolToUchar(flushBeforeBackup), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// GetBackupInfo returns the backups currently held, oldest first.
+func (be *BackupEngine) GetBackupInfo() []BackupInfo {
+	info := C.rocksdb_backup_engine_get_backup_info(be.Engine)
+	defer C.rocksdb_backup_engine_info_destroy(info)
+
+	n := int(C.rocksdb_backup_engine_info_count(info))
+	backups := make([]BackupInfo, n)
+	for i := range backups {
+		ci := C.int(i)
+		backups[i] = BackupInfo{
+			ID:        uint32(C.rocksdb_backup_engine_info_backup_id(info, ci)),
+			Timestamp: time.Unix(int64(C.rocksdb_backup_engine_info_timestamp(info, ci)), 0),
+			Size:      uint64(C.rocksdb_backup_engine_info_size(info, ci)),
+			NumFiles:  uint32(C.rocksdb_backup_engine_info_number_files(info, ci)),
+		}
+	}
+	return backups
+}
+
+// PurgeOldBackups deletes all but the numToKeep most recent backups, along
+// with the files no remaining backup uses.
+func (be *BackupEngine) PurgeOldBackups(numToKeep uint32) error {
+	var errStr *C.char
+	C.rocksdb_backup_engine_purge_old_backups(be.Engine, C.uint32_t(numToKeep), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// DeleteBackup deletes the backup with the given ID, along with the files
+// no remaining backup uses. Unlike PurgeOldBackups, it can remove any
+// backup, for example one that failed VerifyBackup.
+func (be *BackupEngine) DeleteBackup(id uint32) error {
+	var errStr *C.char
+	C.gorocks_backup_engine_delete_backup(be.Engine, C.uint32_t(id), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// VerifyBackup checks that all the files of the backup with the given ID
+// exist and have the expected sizes.
+func (be *BackupEngine) VerifyBackup(id uint32) error {
+	var errStr *C.char
+	C.rocksdb_backup_engine_verify_backup(be.Engine, C.uint32_t(id), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// RestoreDBFromBackup restores the backup with the given ID into dbDir,
+// with its write ahead log in walDir, which is usually the same directory.
+// The database must not be open. ro may be nil to use the default
+// RestoreOptions.
+func (be *BackupEngine) RestoreDBFromBackup(id uint32, dbDir, walDir string, ro *RestoreOptions) error {
+	return be.restore(&id, dbDir, walDir, ro)
+}
+
+// RestoreDBFromLatestBackup is like RestoreDBFromBackup, but restores the
+// most recent backup.
+func (be *BackupEngine) RestoreDBFromLatestBackup(dbDir, walDir string, ro *RestoreOptions) error {
+	return be.restore(nil, dbDir, walDir, ro)
+}
+
+func (be *BackupEngine) restore(id *uint32, dbDir, walDir string, ro *RestoreOptions) error {
+	if ro == nil {
+		ro = NewRestoreOptions()
+		defer ro.Close()
+	}
+	var errStr *C.char
+	cdbDir := C.CString(dbDir)
+	defer C.free(unsafe.Pointer(cdbDir))
+	cwalDir := C.CString(walDir)
+	defer C.free(unsafe.Pointer(cwalDir))
+
+	if id == nil {
+		C.rocksdb_backup_engine_restore_db_from_latest_backup(be.Engine, cdbDir, cwalDir, ro.Opt, &errStr)
+	} else {
+		C.rocksdb_backup_engine_restore_db_from_backup(be.Engine, cdbDir, cwalDir, ro.Opt, C.uint32_t(*id), &errStr)
+	}
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// BackupProgress reports how far a backup has come.
+type BackupProgress struct {
+	// BytesCopied and FilesCopied count the data added to the backup
+	// directory so far. Files shared with earlier backups are not copied
+	// again and do not count.
+	BytesCopied int64
+	FilesCopied int
+}
+
+// CreateNewBackupWithProgress is like CreateNewBackup, but calls progress
+// every interval while the backup runs, and once more when it has
+// completed. Progress is measured by watching the backup directory grow.
+func (be *BackupEngine) CreateNewBackupWithProgress(db *DB, flushBeforeBackup bool, interval time.Duration, progress func(BackupProgress)) error {
+	baseBytes, baseFiles := dirUsage(be.path)
+	report := func() {
+		bytes, files := dirUsage(be.path)
+		progress(BackupProgress{BytesCopied: bytes - baseBytes, FilesCopied: files - baseFiles})
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- be.CreateNewBackup(db, flushBeforeBackup)
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				report()
+			}
+			return err
+		case <-ticker.C:
+			report()
+		}
+	}
+}
+
+// dirUsage returns the total size and number of the regular files under
+// dir. Files that vanish while it runs are skipped.
+func dirUsage(dir string) (bytes int64, files int) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			bytes += info.Size()
+			files++
+		}
+		return nil
+	})
+	return bytes, files
+}
+
+// Close deallocates the BackupEngine, freeing its underlying C struct.
+func (be *BackupEngine) Close() {
+	C.rocksdb_backup_engine_close(be.Engine)
+	if be.env != nil {
+		be.env.Close()
+	}
+}
+
+// BackupEngineOptions represent the options for opening a BackupEngine with
+// OpenBackupEngineWithOptions.
+//
+// To prevent memory leaks, Close must be called on a BackupEngineOptions
+// when the program no longer needs it.
+type BackupEngineOptions struct {
+	Opt *C.rocksdb_backup_engine_options_t
+
+	path string
+}
+
+// NewBackupEngineOptions allocates a new BackupEngineOptions for the backups
+// kept in the directory at path, with the default settings.
+func NewBackupEngineOptions(path string) *BackupEngineOptions {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	return &BackupEngineOptions{Opt: C.rocksdb_backup_engine_options_create(cpath), path: path}
+}
+
+// SetBackupRateLimit caps the rate at which backups copy data, in bytes
+// per second, so that large backups do not starve foreground traffic of
+// disk bandwidth. Zero means no limit.
+func (bo *BackupEngineOptions) SetBackupRateLimit(bytesPerSecond uint64) {
+	C.rocksdb_backup_engine_options_set_backup_rate_limit(bo.Opt, C.uint64_t(bytesPerSecond))
+}
+
+// SetRestoreRateLimit is like SetBackupRateLimit, but for restores.
+func (bo *BackupEngineOptions) SetRestoreRateLimit(bytesPerSecond uint64) {
+	C.rocksdb_backup_engine_options_set_restore_rate_limit(bo.Opt, C.uint64_t(bytesPerSecond))
+}
+
+// SetMaxBackgroundOperations sets the number of files copied in parallel.
+func (bo *BackupEngineOptions) SetMaxBackgroundOperations(n int) {
+	C.rocksdb_backup_engine_options_set_max_background_operations(bo.Opt, C.int(n))
+}
+
+// SetSync, if true, makes the BackupEngine sync the files it writes, so
+// that backups survive a machine crash.
+func (bo *BackupEngineOptions) SetSync(b bool) {
+	C.rocksdb_backup_engine_options_set_sync(bo.Opt, boolToUchar(b))
+}
+
+// Close deallocates the BackupEngineOptions, freeing its underlying C
+// struct.
+func (bo *BackupEngineOptions) Close() {
+	C.rocksdb_backup_engine_options_destroy(bo.Opt)
+}
+
+// RestoreOptions represent the options for restoring a backup with a
+// BackupEngine.
+//
+// To prevent memory leaks, Close must be called on a RestoreOptions when the
+// program no longer needs it.
+type RestoreOptions struct {
+	Opt *C.rocksdb_restore_options_t
+}
+
+// NewRestoreOptions allocates a new RestoreOptions with the default
+// settings.
+func NewRestoreOptions() *RestoreOptions {
+	return &RestoreOptions{C.rocksdb_restore_options_create()}
+}
+
+// SetKeepLogFiles, if true, leaves the write ahead log files already in the
+// WAL directory in place instead of deleting them, so that a backup taken
+// without flushing can be restored together with the newer logs of the
+// original database.
+func (ro *RestoreOptions) SetKeepLogFiles(b bool) {
+	C.rocksdb_restore_options_set_keep_log_files(ro.Opt, boolToInt(b))
+}
+
+// Close deallocates the RestoreOptions, freeing its underlying C struct.
+func (ro *RestoreOptions) Close() {
+	C.rocksdb_restore_options_destroy(ro.Opt)
+}
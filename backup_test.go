@@ -0,0 +1,169 @@
+package gorocks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackupEngine(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	backupDir := tempDir(t)
+	defer deleteDBDirectory(t, backupDir)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	be, err := OpenBackupEngine(options, backupDir)
+	if err != nil {
+		t.Fatalf("Backup engine could not be opened: %v", err)
+	}
+	defer be.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		db.Put(wo, []byte(k), []byte(k))
+		if err := be.CreateNewBackup(db, true); err != nil {
+			t.Fatalf("CreateNewBackup failed: %v", err)
+		}
+	}
+	backups := be.GetBackupInfo()
+	if len(backups) != 3 {
+		t.Fatalf("expected 3 backups, got %d", len(backups))
+	}
+	for _, b := range backups {
+		if b.Size == 0 || b.NumFiles == 0 || b.Timestamp.IsZero() {
+			t.Errorf("incomplete backup info %+v", b)
+		}
+		if err := be.VerifyBackup(b.ID); err != nil {
+			t.Errorf("VerifyBackup(%d) failed: %v", b.ID, err)
+		}
+	}
+
+	if err := be.DeleteBackup(backups[1].ID); err != nil {
+		t.Fatalf("DeleteBackup failed: %v", err)
+	}
+	if n := len(be.GetBackupInfo()); n != 2 {
+		t.Errorf("expected 2 backups after DeleteBackup, got %d", n)
+	}
+	if err := be.VerifyBackup(backups[1].ID); err == nil {
+		t.Errorf("VerifyBackup of a deleted backup should fail")
+	}
+	if err := be.DeleteBackup(backups[1].ID); err == nil {
+		t.Errorf("deleting a backup twice should fail")
+	}
+
+	if err := be.PurgeOldBackups(1); err != nil {
+		t.Fatalf("PurgeOldBackups failed: %v", err)
+	}
+	remaining := be.GetBackupInfo()
+	if len(remaining) != 1 || remaining[0].ID != backups[2].ID {
+		t.Errorf("expected only the latest backup to remain, got %+v", remaining)
+	}
+	if err := be.VerifyBackup(backups[0].ID); err == nil {
+		t.Errorf("VerifyBackup of a purged backup should fail")
+	}
+}
+
+func TestRestoreDBFromBackup(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	backupDir := tempDir(t)
+	defer deleteDBDirectory(t, backupDir)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	be, err := OpenBackupEngine(options, backupDir)
+	if err != nil {
+		t.Fatalf("Backup engine could not be opened: %v", err)
+	}
+	defer be.Close()
+
+	for _, v := range []string{"1", "2", "3"} {
+		db.Put(wo, []byte("key"), []byte(v))
+		if err := be.CreateNewBackup(db, true); err != nil {
+			t.Fatalf("CreateNewBackup failed: %v", err)
+		}
+	}
+	db.Close()
+	backups := be.GetBackupInfo()
+
+	restoreOpts := NewRestoreOptions()
+	defer restoreOpts.Close()
+	if err := be.RestoreDBFromBackup(backups[1].ID, dbname, dbname, restoreOpts); err != nil {
+		t.Fatalf("RestoreDBFromBackup failed: %v", err)
+	}
+	db, err = Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Restored database could not be opened: %v", err)
+	}
+	CheckGet(t, "restored backup", db, ro, []byte("key"), []byte("2"))
+	db.Close()
+
+	if err := be.RestoreDBFromLatestBackup(dbname, dbname, nil); err != nil {
+		t.Fatalf("RestoreDBFromLatestBackup failed: %v", err)
+	}
+	db, err = Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Restored database could not be opened: %v", err)
+	}
+	defer db.Close()
+	CheckGet(t, "latest backup", db, ro, []byte("key"), []byte("3"))
+}
+
+func TestBackupProgress(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	backupDir := tempDir(t)
+	defer deleteDBDirectory(t, backupDir)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	bo := NewBackupEngineOptions(backupDir)
+	bo.SetBackupRateLimit(64 << 20)
+	defer bo.Close()
+	be, err := OpenBackupEngineWithOptions(bo, nil)
+	if err != nil {
+		t.Fatalf("Backup engine could not be opened: %v", err)
+	}
+	defer be.Close()
+
+	for i := 0; i < 16; i++ {
+		db.Put(wo, []byte{byte(i)}, make([]byte, 64<<10))
+	}
+	var last BackupProgress
+	var calls int
+	err = be.CreateNewBackupWithProgress(db, true, time.Millisecond, func(p BackupProgress) {
+		if p.BytesCopied < last.BytesCopied {
+			t.Errorf("progress went backwards: %+v after %+v", p, last)
+		}
+		last = p
+		calls++
+	})
+	if err != nil {
+		t.Fatalf("CreateNewBackupWithProgress failed: %v", err)
+	}
+	if calls == 0 || last.BytesCopied == 0 || last.FilesCopied == 0 {
+		t.Errorf("unexpected final progress %+v after %d calls", last, calls)
+	}
+}
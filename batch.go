@@ -57,13 +57,105 @@ func (w *WriteBatch) Put(key, value []byte) {
 	C.rocksdb_writebatch_put(w.wbatch, k, C.size_t(lenk), v, C.size_t(lenv))
 }
 
+// Merge queues a merge operand for key, as DB.Merge does.
+func (w *WriteBatch) Merge(key, value []byte) {
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+	C.rocksdb_writebatch_merge(w.wbatch, k, C.size_t(len(key)), v, C.size_t(len(value)))
+}
+
 // Delete queues a deletion of the data at key to be deleted later.
 //
 // The key byte slice may be reused safely. Delete takes a copy of
 // them before returning.
 func (w *WriteBatch) Delete(key []byte) {
-	C.rocksdb_writebatch_delete(w.wbatch,
-		(*C.char)(unsafe.Pointer(&key[0])), C.size_t(len(key)))
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	C.rocksdb_writebatch_delete(w.wbatch, k, C.size_t(len(key)))
+}
+
+// PutCF is like Put, but the key-value pair is written to the given column
+// family.
+func (w *WriteBatch) PutCF(cf *ColumnFamilyHandle, key, value []byte) {
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+	C.rocksdb_writebatch_put_cf(w.wbatch, cf.Handle,
+		k, C.size_t(len(key)), v, C.size_t(len(value)))
+}
+
+// MergeCF is like Merge, but the merge operand is written to the given
+// column family.
+func (w *WriteBatch) MergeCF(cf *ColumnFamilyHandle, key, value []byte) {
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+	C.rocksdb_writebatch_merge_cf(w.wbatch, cf.Handle,
+		k, C.size_t(len(key)), v, C.size_t(len(value)))
+}
+
+// DeleteCF is like Delete, but the key is deleted from the given column
+// family.
+func (w *WriteBatch) DeleteCF(cf *ColumnFamilyHandle, key []byte) {
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	C.rocksdb_writebatch_delete_cf(w.wbatch, cf.Handle, k, C.size_t(len(key)))
+}
+
+// DeleteRange queues a deletion of all keys from start up to, but not
+// including, end, as DB.DeleteRange does.
+func (w *WriteBatch) DeleteRange(start, end []byte) {
+	var s, e *C.char
+	if len(start) != 0 {
+		s = (*C.char)(unsafe.Pointer(&start[0]))
+	}
+	if len(end) != 0 {
+		e = (*C.char)(unsafe.Pointer(&end[0]))
+	}
+	C.rocksdb_writebatch_delete_range(w.wbatch, s, C.size_t(len(start)), e, C.size_t(len(end)))
+}
+
+// DeleteRangeCF is like DeleteRange, but the keys are deleted from the
+// given column family.
+func (w *WriteBatch) DeleteRangeCF(cf *ColumnFamilyHandle, start, end []byte) {
+	var s, e *C.char
+	if len(start) != 0 {
+		s = (*C.char)(unsafe.Pointer(&start[0]))
+	}
+	if len(end) != 0 {
+		e = (*C.char)(unsafe.Pointer(&end[0]))
+	}
+	C.rocksdb_writebatch_delete_range_cf(w.wbatch, cf.Handle,
+		s, C.size_t(len(start)), e, C.size_t(len(end)))
+}
+
+// PutLogData adds blob to the WriteBatch. It is not stored in the database,
+// but is written to the write ahead log along with the rest of the batch,
+// where it can be read back by anyone decoding the batch, such as a
+// WriteHook. It does not count towards Count.
+func (w *WriteBatch) PutLogData(blob []byte) {
+	var b *C.char
+	if len(blob) != 0 {
+		b = (*C.char)(unsafe.Pointer(&blob[0]))
+	}
+	C.rocksdb_writebatch_put_log_data(w.wbatch, b, C.size_t(len(blob)))
 }
 
 // Clear removes all the enqueued Put and Deletes in the WriteBatch.
@@ -89,6 +181,10 @@ type WriteBatchIterator struct {
 	data   []byte
 	record Record
 	err    error
+
+	// schema is the last SchemaTag found in the batch so far.
+	schema    SchemaTag
+	hasSchema bool
 }
 
 type RecordType byte
@@ -98,12 +194,26 @@ const (
 	RecordTypeValue    RecordType = 0x1
 	RecordTypeMerge    RecordType = 0x2
 	RecordTypeLogData  RecordType = 0x3
+	// RecordTypeRangeDeletion records have the start of the deleted range
+	// as Key and its exclusive end as Value.
+	RecordTypeRangeDeletion RecordType = 0xF
+
+	// Records targeting a column family other than the default one are
+	// reported with the plain types above and their ColumnFamilyID set.
+	recordTypeColumnFamilyDeletion      RecordType = 0x4
+	recordTypeColumnFamilyValue         RecordType = 0x5
+	recordTypeColumnFamilyMerge         RecordType = 0x6
+	recordTypeColumnFamilyRangeDeletion RecordType = 0xE
 )
 
 type Record struct {
 	Key   []byte
 	Value []byte
 	Type  RecordType
+
+	// ColumnFamilyID is the ID of the column family the record applies to,
+	// 0 for the default column family.
+	ColumnFamilyID uint32
 }
 
 // WriteBatch::rep_ :=
@@ -114,6 +224,12 @@ type Record struct {
 //    kTypeValue varstring varstring
 //    kTypeMerge varstring varstring
 //    kTypeDeletion varstring
+//    kTypeLogData varstring
+//    kTypeRangeDeletion varstring varstring
+//    kTypeColumnFamilyValue varint32 varstring varstring
+//    kTypeColumnFamilyMerge varint32 varstring varstring
+//    kTypeColumnFamilyDeletion varint32 varstring
+//    kTypeColumnFamilyRangeDeletion varint32 varstring varstring
 // varstring :=
 //    len: varint32
 //    data: uint8[len]
@@ -133,38 +249,69 @@ func (this *WriteBatchIterator) Next() bool {
 
 	this.record.Key = nil
 	this.record.Value = nil
+	this.record.ColumnFamilyID = 0
 
 	recordType := RecordType(this.data[0])
-	this.record.Type = recordType
 	this.data = this.data[1:]
 
-	x, n := decodeVarint(this.data)
-	if n == 0 {
-		this.err = io.ErrShortBuffer
-		return false
-	}
-	k := n + int(x)
-	this.record.Key = this.data[n:k]
-	this.data = this.data[k:]
-
-	if recordType == RecordTypeValue || recordType == RecordTypeMerge {
+	switch recordType {
+	case recordTypeColumnFamilyDeletion, recordTypeColumnFamilyValue, recordTypeColumnFamilyMerge,
+		recordTypeColumnFamilyRangeDeletion:
 		x, n := decodeVarint(this.data)
 		if n == 0 {
 			this.err = io.ErrShortBuffer
 			return false
 		}
-		k := n + int(x)
-		this.record.Value = this.data[n:k]
-		this.data = this.data[k:]
+		this.record.ColumnFamilyID = uint32(x)
+		this.data = this.data[n:]
+		if recordType == recordTypeColumnFamilyRangeDeletion {
+			recordType = RecordTypeRangeDeletion
+		} else {
+			recordType -= recordTypeColumnFamilyDeletion - RecordTypeDeletion
+		}
+	}
+	this.record.Type = recordType
+
+	if this.record.Key = this.varstring(); this.err != nil {
+		return false
+	}
+	if recordType == RecordTypeValue || recordType == RecordTypeMerge || recordType == RecordTypeRangeDeletion {
+		if this.record.Value = this.varstring(); this.err != nil {
+			return false
+		}
+	}
+	if recordType == RecordTypeLogData {
+		if tag, ok := DecodeSchemaTag(this.record.Key); ok {
+			this.schema, this.hasSchema = tag, true
+		}
 	}
 
 	return true
 }
 
+func (this *WriteBatchIterator) varstring() []byte {
+	x, n := decodeVarint(this.data)
+	if n == 0 || uint64(len(this.data)-n) < x {
+		this.err = io.ErrShortBuffer
+		return nil
+	}
+	k := n + int(x)
+	s := this.data[n:k]
+	this.data = this.data[k:]
+	return s
+}
+
 func (this *WriteBatchIterator) Record() *Record {
 	return &this.record
 }
 
+// Schema returns the SchemaTag in effect for the current record, that is
+// the last one put into the batch before it, including the current record
+// itself if it is the tag. ok is false if no tag has been seen yet.
+func (this *WriteBatchIterator) Schema() (tag SchemaTag, ok bool) {
+	return this.schema, this.hasSchema
+}
+
 func (this *WriteBatchIterator) Error() error {
 	return this.err
 }
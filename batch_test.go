@@ -2,6 +2,7 @@ package gorocks
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -68,3 +69,93 @@ func TestWriteBatchIterator(t *testing.T) {
 		t.Fatalf("value bytes missing: expected %v, got %v", n, vb)
 	}
 }
+
+func TestWriteBatchCheckLimits(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Put([]byte("key"), make([]byte, 100))
+	wb.Delete([]byte("other"))
+
+	if err := wb.CheckLimits(WriteLimits{}); err != nil {
+		t.Errorf("zero limits should accept any batch: %v", err)
+	}
+	if err := wb.CheckLimits(WriteLimits{MaxBatchBytes: 1 << 10, MaxBatchOps: 2}); err != nil {
+		t.Errorf("batch within limits was rejected: %v", err)
+	}
+	err := wb.CheckLimits(WriteLimits{MaxBatchOps: 1})
+	if !errors.Is(err, ErrBatchTooLarge) {
+		t.Fatalf("expected ErrBatchTooLarge, got %v", err)
+	}
+	if e := err.(*BatchTooLargeError); e.Ops != 2 {
+		t.Errorf("expected 2 ops in error, got %d", e.Ops)
+	}
+	if err := wb.CheckLimits(WriteLimits{MaxBatchBytes: 100}); err == nil {
+		t.Errorf("batch over the byte limit was accepted")
+	}
+}
+
+func TestWriteBatchSchemaTag(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Put([]byte("untagged"), []byte("v"))
+	wb.PutSchemaTag(SchemaTag{"user", 2})
+	wb.Put([]byte("user/1"), []byte("v2"))
+	wb.PutLogData([]byte("not a tag"))
+	wb.Put([]byte("user/2"), []byte("v2"))
+	if wb.Count() != 3 {
+		t.Errorf("Count = %d, want 3", wb.Count())
+	}
+
+	want := map[string]SchemaTag{"user/1": {"user", 2}, "user/2": {"user", 2}}
+	it := wb.NewIterator()
+	for it.Next() {
+		rec := it.Record()
+		if rec.Type != RecordTypeValue {
+			continue
+		}
+		tag, ok := it.Schema()
+		if w, tagged := want[string(rec.Key)]; ok != tagged || tag != w {
+			t.Errorf("%s: Schema = %v, %v; want %v, %v", rec.Key, tag, ok, w, tagged)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := DecodeSchemaTag([]byte("not a tag")); ok {
+		t.Errorf("DecodeSchemaTag accepted plain LogData")
+	}
+}
+
+func TestWriteBatchMerge(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Merge([]byte("counter"), Uint64Operand(1))
+
+	it := wb.NewIterator()
+	if !it.Next() {
+		t.Fatalf("no record in batch: %v", it.Error())
+	}
+	rec := it.Record()
+	if rec.Type != RecordTypeMerge || string(rec.Key) != "counter" || !bytes.Equal(rec.Value, Uint64Operand(1)) {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestWriteBatchDeleteRange(t *testing.T) {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.DeleteRange([]byte("a"), []byte("m"))
+	wb.Put([]byte("z"), []byte("1"))
+
+	it := wb.NewIterator()
+	if !it.Next() {
+		t.Fatalf("no record in batch: %v", it.Error())
+	}
+	rec := it.Record()
+	if rec.Type != RecordTypeRangeDeletion || string(rec.Key) != "a" || string(rec.Value) != "m" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if !it.Next() || it.Record().Type != RecordTypeValue || string(it.Record().Key) != "z" {
+		t.Errorf("record after the range deletion misparsed: %+v, %v", it.Record(), it.Error())
+	}
+}
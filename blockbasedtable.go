@@ -0,0 +1,104 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+// ChecksumType is the checksum protecting the blocks of SST files.
+type ChecksumType int
+
+// Checksum types for BlockBasedTableOptions.SetChecksum. XXH3 is the
+// fastest, and the default since RocksDB 7.0; files using it need RocksDB
+// 6.27 or later to read. CRC32c is fast on CPUs with hardware support for it.
+const (
+	NoChecksum       = ChecksumType(0)
+	CRC32cChecksum   = ChecksumType(1)
+	XXHashChecksum   = ChecksumType(2)
+	XXHash64Checksum = ChecksumType(3)
+	XXH3Checksum     = ChecksumType(4)
+)
+
+// BlockBasedTableOptions configures RocksDB's default SST file format, in
+// which files are made of blocks indexed by an index block and optionally
+// guarded by filter blocks. It is installed with
+// Options.SetBlockBasedTableFactory.
+//
+// To prevent memory leaks, Close must be called on a BlockBasedTableOptions
+// when the program no longer needs it.
+type BlockBasedTableOptions struct {
+	Opt *C.rocksdb_block_based_table_options_t
+}
+
+// NewBlockBasedTableOptions allocates a BlockBasedTableOptions with
+// RocksDB's defaults.
+func NewBlockBasedTableOptions() *BlockBasedTableOptions {
+	return &BlockBasedTableOptions{C.rocksdb_block_based_options_create()}
+}
+
+// Close deallocates the BlockBasedTableOptions, freeing its underlying C
+// struct. Options it was installed on are not affected.
+func (bo *BlockBasedTableOptions) Close() {
+	C.rocksdb_block_based_options_destroy(bo.Opt)
+}
+
+// SetBlockCache sets the cache data blocks are read through, and, with
+// SetCacheIndexAndFilterBlocks, index and filter blocks too.
+func (bo *BlockBasedTableOptions) SetBlockCache(cache *Cache) {
+	C.rocksdb_block_based_options_set_block_cache(bo.Opt, cache.Cache)
+}
+
+// SetBlockSize sets the approximate size of user data packed per block.
+func (bo *BlockBasedTableOptions) SetBlockSize(s int) {
+	C.rocksdb_block_based_options_set_block_size(bo.Opt, C.size_t(s))
+}
+
+// SetFilterPolicy sets the filter built for each file, such as
+// NewBloomFilterFull. RocksDB takes over fp, which must not be used again,
+// and Close on it does nothing.
+func (bo *BlockBasedTableOptions) SetFilterPolicy(fp *FilterPolicy) {
+	C.rocksdb_block_based_options_set_filter_policy(bo.Opt, fp.Policy)
+	fp.Policy = nil
+}
+
+// SetCacheIndexAndFilterBlocks keeps index and filter blocks in the block
+// cache instead of holding them on the heap for every open file. This
+// bounds their memory by the cache's capacity, which matters for large
+// databases, at the cost of reads missing the cache for them too.
+func (bo *BlockBasedTableOptions) SetCacheIndexAndFilterBlocks(b bool) {
+	C.rocksdb_block_based_options_set_cache_index_and_filter_blocks(bo.Opt, boolToUchar(b))
+}
+
+// SetCacheIndexAndFilterBlocksWithHighPriority puts index and filter blocks
+// in the high-priority pool of the block cache, so that data blocks are
+// evicted before them. It only has an effect with
+// SetCacheIndexAndFilterBlocks and a cache with a high-priority pool.
+func (bo *BlockBasedTableOptions) SetCacheIndexAndFilterBlocksWithHighPriority(b bool) {
+	C.rocksdb_block_based_options_set_cache_index_and_filter_blocks_with_high_priority(bo.Opt, boolToUchar(b))
+}
+
+// SetPinL0FilterAndIndexBlocksInCache keeps the index and filter blocks of
+// level 0 files in the block cache for as long as the files exist. Level 0
+// files are consulted by almost every read, so evicting their blocks is
+// rarely worth it. It only has an effect with SetCacheIndexAndFilterBlocks.
+func (bo *BlockBasedTableOptions) SetPinL0FilterAndIndexBlocksInCache(b bool) {
+	C.rocksdb_block_based_options_set_pin_l0_filter_and_index_blocks_in_cache(bo.Opt, boolToUchar(b))
+}
+
+// SetPinTopLevelIndexAndFilter keeps the top level of partitioned index and
+// filter blocks in the block cache for as long as their files exist.
+func (bo *BlockBasedTableOptions) SetPinTopLevelIndexAndFilter(b bool) {
+	C.rocksdb_block_based_options_set_pin_top_level_index_and_filter(bo.Opt, boolToUchar(b))
+}
+
+// SetFormatVersion sets the version of the format files are written in.
+// Newer versions are smaller or faster to read, but cannot be read by
+// RocksDB releases older than them: 4 shrinks index blocks and needs
+// RocksDB 5.16, 5 brings faster full filters and needs 6.6.
+func (bo *BlockBasedTableOptions) SetFormatVersion(v int) {
+	C.rocksdb_block_based_options_set_format_version(bo.Opt, C.int(v))
+}
+
+// SetChecksum sets the checksum written for each block. Files written with
+// any checksum type can be read whatever the setting.
+func (bo *BlockBasedTableOptions) SetChecksum(t ChecksumType) {
+	C.rocksdb_block_based_options_set_checksum(bo.Opt, C.char(t))
+}
@@ -0,0 +1,98 @@
+package gorocks
+
+import "testing"
+
+// tableOption returns the value the latest OPTIONS file of the database in
+// dir records for the given option of the default column family's table
+// factory.
+func tableOption(t *testing.T, dir, factory, name string) string {
+	contents, err := latestOptionsFile(dir)
+	if err != nil {
+		t.Fatalf("No OPTIONS file: %v", err)
+	}
+	return parseOptionsFile(contents)["TableOptions/"+factory+` "default"`+"\t"+name]
+}
+
+func TestBlockBasedTableOptions(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	cache := NewLRUCache(1 << 20)
+	defer cache.Close()
+	bo := NewBlockBasedTableOptions()
+	bo.SetBlockCache(cache)
+	bo.SetBlockSize(8 << 10)
+	bo.SetCacheIndexAndFilterBlocks(true)
+	bo.SetCacheIndexAndFilterBlocksWithHighPriority(true)
+	bo.SetPinL0FilterAndIndexBlocksInCache(true)
+	bo.SetPinTopLevelIndexAndFilter(true)
+	bo.SetFormatVersion(5)
+	bo.SetChecksum(XXH3Checksum)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetBlockBasedTableFactory(bo)
+	bo.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.CompactRange(Range{})
+	CheckGet(t, "after compaction", db, ro, []byte("a"), []byte("1"))
+
+	for name, want := range map[string]string{
+		"block_size":                    "8192",
+		"cache_index_and_filter_blocks": "true",
+		"cache_index_and_filter_blocks_with_high_priority": "true",
+		"pin_l0_filter_and_index_blocks_in_cache":          "true",
+		"pin_top_level_index_and_filter":                   "true",
+		"format_version":                                   "5",
+		"checksum":                                         "kXXH3",
+	} {
+		if got := tableOption(t, dbname, "BlockBasedTable", name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBloomFilterFull(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	filter := NewBloomFilterFull(10)
+	defer filter.Close()
+	bo := NewBlockBasedTableOptions()
+	bo.SetFilterPolicy(filter)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetBlockBasedTableFactory(bo)
+	bo.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.CompactRange(Range{})
+
+	CheckGet(t, "present", db, ro, []byte("a"), []byte("1"))
+	_, stats, err := db.GetWithStats(ro, []byte("b"))
+	if err != nil {
+		t.Fatalf("GetWithStats failed: %v", err)
+	}
+	if stats.BloomUseful != 1 {
+		t.Errorf("filter did not rule out a missing key: %+v", stats)
+	}
+	if got := tableOption(t, dbname, "BlockBasedTable", "filter_policy"); got == "" || got == "nullptr" {
+		t.Errorf("filter_policy = %q, want a bloom filter", got)
+	}
+}
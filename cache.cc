@@ -0,0 +1,39 @@
+// Caches configured through RocksDB's C++ API, for settings the C API does
+// not expose. They are returned as rocksdb_cache_t so that the rest of the
+// C API accepts them.
+
+#include <memory>
+
+#include "cinternal.h"
+
+struct gorocks_secondary_cache_t {
+	std::shared_ptr<rocksdb::SecondaryCache> rep;
+};
+
+extern "C" rocksdb_cache_t* gorocks_cache_create_lru(size_t capacity,
+		int num_shard_bits, unsigned char strict_capacity_limit,
+		double high_pri_pool_ratio, gorocks_secondary_cache_t* secondary) {
+	rocksdb::LRUCacheOptions opts;
+	opts.capacity = capacity;
+	opts.num_shard_bits = num_shard_bits;
+	opts.strict_capacity_limit = strict_capacity_limit;
+	opts.high_pri_pool_ratio = high_pri_pool_ratio;
+	if (secondary != nullptr) {
+		opts.secondary_cache = secondary->rep;
+	}
+	rocksdb_cache_t* c = new rocksdb_cache_t;
+	c->rep = rocksdb::NewLRUCache(opts);
+	return c;
+}
+
+extern "C" gorocks_secondary_cache_t* gorocks_secondary_cache_create_compressed(size_t capacity) {
+	rocksdb::CompressedSecondaryCacheOptions opts;
+	opts.capacity = capacity;
+	gorocks_secondary_cache_t* c = new gorocks_secondary_cache_t;
+	c->rep = rocksdb::NewCompressedSecondaryCache(opts);
+	return c;
+}
+
+extern "C" void gorocks_secondary_cache_destroy(gorocks_secondary_cache_t* c) {
+	delete c;
+}
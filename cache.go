@@ -1,7 +1,16 @@
 package gorocks
 
-// #include <stdint.h>
-// #include "rocksdb/c.h"
+/*
+#cgo CXXFLAGS: -std=c++17
+#include <stdint.h>
+#include "rocksdb/c.h"
+
+typedef struct gorocks_secondary_cache_t gorocks_secondary_cache_t;
+
+extern rocksdb_cache_t* gorocks_cache_create_lru(size_t capacity, int num_shard_bits, unsigned char strict_capacity_limit, double high_pri_pool_ratio, gorocks_secondary_cache_t* secondary);
+extern gorocks_secondary_cache_t* gorocks_secondary_cache_create_compressed(size_t capacity);
+extern void gorocks_secondary_cache_destroy(gorocks_secondary_cache_t* c);
+*/
 import "C"
 
 // Cache is a cache used to store data read from data in memory.
@@ -25,6 +34,113 @@ func NewLRUCache(capacity int) *Cache {
 	return &Cache{C.rocksdb_cache_create_lru(C.size_t(capacity))}
 }
 
+// LRUCacheOptions tunes an LRU cache created with NewLRUCacheWithOptions.
+// The zero value is not RocksDB's default; start from
+// DefaultLRUCacheOptions.
+type LRUCacheOptions struct {
+	// NumShardBits splits the cache into 2^NumShardBits shards, each with
+	// its own lock, or lets RocksDB choose from the capacity if negative.
+	// More shards mean less contention between concurrent readers, but
+	// capacity is divided evenly between the shards, so small shards make
+	// the cache less effective.
+	NumShardBits int
+
+	// StrictCapacityLimit makes inserting into a full cache fail, failing
+	// the read that needed the block, instead of going over capacity when
+	// every entry is in use.
+	StrictCapacityLimit bool
+
+	// HighPriPoolRatio is the fraction of the capacity reserved for high
+	// priority entries, such as index and filter blocks cached with
+	// BlockBasedTableOptions.SetCacheIndexAndFilterBlocksWithHighPriority.
+	// Entries also enter the low priority pool at the middle of the LRU
+	// list, so that a scan cannot evict them all.
+	HighPriPoolRatio float64
+
+	// SecondaryCache, if set, keeps blocks evicted from the cache, so that
+	// warm data can be served from it instead of being read from disk.
+	SecondaryCache *SecondaryCache
+}
+
+// DefaultLRUCacheOptions returns RocksDB's default LRUCacheOptions.
+func DefaultLRUCacheOptions() LRUCacheOptions {
+	return LRUCacheOptions{NumShardBits: -1, HighPriPoolRatio: 0.5}
+}
+
+// NewLRUCacheWithOptions is like NewLRUCache, with the given tuning.
+func NewLRUCacheWithOptions(capacity int, o LRUCacheOptions) *Cache {
+	var secondary *C.gorocks_secondary_cache_t
+	if o.SecondaryCache != nil {
+		secondary = o.SecondaryCache.cache
+	}
+	c := C.gorocks_cache_create_lru(C.size_t(capacity), C.int(o.NumShardBits),
+		boolToUchar(o.StrictCapacityLimit), C.double(o.HighPriPoolRatio), secondary)
+	return &Cache{c}
+}
+
+// SecondaryCache is a second tier for a block cache, holding the blocks
+// evicted from it in cheaper memory than the primary cache. It is attached
+// to a Cache through LRUCacheOptions.SecondaryCache, and may be shared by
+// several.
+//
+// To prevent memory leaks, a SecondaryCache must have Close called on it
+// when it is no longer needed by the program. The caches it is attached to
+// keep it alive until they are closed too.
+type SecondaryCache struct {
+	cache *C.gorocks_secondary_cache_t
+}
+
+// NewCompressedSecondaryCache creates a SecondaryCache that keeps blocks in
+// memory compressed with LZ4, up to capacity bytes, so that more of the
+// working set fits in memory at the cost of decompressing blocks on a hit.
+func NewCompressedSecondaryCache(capacity int) *SecondaryCache {
+	return &SecondaryCache{C.gorocks_secondary_cache_create_compressed(C.size_t(capacity))}
+}
+
+// Close releases the SecondaryCache.
+func (sc *SecondaryCache) Close() {
+	C.gorocks_secondary_cache_destroy(sc.cache)
+}
+
+// NewHyperClockCache creates a Cache with the capacity given, using
+// RocksDB's HyperClockCache instead of an LRU list. Lookups in it are
+// lock-free, which avoids the contention on the shard mutexes of an LRU
+// cache under very high read concurrency, at the cost of a less precise
+// eviction order.
+//
+// estimatedEntryCharge is the expected size of a cached block, the block
+// size of the tables using the cache if they are uncompressed. Zero, the
+// recommended setting, has RocksDB size its table of entries dynamically.
+//
+// To prevent memory leaks, Close should be called on the Cache when the
+// program no longer needs it.
+func NewHyperClockCache(capacity, estimatedEntryCharge int) *Cache {
+	return &Cache{C.rocksdb_cache_create_hyper_clock(C.size_t(capacity), C.size_t(estimatedEntryCharge))}
+}
+
+// GetUsage returns the memory used by the entries in the cache, in bytes.
+func (c *Cache) GetUsage() int {
+	return int(C.rocksdb_cache_get_usage(c.Cache))
+}
+
+// GetPinnedUsage returns the memory used by the entries in the cache that
+// are in use, by iterators or pinned index and filter blocks for example,
+// and so cannot be evicted.
+func (c *Cache) GetPinnedUsage() int {
+	return int(C.rocksdb_cache_get_pinned_usage(c.Cache))
+}
+
+// GetCapacity returns the capacity of the cache, in bytes.
+func (c *Cache) GetCapacity() int {
+	return int(C.rocksdb_cache_get_capacity(c.Cache))
+}
+
+// SetCapacity resizes the cache while in use by open databases. Shrinking
+// it evicts entries until the usage fits, as far as entries in use allow.
+func (c *Cache) SetCapacity(capacity int) {
+	C.rocksdb_cache_set_capacity(c.Cache, C.size_t(capacity))
+}
+
 // Close deallocates the underlying memory of the Cache object.
 func (c *Cache) Close() {
 	C.rocksdb_cache_destroy(c.Cache)
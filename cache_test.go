@@ -0,0 +1,136 @@
+package gorocks
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestLRUCacheWithOptions(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	o := DefaultLRUCacheOptions()
+	o.NumShardBits = 2
+	o.StrictCapacityLimit = true
+	o.HighPriPoolRatio = 0.2
+	cache := NewLRUCacheWithOptions(1<<20, o)
+	defer cache.Close()
+	bo := NewBlockBasedTableOptions()
+	bo.SetBlockCache(cache)
+	bo.SetCacheIndexAndFilterBlocks(true)
+	bo.SetCacheIndexAndFilterBlocksWithHighPriority(true)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetBlockBasedTableFactory(bo)
+	bo.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.CompactRange(Range{})
+
+	CheckGet(t, "through the cache", db, ro, []byte("a"), []byte("1"))
+	_, stats, err := db.GetWithStats(ro, []byte("a"))
+	if err != nil {
+		t.Fatalf("GetWithStats failed: %v", err)
+	}
+	if stats.BlockCacheHits == 0 {
+		t.Errorf("second read found no blocks in the cache: %+v", stats)
+	}
+}
+
+func TestCacheUsage(t *testing.T) {
+	for name, newCache := range map[string]func(int) *Cache{
+		"LRU":        NewLRUCache,
+		"HyperClock": func(capacity int) *Cache { return NewHyperClockCache(capacity, 0) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			testCacheUsage(t, newCache(1<<20))
+		})
+	}
+}
+
+func testCacheUsage(t *testing.T, cache *Cache) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	defer cache.Close()
+	bo := NewBlockBasedTableOptions()
+	bo.SetBlockCache(cache)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetBlockBasedTableFactory(bo)
+	bo.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), make([]byte, 1000))
+	db.CompactRange(Range{})
+	db.Get(ro, []byte("a"))
+
+	if cache.GetUsage() == 0 {
+		t.Errorf("cache is empty after a read")
+	}
+	if cache.GetPinnedUsage() > cache.GetUsage() {
+		t.Errorf("pinned usage %d is above usage %d", cache.GetPinnedUsage(), cache.GetUsage())
+	}
+	cache.SetCapacity(2 << 20)
+	if got := cache.GetCapacity(); got != 2<<20 {
+		t.Errorf("capacity after SetCapacity = %d, want %d", got, 2<<20)
+	}
+}
+
+func TestSecondaryCache(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	secondary := NewCompressedSecondaryCache(4 << 20)
+	defer secondary.Close()
+	o := DefaultLRUCacheOptions()
+	o.SecondaryCache = secondary
+	cache := NewLRUCacheWithOptions(64<<10, o)
+	defer cache.Close()
+	bo := NewBlockBasedTableOptions()
+	bo.SetBlockCache(cache)
+	bo.SetBlockSize(4 << 10)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetBlockBasedTableFactory(bo)
+	bo.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	value := bytes.Repeat([]byte("compressible "), 100)
+	for i := 0; i < 1000; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%04d", i)), value)
+	}
+	db.CompactRange(Range{})
+
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < 1000; i++ {
+			CheckGet(t, "with secondary cache", db, ro, []byte(fmt.Sprintf("key%04d", i)), value)
+		}
+	}
+	if cache.GetUsage() > cache.GetCapacity() {
+		t.Errorf("primary cache usage %d is above its capacity %d", cache.GetUsage(), cache.GetCapacity())
+	}
+}
@@ -0,0 +1,117 @@
+package gorocks
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CgoCall identifies a kind of call from Go into RocksDB counted while cgo
+// stats are enabled.
+type CgoCall int
+
+const (
+	CgoGet CgoCall = iota
+	CgoMultiGet
+	CgoPut
+	CgoMerge
+	CgoDelete
+	CgoDeleteRange
+	CgoWrite
+	CgoIteratorCreate
+	CgoIteratorSeek
+	CgoIteratorStep
+	CgoIteratorValid
+	CgoIteratorKey
+	CgoIteratorValue
+
+	numCgoCalls
+)
+
+var cgoCallNames = [...]string{
+	CgoGet:            "get",
+	CgoMultiGet:       "multi_get",
+	CgoPut:            "put",
+	CgoMerge:          "merge",
+	CgoDelete:         "delete",
+	CgoDeleteRange:    "delete_range",
+	CgoWrite:          "write",
+	CgoIteratorCreate: "iterator_create",
+	CgoIteratorSeek:   "iterator_seek",
+	CgoIteratorStep:   "iterator_step",
+	CgoIteratorValid:  "iterator_valid",
+	CgoIteratorKey:    "iterator_key",
+	CgoIteratorValue:  "iterator_value",
+}
+
+func (c CgoCall) String() string {
+	if c < 0 || c >= numCgoCalls {
+		return "unknown"
+	}
+	return cgoCallNames[c]
+}
+
+// CgoCallStats are the totals of one kind of call into RocksDB.
+type CgoCallStats struct {
+	Call  CgoCall
+	Calls uint64
+	// Time is the time spent in the calls, measured around the cgo call
+	// itself. It includes the cost of crossing into C and back, but not the
+	// work done in Go before and after, such as copying results.
+	Time time.Duration
+}
+
+var (
+	cgoStatsEnabled atomic.Bool
+	cgoStats        [numCgoCalls]struct {
+		calls, nanos atomic.Uint64
+	}
+)
+
+// EnableCgoStats turns the counting and timing of calls into RocksDB on or
+// off for the whole process. It is off by default, since reading the clock
+// around every call costs about as much as a cheap call itself. Comparing
+// the totals with the durations reported to an OpHook shows how much time
+// goes into the binding rather than RocksDB.
+func EnableCgoStats(on bool) {
+	cgoStatsEnabled.Store(on)
+}
+
+// CgoStats returns the totals of the calls made while cgo stats were
+// enabled, for each kind of call.
+func CgoStats() []CgoCallStats {
+	stats := make([]CgoCallStats, numCgoCalls)
+	for i := range stats {
+		stats[i] = CgoCallStats{
+			Call:  CgoCall(i),
+			Calls: cgoStats[i].calls.Load(),
+			Time:  time.Duration(cgoStats[i].nanos.Load()),
+		}
+	}
+	return stats
+}
+
+// ResetCgoStats sets the totals returned by CgoStats back to zero.
+func ResetCgoStats() {
+	for i := range cgoStats {
+		cgoStats[i].calls.Store(0)
+		cgoStats[i].nanos.Store(0)
+	}
+}
+
+// cgoStart returns the time a call into RocksDB starts, or the zero time if
+// cgo stats are disabled. The call is recorded by passing the result to
+// cgoDone.
+func cgoStart() time.Time {
+	if !cgoStatsEnabled.Load() {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func cgoDone(c CgoCall, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	cgoStats[c].calls.Add(1)
+	cgoStats[c].nanos.Add(uint64(time.Since(start)))
+}
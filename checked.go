@@ -0,0 +1,252 @@
+package gorocks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StartupChecks selects the validations OpenChecked runs.
+type StartupChecks struct {
+	// Files checks that every live SST file recorded in the MANIFEST is
+	// present in the database directory with the recorded size.
+	Files bool
+	// ChecksumSampleFiles is the number of live SST files, spread over the
+	// levels, whose key ranges are read back with checksum verification.
+	// Zero skips the check; a negative value checks every file.
+	//
+	// Only files of the default column family, the one OpenChecked opens,
+	// are sampled; the others are skipped and not counted.
+	//
+	// RocksDB's C API cannot read a single SST file, so each range is read
+	// through the file's column family, which verifies the blocks of the
+	// sampled file that the read steps through along with those of the
+	// files overlapping it. That is usually all of the file's
+	// blocks, but RocksDB may seek past some, for example ones holding keys
+	// deleted by a newer range deletion, and those are not verified.
+	ChecksumSampleFiles int
+	// Options compares the options the database is opened with against
+	// those it was last opened with, as recorded in its OPTIONS file.
+	Options bool
+	// Manifest, if not nil, is validated against the database, as with
+	// SchemaManifest.Validate.
+	Manifest *SchemaManifest
+}
+
+// OptionChange is an option whose value differs from the one the database
+// was last opened with.
+type OptionChange struct {
+	// Section is the section of the OPTIONS file, for example
+	// `CFOptions "default"`.
+	Section  string
+	Name     string
+	Old, New string
+	// Incompatible is set for options that change how existing data is
+	// interpreted, such as the comparator or the merge operator.
+	Incompatible bool
+}
+
+// StartupReport is the result of the checks run by OpenChecked.
+type StartupReport struct {
+	// MissingFiles lists the live SST files not found on disk, and
+	// SizeMismatches those whose size differs from the recorded one.
+	MissingFiles   []string
+	SizeMismatches []string
+	// ChecksumFilesChecked is the number of files whose key ranges were
+	// read back, and ChecksumErrors the errors found doing so.
+	ChecksumFilesChecked int
+	ChecksumErrors       []error
+	OptionChanges        []OptionChange
+	// ManifestMismatches lists the differences from StartupChecks.Manifest.
+	ManifestMismatches []string
+}
+
+// Err summarizes the problems found as an error, or returns nil if there
+// were none. Option changes only count if they are incompatible.
+func (r *StartupReport) Err() error {
+	var problems []string
+	if n := len(r.MissingFiles); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d missing files", n))
+	}
+	if n := len(r.SizeMismatches); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d files with the wrong size", n))
+	}
+	if n := len(r.ChecksumErrors); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d checksum errors, first: %v", n, r.ChecksumErrors[0]))
+	}
+	problems = append(problems, r.ManifestMismatches...)
+	for _, c := range r.OptionChanges {
+		if c.Incompatible {
+			problems = append(problems, fmt.Sprintf("incompatible change of %s from %q to %q", c.Name, c.Old, c.New))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return DatabaseError("startup checks failed: " + strings.Join(problems, "; "))
+}
+
+// incompatibleOptions are the options whose change makes existing data
+// unreadable or wrongly interpreted.
+var incompatibleOptions = map[string]bool{
+	"comparator":       true,
+	"merge_operator":   true,
+	"prefix_extractor": true,
+	"table_factory":    true,
+}
+
+// OpenChecked is like Open, but runs the selected startup checks before
+// returning, so that a service can refuse to serve traffic from a damaged
+// or misconfigured database. The DB is returned even if checks fail; the
+// caller decides, usually by looking at report.Err(). An error is only
+// returned if the database could not be opened.
+func OpenChecked(dbname string, o *Options, checks StartupChecks) (*DB, *StartupReport, error) {
+	var before map[string]string
+	if checks.Options {
+		if prev, err := latestOptionsFile(dbname); err == nil {
+			before = parseOptionsFile(prev)
+		}
+	}
+	db, err := Open(dbname, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &StartupReport{}
+	live := db.LiveFiles()
+	if checks.Files {
+		checkFiles(db.path, live, report)
+	}
+	if checks.ChecksumSampleFiles != 0 {
+		compare := bytes.Compare
+		if o.cmp != nil {
+			compare = o.cmp.Compare
+		}
+		cfs := map[string]*ColumnFamilyHandle{DefaultColumnFamilyName: nil}
+		db.checkFileChecksums(live, cfs, checks.ChecksumSampleFiles, compare, report)
+	}
+	if before != nil {
+		if now, err := db.OptionsFile(); err == nil {
+			report.OptionChanges = diffOptions(before, parseOptionsFile(now))
+		}
+	}
+	if checks.Manifest != nil {
+		if now, err := db.SchemaManifest(); err != nil {
+			report.ManifestMismatches = append(report.ManifestMismatches, err.Error())
+		} else {
+			report.ManifestMismatches = checks.Manifest.mismatches(now)
+		}
+	}
+	return db, report, nil
+}
+
+// checkFiles records the files missing from the database directory dir, or
+// whose size differs from the one in their metadata.
+func checkFiles(dir string, files []LiveFileMetadata, report *StartupReport) {
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(dir, f.Name))
+		switch {
+		case err != nil:
+			report.MissingFiles = append(report.MissingFiles, f.Name)
+		case info.Size() != f.Size:
+			report.SizeMismatches = append(report.SizeMismatches, f.Name)
+		}
+	}
+}
+
+// checkFileChecksums reads back the key ranges of up to n of the files,
+// picked evenly over the levels, with checksum verification, as described
+// for StartupChecks.ChecksumSampleFiles. cfs maps the names of the column
+// families to check to their handles, nil for the default one; files of
+// other column families are skipped. compare is the order of the keys.
+func (db *DB) checkFileChecksums(files []LiveFileMetadata, cfs map[string]*ColumnFamilyHandle, n int, compare func(a, b []byte) int, report *StartupReport) {
+	var sample []LiveFileMetadata
+	for _, f := range files {
+		if _, ok := cfs[f.ColumnFamily]; ok {
+			sample = append(sample, f)
+		}
+	}
+	files = sample
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Level < files[j].Level
+	})
+	if n < 0 || n > len(files) {
+		n = len(files)
+	}
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetFillCache(false)
+	for i := 0; i < n; i++ {
+		f := files[i*len(files)/n]
+		var it *Iterator
+		if cf := cfs[f.ColumnFamily]; cf != nil {
+			it = db.NewIteratorCF(ro, cf)
+		} else {
+			it = db.NewIterator(ro)
+		}
+		for it.Seek(f.SmallestKey); it.Valid(); it.Next() {
+			if compare(it.Key(), f.LargestKey) > 0 {
+				break
+			}
+		}
+		if err := it.GetError(); err != nil {
+			report.ChecksumErrors = append(report.ChecksumErrors, fmt.Errorf("%s: %v", f.Name, err))
+		}
+		it.Close()
+		report.ChecksumFilesChecked++
+	}
+}
+
+// parseOptionsFile parses the INI format of an OPTIONS file into a map
+// from section and option name, separated by a tab, to value.
+func parseOptionsFile(contents string) map[string]string {
+	opts := make(map[string]string)
+	var section string
+	sc := bufio.NewScanner(strings.NewReader(contents))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+		default:
+			if i := strings.IndexByte(line, '='); i >= 0 {
+				opts[section+"\t"+line[:i]] = line[i+1:]
+			}
+		}
+	}
+	return opts
+}
+
+// diffOptions lists the options of the column family and database sections
+// that differ between before and after, sorted by section and name.
+func diffOptions(before, after map[string]string) []OptionChange {
+	var changes []OptionChange
+	for key, old := range before {
+		section := key[:strings.IndexByte(key, '\t')]
+		if strings.HasPrefix(section, "Version") {
+			continue
+		}
+		if now, ok := after[key]; ok && now != old {
+			name := key[len(section)+1:]
+			changes = append(changes, OptionChange{
+				Section:      section,
+				Name:         name,
+				Old:          old,
+				New:          now,
+				Incompatible: incompatibleOptions[name],
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
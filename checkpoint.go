@@ -0,0 +1,54 @@
+package gorocks
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"unsafe"
+)
+
+// Checkpoint makes openable copies of a DB as of a point in time. SST files
+// are hard-linked into the copy when it is on the same filesystem, which
+// makes checkpoints cheap. It is created by DB.NewCheckpoint.
+//
+// To prevent memory leaks, Close must be called on a Checkpoint when the
+// program no longer needs it.
+type Checkpoint struct {
+	Checkpoint *C.rocksdb_checkpoint_t
+}
+
+// NewCheckpoint returns a Checkpoint of the database.
+func (db *DB) NewCheckpoint() (*Checkpoint, error) {
+	var errStr *C.char
+	cp := C.rocksdb_checkpoint_object_create(db.Ldb, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	return &Checkpoint{cp}, nil
+}
+
+// Create writes a checkpoint of the database into dir, which must not exist
+// yet. If the write ahead log is larger than logSizeForFlush bytes, the
+// memtables are flushed first and the log is left out of the checkpoint;
+// otherwise the log is copied. A logSizeForFlush of 0 always flushes.
+func (cp *Checkpoint) Create(dir string, logSizeForFlush uint64) error {
+	var errStr *C.char
+	cdir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cdir))
+
+	C.rocksdb_checkpoint_create(cp.Checkpoint, cdir, C.uint64_t(logSizeForFlush), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Close deallocates the Checkpoint, freeing its underlying C struct.
+func (cp *Checkpoint) Close() {
+	C.rocksdb_checkpoint_object_destroy(cp.Checkpoint)
+}
@@ -0,0 +1,179 @@
+package gorocks
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+)
+
+// ChunkSize is the size of the chunks PutReader splits values into.
+const ChunkSize = 1 << 20
+
+// chunkBatchChunks is the number of chunks PutReader buffers in a
+// WriteBatch before writing it.
+const chunkBatchChunks = 8
+
+// chunkManifestMagic starts the value stored under the key of a chunked
+// value, so that GetWriter can tell it apart from a plain value.
+const chunkManifestMagic = "gorocks-chunked\x00"
+
+const chunkManifestLen = len(chunkManifestMagic) + 8 + 4 + 8
+
+// A chunk key is the key of the value, a zero byte, the generation of the
+// value and the index of the chunk. Each PutReader writes its chunks under
+// a new generation, so that they do not disturb the chunks of the value
+// being replaced until the manifest is switched over.
+func chunkKey(key []byte, gen uint64, i uint32) []byte {
+	k := make([]byte, len(key)+1+8+4)
+	copy(k, key)
+	binary.BigEndian.PutUint64(k[len(key)+1:], gen)
+	binary.BigEndian.PutUint32(k[len(key)+1+8:], i)
+	return k
+}
+
+// chunkRange is the range of the chunk keys of one generation.
+func chunkRange(key []byte, gen uint64) Range {
+	prefix := chunkKey(key, gen, 0)[:len(key)+1+8]
+	return Range{prefix, append(prefix, 0xff, 0xff, 0xff, 0xff, 0)}
+}
+
+func encodeChunkManifest(size int64, chunks uint32, gen uint64) []byte {
+	m := make([]byte, chunkManifestLen)
+	copy(m, chunkManifestMagic)
+	binary.BigEndian.PutUint64(m[len(chunkManifestMagic):], uint64(size))
+	binary.BigEndian.PutUint32(m[len(chunkManifestMagic)+8:], chunks)
+	binary.BigEndian.PutUint64(m[len(chunkManifestMagic)+12:], gen)
+	return m
+}
+
+func decodeChunkManifest(m []byte) (size int64, chunks uint32, gen uint64, ok bool) {
+	if len(m) != chunkManifestLen || string(m[:len(chunkManifestMagic)]) != chunkManifestMagic {
+		return 0, 0, 0, false
+	}
+	size = int64(binary.BigEndian.Uint64(m[len(chunkManifestMagic):]))
+	chunks = binary.BigEndian.Uint32(m[len(chunkManifestMagic)+8:])
+	gen = binary.BigEndian.Uint64(m[len(chunkManifestMagic)+12:])
+	return size, chunks, gen, true
+}
+
+// PutReader stores everything read from r as the value of key, split into
+// chunks of ChunkSize bytes kept under separate keys, since single values of
+// hundreds of megabytes perform poorly in the LSM tree. It returns the number
+// of bytes stored.
+//
+// The chunks are written in batches of a few chunks as they are read, so
+// memory use stays bounded whatever the size of the value. A small manifest
+// under key itself is written last, replacing any value previously stored
+// under key and deleting its chunks in the same atomic WriteBatch. Readers
+// see either the old value or the new one. The manifest is switched while
+// holding the same per-key lock as DB.Update, so concurrent PutReader and
+// Update calls on key through this DB handle are serialized. Writers using
+// other handles must not write key concurrently.
+//
+// If PutReader fails or the process dies before the manifest is written,
+// the chunks written so far stay behind until DeleteChunked removes them.
+// All keys starting with key followed by a zero byte are reserved for the
+// chunks.
+func (db *DB) PutReader(ro *ReadOptions, wo *WriteOptions, key []byte, r io.Reader) (int64, error) {
+	gen := rand.Uint64()
+	wb := NewWriteBatch()
+	defer wb.Close()
+	buf := make([]byte, ChunkSize)
+	var size int64
+	var chunks uint32
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			wb.Put(chunkKey(key, gen, chunks), buf[:n])
+			chunks++
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if chunks%chunkBatchChunks == 0 {
+			if err := db.Write(wo, wb); err != nil {
+				return 0, err
+			}
+			wb.Clear()
+		}
+	}
+
+	unlock := db.updateLocks.lockKeys([][]byte{key})
+	defer unlock()
+	old, err := db.Get(ro, key)
+	if err != nil {
+		return 0, err
+	}
+	if _, _, oldGen, ok := decodeChunkManifest(old); ok && oldGen != gen {
+		cr := chunkRange(key, oldGen)
+		wb.DeleteRange(cr.Start, cr.Limit)
+	}
+	wb.Put(key, encodeChunkManifest(size, chunks, gen))
+	if err := db.Write(wo, wb); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// GetWriter writes the value of key to w and returns the number of bytes
+// written. Values stored by PutReader are reassembled from their chunks,
+// all read from a single Snapshot unless ro already carries one; plain
+// values are written as they are. found is false if the key does not exist.
+//
+// ro is modified while GetWriter runs and must not be used by other
+// goroutines at the same time.
+func (db *DB) GetWriter(ro *ReadOptions, key []byte, w io.Writer) (n int64, found bool, err error) {
+	if ro.snap == nil {
+		snap := db.NewSnapshot()
+		defer db.ReleaseSnapshot(snap)
+		ro.SetSnapshot(snap)
+		defer ro.SetSnapshot(nil)
+	}
+
+	value, err := db.Get(ro, key)
+	if err != nil || value == nil {
+		return 0, false, err
+	}
+	size, chunks, gen, ok := decodeChunkManifest(value)
+	if !ok {
+		written, err := w.Write(value)
+		return int64(written), true, err
+	}
+	for i := uint32(0); i < chunks; i++ {
+		chunk, err := db.Get(ro, chunkKey(key, gen, i))
+		if err != nil {
+			return n, true, err
+		}
+		if chunk == nil {
+			return n, true, DatabaseError("chunked value is missing a chunk")
+		}
+		written, err := w.Write(chunk)
+		n += int64(written)
+		if err != nil {
+			return n, true, err
+		}
+	}
+	if n != size {
+		return n, true, DatabaseError("chunked value has the wrong size")
+	}
+	return n, true, nil
+}
+
+// DeleteChunked removes key along with all chunks stored under it by
+// PutReader, including those left behind by PutReader calls that did not
+// complete, in one atomic WriteBatch. It must not run concurrently with a
+// PutReader of the same key, whose chunks it would remove.
+func (db *DB) DeleteChunked(ro *ReadOptions, wo *WriteOptions, key []byte) error {
+	unlock := db.updateLocks.lockKeys([][]byte{key})
+	defer unlock()
+	wb := NewWriteBatch()
+	defer wb.Close()
+	start := append(key[:len(key):len(key)], 0)
+	wb.DeleteRange(start, append(key[:len(key):len(key)], 1))
+	wb.Delete(key)
+	return db.Write(wo, wb)
+}
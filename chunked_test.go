@@ -0,0 +1,81 @@
+package gorocks
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunkedValues(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	key := []byte("blob")
+	big := bytes.Repeat([]byte("0123456789"), ChunkSize/4)
+	n, err := db.PutReader(ro, wo, key, bytes.NewReader(big))
+	if err != nil || n != int64(len(big)) {
+		t.Fatalf("PutReader: got %d, %v", n, err)
+	}
+	var buf bytes.Buffer
+	n, found, err := db.GetWriter(ro, key, &buf)
+	if err != nil || !found || n != int64(len(big)) || !bytes.Equal(buf.Bytes(), big) {
+		t.Fatalf("GetWriter: got %d bytes, %v, %v", n, found, err)
+	}
+
+	// More chunks than fit in one batch, so they are written in several.
+	huge := bytes.Repeat([]byte{'x'}, (chunkBatchChunks+2)*ChunkSize+1)
+	n, err = db.PutReader(ro, wo, key, bytes.NewReader(huge))
+	if err != nil || n != int64(len(huge)) {
+		t.Fatalf("PutReader of %d chunks: got %d, %v", chunkBatchChunks+3, n, err)
+	}
+	if got := countChunks(t, db, ro, key); got != chunkBatchChunks+3 {
+		t.Errorf("%d chunks stored, want only the %d of the new value", got, chunkBatchChunks+3)
+	}
+	buf.Reset()
+	if _, _, err := db.GetWriter(ro, key, &buf); err != nil || !bytes.Equal(buf.Bytes(), huge) {
+		t.Fatalf("GetWriter after a batched PutReader: %v", err)
+	}
+
+	small := []byte("small")
+	db.PutReader(ro, wo, key, bytes.NewReader(small))
+	if got := countChunks(t, db, ro, key); got != 1 {
+		t.Errorf("%d chunks stored after overwrite, want 1", got)
+	}
+	buf.Reset()
+	db.GetWriter(ro, key, &buf)
+	if !bytes.Equal(buf.Bytes(), small) {
+		t.Errorf("expected %q after overwrite, got %q", small, buf.Bytes())
+	}
+
+	if err := db.DeleteChunked(ro, wo, key); err != nil {
+		t.Errorf("DeleteChunked failed: %v", err)
+	}
+	if got := countChunks(t, db, ro, key); got != 0 {
+		t.Errorf("%d chunks left after DeleteChunked", got)
+	}
+	if _, found, _ := db.GetWriter(ro, key, &buf); found {
+		t.Errorf("deleted chunked value should not be found")
+	}
+}
+
+// countChunks returns the number of chunk keys stored for key.
+func countChunks(t *testing.T, db *DB, ro *ReadOptions, key []byte) uint64 {
+	t.Helper()
+	start := append(key[:len(key):len(key)], 0)
+	n, err := db.CountRange(ro, Range{start, append(key[:len(key):len(key)], 1)})
+	if err != nil {
+		t.Fatalf("CountRange failed: %v", err)
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+// Private types of RocksDB's C API, for the C++ shims that reach the C++
+// objects behind the C handles.
+//
+// RocksDB defines these structs in db/c.cc and does not install them, so
+// they are copied here from RocksDB 9.10; they have had the same layout
+// since RocksDB 8.0. Only the leading rep members, which are all the shims
+// use, are declared. A RocksDB release that changes them breaks the shims
+// silently, hence the version check: on a new major version, compare the
+// structs below with those in its db/c.cc, then extend the check.
+
+#ifndef GOROCKS_CINTERNAL_H
+#define GOROCKS_CINTERNAL_H
+
+#include <memory>
+
+#include "rocksdb/c.h"
+#include "rocksdb/cache.h"
+#include "rocksdb/db.h"
+#include "rocksdb/options.h"
+#include "rocksdb/utilities/backup_engine.h"
+#include "rocksdb/utilities/transaction.h"
+#include "rocksdb/utilities/transaction_db.h"
+#include "rocksdb/version.h"
+
+#if ROCKSDB_MAJOR < 8 || ROCKSDB_MAJOR > 9
+#error "cinternal.h mirrors private structs of RocksDB 8 and 9; check them against db/c.cc of this version"
+#endif
+
+struct rocksdb_t {
+	rocksdb::DB* rep;
+};
+
+struct rocksdb_backup_engine_t {
+	rocksdb::BackupEngine* rep;
+};
+
+struct rocksdb_cache_t {
+	std::shared_ptr<rocksdb::Cache> rep;
+};
+
+struct rocksdb_options_t {
+	rocksdb::Options rep;
+};
+
+struct rocksdb_readoptions_t {
+	rocksdb::ReadOptions rep;
+};
+
+struct rocksdb_column_family_handle_t {
+	rocksdb::ColumnFamilyHandle* rep;
+};
+
+struct rocksdb_transactiondb_t {
+	rocksdb::TransactionDB* rep;
+};
+
+struct rocksdb_transaction_t {
+	rocksdb::Transaction* rep;
+};
+
+// The shims hand rocksdb_cache_t objects they create to the C API, which
+// deletes them, so that struct must match exactly, not just its prefix.
+static_assert(sizeof(rocksdb_cache_t) == sizeof(std::shared_ptr<rocksdb::Cache>),
+	"rocksdb_cache_t must hold nothing but the shared_ptr");
+
+#endif  // GOROCKS_CINTERNAL_H
@@ -0,0 +1,60 @@
+package gorocks
+
+import (
+	"os"
+)
+
+// CloneAtSnapshot produces a frozen, queryable copy of the database as it is
+// now, for reporting jobs that need a stable view for longer than a Snapshot
+// should be held. The copy is made in dir, which must not exist yet, mostly
+// by hard-linking the SST files, and opened for reading only with o.
+//
+// Closing the returned DB removes dir.
+func (db *DB) CloneAtSnapshot(dir string, o *Options) (*DB, error) {
+	cp, err := db.NewCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	// A log size of 0 forces a flush, so the clone has no WAL to replay.
+	err = cp.Create(dir, 0)
+	cp.Close()
+	if err != nil {
+		return nil, err
+	}
+	clone, err := OpenForReadOnly(dir, o, false)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	clone.closed = func() {
+		os.RemoveAll(dir)
+	}
+	return clone, nil
+}
+
+// Fork produces a writable copy of the database as it is now, in dir, which
+// must not exist yet, and opens it with o. Like CloneAtSnapshot, the SST
+// files are hard-linked where possible, so forking is cheap even for large
+// databases; writes to the fork do not affect the original and vice versa.
+//
+// Closing the returned DB removes dir.
+func (db *DB) Fork(dir string, o *Options) (*DB, error) {
+	cp, err := db.NewCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	err = cp.Create(dir, 0)
+	cp.Close()
+	if err != nil {
+		return nil, err
+	}
+	fork, err := Open(dir, o)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	fork.closed = func() {
+		os.RemoveAll(dir)
+	}
+	return fork, nil
+}
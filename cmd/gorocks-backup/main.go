@@ -0,0 +1,261 @@
+// Command gorocks-backup manages backups of RocksDB databases with the
+// gorocks BackupEngine, for use from cron jobs and operators' shells.
+//
+// Usage:
+//
+//	gorocks-backup create -db DIR -backup DIR [-flush] [-rate BYTES]
+//	gorocks-backup list -backup DIR
+//	gorocks-backup verify -backup DIR [-id ID]
+//	gorocks-backup purge -backup DIR -keep N
+//	gorocks-backup restore -backup DIR -db DIR [-wal DIR] [-id ID] [-keep-log-files]
+//
+// create backs up a database, with all of its column families, that is not
+// open elsewhere. A database held open by another process is refused: read
+// only, it could not stop that process from compacting away and deleting
+// files during the copy, so such a database must be backed up from the
+// process holding it.
+//
+// verify checks every backup unless -id is given, and restore restores the
+// latest backup unless -id is given. The database must not be open while
+// it is restored.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alberts/gorocks"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  gorocks-backup create -db DIR -backup DIR [-flush] [-rate BYTES]
+  gorocks-backup list -backup DIR
+  gorocks-backup verify -backup DIR [-id ID]
+  gorocks-backup purge -backup DIR -keep N
+  gorocks-backup restore -backup DIR -db DIR [-wal DIR] [-id ID] [-keep-log-files]`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmds := map[string]func([]string) error{
+		"create":  create,
+		"list":    list,
+		"verify":  verify,
+		"purge":   purge,
+		"restore": restore,
+	}
+	cmd, ok := cmds[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "gorocks-backup %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// engineFlags holds the flags shared by all commands.
+type engineFlags struct {
+	fs        *flag.FlagSet
+	backupDir *string
+	rate      *uint64
+}
+
+func newFlags(name string) *engineFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return &engineFlags{
+		fs:        fs,
+		backupDir: fs.String("backup", "", "backup `directory`"),
+		rate:      fs.Uint64("rate", 0, "backup and restore rate limit in `bytes` per second, 0 for none"),
+	}
+}
+
+func (f *engineFlags) parse(args []string) error {
+	f.fs.Parse(args)
+	if *f.backupDir == "" {
+		return errors.New("-backup is required")
+	}
+	return nil
+}
+
+// open opens the BackupEngine. The returned function closes it.
+func (f *engineFlags) open() (*gorocks.BackupEngine, func(), error) {
+	bo := gorocks.NewBackupEngineOptions(*f.backupDir)
+	defer bo.Close()
+	bo.SetBackupRateLimit(*f.rate)
+	bo.SetRestoreRateLimit(*f.rate)
+	bo.SetSync(true)
+	be, err := gorocks.OpenBackupEngineWithOptions(bo, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return be, be.Close, nil
+}
+
+func create(args []string) error {
+	f := newFlags("create")
+	dbDir := f.fs.String("db", "", "database `directory`")
+	flush := f.fs.Bool("flush", false, "flush the memtables before the backup")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *dbDir == "" {
+		return errors.New("-db is required")
+	}
+
+	opts := gorocks.NewOptions()
+	defer opts.Close()
+	names, err := gorocks.ListColumnFamilies(*dbDir, opts)
+	if err != nil {
+		return err
+	}
+	cfOpts := make([]*gorocks.Options, len(names))
+	for i := range cfOpts {
+		cfOpts[i] = opts
+	}
+	db, handles, err := gorocks.OpenColumnFamilies(*dbDir, opts, names, cfOpts)
+	if errors.Is(err, gorocks.ErrDBLocked) {
+		return fmt.Errorf("%v; back it up from the process holding it open", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	for _, h := range handles {
+		defer h.Close()
+	}
+
+	be, closeEngine, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer closeEngine()
+	start := time.Now()
+	err = be.CreateNewBackupWithProgress(db, *flush, 10*time.Second, func(p gorocks.BackupProgress) {
+		fmt.Fprintf(os.Stderr, "%d files, %d bytes copied\n", p.FilesCopied, p.BytesCopied)
+	})
+	if err != nil {
+		return err
+	}
+	backups := be.GetBackupInfo()
+	latest := backups[len(backups)-1]
+	fmt.Printf("created backup %d in %v\n", latest.ID, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func list(args []string) error {
+	f := newFlags("list")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	be, closeEngine, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer closeEngine()
+	fmt.Printf("%-6s %-25s %8s %14s\n", "ID", "TIME", "FILES", "BYTES")
+	for _, b := range be.GetBackupInfo() {
+		fmt.Printf("%-6d %-25s %8d %14d\n", b.ID, b.Timestamp.Format(time.RFC3339), b.NumFiles, b.Size)
+	}
+	return nil
+}
+
+func verify(args []string) error {
+	f := newFlags("verify")
+	id := f.fs.Uint("id", 0, "backup `ID` to verify, 0 for all")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	be, closeEngine, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer closeEngine()
+
+	var ids []uint32
+	if *id != 0 {
+		ids = append(ids, uint32(*id))
+	} else {
+		for _, b := range be.GetBackupInfo() {
+			ids = append(ids, b.ID)
+		}
+	}
+	var failed int
+	for _, id := range ids {
+		if err := be.VerifyBackup(id); err != nil {
+			fmt.Printf("backup %d: %v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Printf("backup %d: ok\n", id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d backups failed verification", failed, len(ids))
+	}
+	return nil
+}
+
+func purge(args []string) error {
+	f := newFlags("purge")
+	keep := f.fs.Uint("keep", 0, "`number` of most recent backups to keep")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *keep == 0 {
+		return errors.New("-keep must be at least 1")
+	}
+	be, closeEngine, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer closeEngine()
+	before := len(be.GetBackupInfo())
+	if err := be.PurgeOldBackups(uint32(*keep)); err != nil {
+		return err
+	}
+	fmt.Printf("purged %d backups\n", before-len(be.GetBackupInfo()))
+	return nil
+}
+
+func restore(args []string) error {
+	f := newFlags("restore")
+	dbDir := f.fs.String("db", "", "database `directory` to restore into")
+	walDir := f.fs.String("wal", "", "write ahead log `directory`, default the database directory")
+	id := f.fs.Uint("id", 0, "backup `ID` to restore, 0 for the latest")
+	keepLogs := f.fs.Bool("keep-log-files", false, "keep the log files already in the WAL directory")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *dbDir == "" {
+		return errors.New("-db is required")
+	}
+	if *walDir == "" {
+		*walDir = *dbDir
+	}
+	be, closeEngine, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer closeEngine()
+
+	ro := gorocks.NewRestoreOptions()
+	defer ro.Close()
+	ro.SetKeepLogFiles(*keepLogs)
+	if *id != 0 {
+		err = be.RestoreDBFromBackup(uint32(*id), *dbDir, *walDir, ro)
+	} else {
+		err = be.RestoreDBFromLatestBackup(*dbDir, *walDir, ro)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored into %s\n", *dbDir)
+	return nil
+}
@@ -0,0 +1,223 @@
+package gorocks
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"unsafe"
+)
+
+// ColumnFamilyHandle is a reference to a single column family of an open DB.
+// It is passed to the *CF variants of the DB methods to direct them at that
+// column family instead of the default one.
+//
+// To prevent memory leaks, Close must be called on a ColumnFamilyHandle when
+// the program no longer needs it. All handles must be closed before the DB
+// that created them.
+type ColumnFamilyHandle struct {
+	Handle *C.rocksdb_column_family_handle_t
+}
+
+// DefaultColumnFamilyName is the name of the column family every database
+// has. It must be included when opening a database with OpenColumnFamilies.
+const DefaultColumnFamilyName = "default"
+
+// ColumnFamilyDescriptor pairs the name of a column family with the Options
+// it is opened with, so that each column family can have its own
+// compression, write buffer sizes, filter policy and so on.
+type ColumnFamilyDescriptor struct {
+	Name    string
+	Options *Options
+}
+
+// OpenWithDescriptors is like OpenColumnFamilies, but takes the column
+// families as descriptors. The returned handles are in the same order as
+// the descriptors.
+func OpenWithDescriptors(dbname string, o *Options, cfs []ColumnFamilyDescriptor) (*DB, []*ColumnFamilyHandle, error) {
+	names := make([]string, len(cfs))
+	opts := make([]*Options, len(cfs))
+	for i, cf := range cfs {
+		names[i] = cf.Name
+		opts[i] = cf.Options
+	}
+	return OpenColumnFamilies(dbname, o, names, opts)
+}
+
+// OpenColumnFamilies opens a database along with the named column families,
+// each configured with the Options at the same position in cfOpts. The
+// returned handles are in the same order as cfNames.
+//
+// All column families that exist in the database, including
+// DefaultColumnFamilyName, must be listed. Calling SetCreateIfMissing(true)
+// on o creates the database, and SetCreateMissingColumnFamilies(true) creates
+// any of the listed column families that do not exist yet.
+func OpenColumnFamilies(dbname string, o *Options, cfNames []string, cfOpts []*Options) (*DB, []*ColumnFamilyHandle, error) {
+	if len(cfNames) != len(cfOpts) {
+		return nil, nil, DatabaseError("column family names and options must have the same length")
+	}
+	if len(cfNames) == 0 {
+		return nil, nil, DatabaseError("at least the default column family must be opened")
+	}
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	cnames := make([]*C.char, len(cfNames))
+	copts := make([]*C.rocksdb_options_t, len(cfOpts))
+	for i, name := range cfNames {
+		cnames[i] = C.CString(name)
+		defer C.free(unsafe.Pointer(cnames[i]))
+		copts[i] = cfOpts[i].Opt
+	}
+	chandles := make([]*C.rocksdb_column_family_handle_t, len(cfNames))
+
+	rocksdb := C.rocksdb_open_column_families(o.Opt, ldbname,
+		C.int(len(cfNames)), &cnames[0], &copts[0], &chandles[0], &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, nil, openError(dbname, gs)
+	}
+
+	handles := make([]*ColumnFamilyHandle, len(chandles))
+	for i, h := range chandles {
+		handles[i] = &ColumnFamilyHandle{h}
+	}
+	return newDB(rocksdb, dbname), handles, nil
+}
+
+// ListColumnFamilies returns the names of the column families in the
+// database at path, which must not be open in this process. It is useful to
+// discover the names that must be passed to OpenColumnFamilies.
+func ListColumnFamilies(path string, o *Options) ([]string, error) {
+	var errStr *C.char
+	var n C.size_t
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	cnames := C.rocksdb_list_column_families(o.Opt, cpath, &n, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	defer C.rocksdb_list_column_families_destroy(cnames, n)
+
+	names := make([]string, int(n))
+	cnameSlice := (*[1 << 30]*C.char)(unsafe.Pointer(cnames))[:n:n]
+	for i, cname := range cnameSlice {
+		names[i] = C.GoString(cname)
+	}
+	return names, nil
+}
+
+// DefaultColumnFamily returns a handle to the default column family of the
+// database. Like any other handle, it must be closed.
+func (db *DB) DefaultColumnFamily() *ColumnFamilyHandle {
+	return &ColumnFamilyHandle{C.rocksdb_get_default_column_family_handle(db.Ldb)}
+}
+
+// CreateColumnFamily creates a new column family in the open database and
+// returns a handle to it.
+func (db *DB) CreateColumnFamily(o *Options, name string) (*ColumnFamilyHandle, error) {
+	var errStr *C.char
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	handle := C.rocksdb_create_column_family(db.Ldb, o.Opt, cname, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	return &ColumnFamilyHandle{handle}, nil
+}
+
+// DropColumnFamily removes the column family and all of its data from the
+// database. The handle must still be closed afterwards.
+func (db *DB) DropColumnFamily(cf *ColumnFamilyHandle) error {
+	var errStr *C.char
+	C.rocksdb_drop_column_family(db.Ldb, cf.Handle, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// LevelMetaData describes one level of a column family.
+type LevelMetaData struct {
+	Level     int
+	Size      uint64
+	FileCount int
+}
+
+// ColumnFamilyMetaData describes the on-disk state of a column family.
+type ColumnFamilyMetaData struct {
+	Name      string
+	Size      uint64
+	FileCount int
+	Levels    []LevelMetaData
+
+	// EstimatedNumKeys is the "rocksdb.estimate-num-keys" property of the
+	// column family.
+	EstimatedNumKeys uint64
+}
+
+// GetColumnFamilyMetaData returns the size, per-level file counts and
+// estimated number of keys of the given column family, or of the default
+// column family if cf is nil. It helps operators see which column family is
+// consuming space.
+func (db *DB) GetColumnFamilyMetaData(cf *ColumnFamilyHandle) ColumnFamilyMetaData {
+	var cmeta *C.rocksdb_column_family_metadata_t
+	var meta ColumnFamilyMetaData
+	if cf == nil {
+		cmeta = C.rocksdb_get_column_family_metadata(db.Ldb)
+		meta.EstimatedNumKeys, _ = db.IntPropertyValue("rocksdb.estimate-num-keys")
+	} else {
+		cmeta = C.rocksdb_get_column_family_metadata_cf(db.Ldb, cf.Handle)
+		meta.EstimatedNumKeys, _ = db.IntPropertyValueCF(cf, "rocksdb.estimate-num-keys")
+	}
+	defer C.rocksdb_column_family_metadata_destroy(cmeta)
+
+	cname := C.rocksdb_column_family_metadata_get_name(cmeta)
+	meta.Name = C.GoString(cname)
+	C.free(unsafe.Pointer(cname))
+	meta.Size = uint64(C.rocksdb_column_family_metadata_get_size(cmeta))
+	meta.FileCount = int(C.rocksdb_column_family_metadata_get_file_count(cmeta))
+
+	n := C.rocksdb_column_family_metadata_get_level_count(cmeta)
+	meta.Levels = make([]LevelMetaData, int(n))
+	for i := C.size_t(0); i < n; i++ {
+		lmeta := C.rocksdb_column_family_metadata_get_level_metadata(cmeta, i)
+		meta.Levels[i] = LevelMetaData{
+			Level:     int(C.rocksdb_level_metadata_get_level(lmeta)),
+			Size:      uint64(C.rocksdb_level_metadata_get_size(lmeta)),
+			FileCount: int(C.rocksdb_level_metadata_get_file_count(lmeta)),
+		}
+		C.rocksdb_level_metadata_destroy(lmeta)
+	}
+	return meta
+}
+
+// ID returns the numeric ID RocksDB assigned to the column family. It is
+// the ID reported in Record.ColumnFamilyID.
+func (cf *ColumnFamilyHandle) ID() uint32 {
+	return uint32(C.rocksdb_column_family_handle_get_id(cf.Handle))
+}
+
+// Name returns the name of the column family.
+func (cf *ColumnFamilyHandle) Name() string {
+	var size C.size_t
+	cname := C.rocksdb_column_family_handle_get_name(cf.Handle, &size)
+	defer C.free(unsafe.Pointer(cname))
+	return C.GoStringN(cname, C.int(size))
+}
+
+// Close deallocates the ColumnFamilyHandle, freeing its underlying C struct.
+// The column family itself is left untouched in the database.
+func (cf *ColumnFamilyHandle) Close() {
+	C.rocksdb_column_family_handle_destroy(cf.Handle)
+}
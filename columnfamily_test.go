@@ -0,0 +1,330 @@
+package gorocks
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOpenColumnFamilies(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCreateMissingColumnFamilies(true)
+	defer options.Close()
+
+	names := []string{DefaultColumnFamilyName, "cold"}
+	db, handles, err := OpenColumnFamilies(dbname, options, names, []*Options{options, options})
+	if err != nil {
+		t.Fatalf("OpenColumnFamilies failed: %v", err)
+	}
+	if len(handles) != 2 {
+		t.Fatalf("expected 2 handles, got %d", len(handles))
+	}
+	for i, h := range handles {
+		if h.Name() != names[i] {
+			t.Errorf("handle %d: expected name %q, got %q", i, names[i], h.Name())
+		}
+	}
+	if handles[0].ID() != 0 || handles[1].ID() == 0 {
+		t.Errorf("unexpected column family IDs %d, %d", handles[0].ID(), handles[1].ID())
+	}
+	if _, ok := db.IntPropertyValueCF(handles[1], "rocksdb.estimate-num-keys"); !ok {
+		t.Errorf("rocksdb.estimate-num-keys should be available per column family")
+	}
+	meta := db.GetColumnFamilyMetaData(handles[1])
+	if meta.Name != "cold" || len(meta.Levels) == 0 {
+		t.Errorf("unexpected column family metadata %+v", meta)
+	}
+	for _, h := range handles {
+		h.Close()
+	}
+	db.Close()
+
+	listed, err := ListColumnFamilies(dbname, options)
+	if err != nil {
+		t.Fatalf("ListColumnFamilies failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0] != names[0] || listed[1] != names[1] {
+		t.Errorf("expected column families %v, got %v", names, listed)
+	}
+
+	_, _, err = OpenColumnFamilies(dbname, options, []string{DefaultColumnFamilyName}, []*Options{options})
+	if err == nil {
+		t.Errorf("opening without listing every column family should fail")
+	}
+}
+
+func TestCreateDropColumnFamily(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	cf, err := db.CreateColumnFamily(options, "tmp")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	if _, err := db.CreateColumnFamily(options, "tmp"); err == nil {
+		t.Errorf("creating an existing column family should fail")
+	}
+	if err := db.DropColumnFamily(cf); err != nil {
+		t.Errorf("DropColumnFamily failed: %v", err)
+	}
+	cf.Close()
+}
+
+func TestColumnFamilyGetPutDelete(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	cf, err := db.CreateColumnFamily(options, "other")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	defer cf.Close()
+
+	key := []byte("key")
+	if err := db.PutCF(wo, cf, key, []byte("cf value")); err != nil {
+		t.Errorf("PutCF failed: %v", err)
+	}
+	CheckGet(t, "default after PutCF", db, ro, key, nil)
+	value, err := db.GetCF(ro, cf, key)
+	if err != nil || string(value) != "cf value" {
+		t.Errorf("GetCF: expected %q, got %q, %v", "cf value", value, err)
+	}
+
+	it := db.NewIteratorCF(ro, cf)
+	it.SeekToFirst()
+	CheckIter(t, it, key, []byte("cf value"))
+	it.Next()
+	if it.Valid() {
+		t.Errorf("column family iterator should only see its own key")
+	}
+	it.Close()
+
+	db.Put(wo, key, []byte("default value"))
+	def := db.DefaultColumnFamily()
+	values, err := db.MultiGetCF(ro, []*ColumnFamilyHandle{cf, def, cf}, [][]byte{key, key, []byte("missing")})
+	def.Close()
+	if err != nil {
+		t.Errorf("MultiGetCF failed: %v", err)
+	} else if string(values[0]) != "cf value" || string(values[1]) != "default value" || values[2] != nil {
+		t.Errorf("unexpected MultiGetCF values %q", values)
+	}
+
+	if err := db.DeleteCF(wo, cf, key); err != nil {
+		t.Errorf("DeleteCF failed: %v", err)
+	}
+	if value, _ := db.GetCF(ro, cf, key); value != nil {
+		t.Errorf("GetCF after DeleteCF should return nil, got %q", value)
+	}
+}
+
+func TestOpenWithDescriptors(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCreateMissingColumnFamilies(true)
+	defer options.Close()
+	cold := NewOptions()
+	cold.SetCompression(SnappyCompression)
+	cold.SetWriteBufferSize(1 << 20)
+	defer cold.Close()
+
+	db, handles, err := OpenWithDescriptors(dbname, options, []ColumnFamilyDescriptor{
+		{DefaultColumnFamilyName, options},
+		{"cold", cold},
+	})
+	if err != nil {
+		t.Fatalf("OpenWithDescriptors failed: %v", err)
+	}
+	if handles[1].Name() != "cold" {
+		t.Errorf("expected second handle to be cold, got %q", handles[1].Name())
+	}
+	for _, h := range handles {
+		h.Close()
+	}
+	db.Close()
+}
+
+func TestExportImportColumnFamily(t *testing.T) {
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+
+	srcname := tempDir(t)
+	defer deleteDBDirectory(t, srcname)
+	src, err := Open(srcname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer src.Close()
+	shard, err := src.CreateColumnFamily(options, "shard")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	defer shard.Close()
+	src.PutCF(wo, shard, []byte("a"), []byte("1"))
+	src.PutCF(wo, shard, []byte("b"), []byte("2"))
+
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	paths, err := src.ExportColumnFamily(shard, dir, options)
+	if err != nil {
+		t.Fatalf("ExportColumnFamily failed: %v", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("expected 1 exported file, got %v", paths)
+	}
+
+	dstname := tempDir(t)
+	defer deleteDBDirectory(t, dstname)
+	dst, err := Open(dstname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer dst.Close()
+	imported, err := dst.ImportColumnFamily(options, "shard", dir)
+	if err != nil {
+		t.Fatalf("ImportColumnFamily failed: %v", err)
+	}
+	defer imported.Close()
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}} {
+		v, err := dst.GetCF(ro, imported, []byte(kv[0]))
+		if err != nil || string(v) != kv[1] {
+			t.Errorf("%s: got %q, %v; want %q", kv[0], v, err, kv[1])
+		}
+	}
+}
+
+func TestIndexerRejectsRangeDeletion(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	cf, err := db.CreateColumnFamily(options, "index")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	defer cf.Close()
+	ix := NewIndexer(db, cf)
+	defer ix.Close()
+
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.DeleteRange([]byte("a"), []byte("z"))
+	if err := ix.Write(wo, wb); err == nil {
+		t.Errorf("Indexer.Write accepted a range deletion")
+	}
+}
+
+func TestIndexerKeepsLogData(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	cf, err := db.CreateColumnFamily(options, "index")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	defer cf.Close()
+	ix := NewIndexer(db, cf)
+	defer ix.Close()
+
+	var tags []SchemaTag
+	db.SetWriteHook(func(seq uint64, batch *WriteBatch) {
+		it := batch.NewIterator()
+		for it.Next() {
+			if rec := it.Record(); rec.Type == RecordTypeValue && string(rec.Key) == "a" {
+				if tag, ok := it.Schema(); ok {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	})
+	defer db.SetWriteHook(nil)
+
+	wb := NewWriteBatch()
+	defer wb.Close()
+	want := SchemaTag{Name: "user", Version: 2}
+	wb.PutSchemaTag(want)
+	wb.Put([]byte("a"), []byte("1"))
+	if err := ix.Write(wo, wb); err != nil {
+		t.Fatalf("Indexer.Write failed: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != want {
+		t.Errorf("schema tags seen by the write hook = %v, want [%v]", tags, want)
+	}
+}
+
+func TestCheckFileChecksumsColumnFamily(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCreateMissingColumnFamilies(true)
+	defer options.Close()
+	names := []string{DefaultColumnFamilyName, "other"}
+	db, handles, err := OpenColumnFamilies(dbname, options, names, []*Options{options, options})
+	if err != nil {
+		t.Fatalf("OpenColumnFamilies failed: %v", err)
+	}
+	defer db.Close()
+	defer handles[1].Close()
+
+	files := []LiveFileMetadata{
+		{Name: "/000010.sst", ColumnFamily: DefaultColumnFamilyName, SmallestKey: []byte("a"), LargestKey: []byte("b")},
+		{Name: "/000011.sst", ColumnFamily: "other", SmallestKey: []byte("a"), LargestKey: []byte("b")},
+	}
+	report := &StartupReport{}
+	db.checkFileChecksums(files, map[string]*ColumnFamilyHandle{DefaultColumnFamilyName: nil}, -1, bytes.Compare, report)
+	if report.ChecksumFilesChecked != 1 {
+		t.Errorf("without a handle for \"other\", checked %d files, want 1", report.ChecksumFilesChecked)
+	}
+
+	report = &StartupReport{}
+	cfs := map[string]*ColumnFamilyHandle{DefaultColumnFamilyName: nil, "other": handles[1]}
+	db.checkFileChecksums(files, cfs, -1, bytes.Compare, report)
+	if report.ChecksumFilesChecked != 2 || len(report.ChecksumErrors) != 0 {
+		t.Errorf("with every handle, checked %d files with errors %v, want 2 and none", report.ChecksumFilesChecked, report.ChecksumErrors)
+	}
+}
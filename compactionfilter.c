@@ -0,0 +1,56 @@
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+#include "_cgo_export.h"
+
+/* Compaction filters implemented in Go. The factory's state is a cgo.Handle
+   of the goCompactionFilter and is shared by the filters it creates, one
+   per compaction, which forward to the exported Go functions in
+   compactionfilter.go. */
+
+/* RocksDB copies a changed value right after the filter returns but never
+   frees it, so the last one returned on each thread is kept here and freed
+   on the next call. */
+static _Thread_local char* gorocks_compactionfilter_new_value;
+
+static void gorocks_compactionfilter_destructor(void* state) {
+	/* The state belongs to the factory. */
+}
+
+static unsigned char gorocks_compactionfilter_filter(void* state, int level,
+		const char* key, size_t key_length,
+		const char* existing_value, size_t value_length,
+		char** new_value, size_t* new_value_length, unsigned char* value_changed) {
+	free(gorocks_compactionfilter_new_value);
+	gorocks_compactionfilter_new_value = NULL;
+	unsigned char remove = gorocksCompactionFilterFilter((uintptr_t)state, level,
+		(char*)key, key_length, (char*)existing_value, value_length,
+		new_value, new_value_length, value_changed);
+	if (*value_changed) {
+		gorocks_compactionfilter_new_value = *new_value;
+	}
+	return remove;
+}
+
+static const char* gorocks_compactionfilter_name(void* state) {
+	return gorocksCompactionFilterName((uintptr_t)state);
+}
+
+static rocksdb_compactionfilter_t* gorocks_compactionfilterfactory_create_filter(void* state,
+		rocksdb_compactionfiltercontext_t* context) {
+	return rocksdb_compactionfilter_create(state,
+		gorocks_compactionfilter_destructor,
+		gorocks_compactionfilter_filter,
+		gorocks_compactionfilter_name);
+}
+
+static void gorocks_compactionfilterfactory_destructor(void* state) {
+	gorocksCompactionFilterDestroy((uintptr_t)state);
+}
+
+rocksdb_compactionfilterfactory_t* gorocks_compactionfilterfactory_create(uintptr_t state) {
+	return rocksdb_compactionfilterfactory_create((void*)state,
+		gorocks_compactionfilterfactory_destructor,
+		gorocks_compactionfilterfactory_create_filter,
+		gorocks_compactionfilter_name);
+}
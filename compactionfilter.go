@@ -0,0 +1,82 @@
+package gorocks
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern rocksdb_compactionfilterfactory_t* gorocks_compactionfilterfactory_create(uintptr_t state);
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+)
+
+// CompactionFilter decides, while compaction rewrites the data, which
+// key-value pairs to keep, drop or change. It makes it possible to expire
+// data lazily, for example by time, without scanning for it and issuing
+// deletes. It is installed with Options.SetCompactionFilter.
+//
+// Filter is called from RocksDB's compaction threads and must be safe for
+// concurrent use. The slices passed to it are only valid for the duration
+// of the call. A panic in Filter crashes the program.
+type CompactionFilter interface {
+	// Name identifies the filter in RocksDB's logs.
+	Name() string
+
+	// Filter is called for each value found by a compaction of the given
+	// level. It returns true to remove the key, or otherwise a new value to
+	// replace the current one with, or nil to keep the value as it is.
+	// Removing a key can bring back an older value of it that has not been
+	// compacted yet, so filters are best used on keys that are not
+	// overwritten. Deletions and merge operands are not passed to Filter.
+	Filter(level int, key, value []byte) (remove bool, newValue []byte)
+}
+
+// goCompactionFilter is the state of a CompactionFilter handed to RocksDB.
+type goCompactionFilter struct {
+	f    CompactionFilter
+	name *C.char
+}
+
+// SetCompactionFilter sets the CompactionFilter applied by compactions.
+// RocksDB holds on to the filter until the Options and every database
+// opened with them have been closed.
+func (o *Options) SetCompactionFilter(f CompactionFilter) {
+	state := &goCompactionFilter{f: f, name: C.CString(f.Name())}
+	factory := C.gorocks_compactionfilterfactory_create(C.uintptr_t(cgo.NewHandle(state)))
+	C.rocksdb_options_set_compaction_filter_factory(o.Opt, factory)
+}
+
+func compactionFilterState(state C.uintptr_t) *goCompactionFilter {
+	return cgo.Handle(state).Value().(*goCompactionFilter)
+}
+
+//export gorocksCompactionFilterFilter
+func gorocksCompactionFilterFilter(state C.uintptr_t, level C.int, key *C.char, keyLen C.size_t, value *C.char, valueLen C.size_t, newValue **C.char, newValueLen *C.size_t, valueChanged *C.uchar) C.uchar {
+	s := compactionFilterState(state)
+	remove, nv := s.f.Filter(int(level), cBytes(key, keyLen), cBytes(value, valueLen))
+	if remove {
+		return 1
+	}
+	if nv != nil {
+		*newValue = (*C.char)(C.CBytes(nv))
+		*newValueLen = C.size_t(len(nv))
+		*valueChanged = 1
+	}
+	return 0
+}
+
+//export gorocksCompactionFilterName
+func gorocksCompactionFilterName(state C.uintptr_t) *C.char {
+	return compactionFilterState(state).name
+}
+
+//export gorocksCompactionFilterDestroy
+func gorocksCompactionFilterDestroy(state C.uintptr_t) {
+	h := cgo.Handle(state)
+	C.free(unsafe.Pointer(h.Value().(*goCompactionFilter).name))
+	h.Delete()
+}
@@ -0,0 +1,93 @@
+package gorocks
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// expiringFilter removes keys starting with "tmp/" and upper-cases the
+// values of keys starting with "up/".
+type expiringFilter struct{}
+
+func (expiringFilter) Name() string { return "test.expiring" }
+
+func (expiringFilter) Filter(level int, key, value []byte) (bool, []byte) {
+	switch {
+	case bytes.HasPrefix(key, []byte("tmp/")):
+		return true, nil
+	case bytes.HasPrefix(key, []byte("up/")):
+		return false, bytes.ToUpper(value)
+	}
+	return false, nil
+}
+
+func TestCompactionFilter(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCompactionFilter(expiringFilter{})
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("tmp/1"), []byte("scratch"))
+	db.Put(wo, []byte("up/1"), []byte("shout"))
+	db.Put(wo, []byte("keep"), []byte("as is"))
+	db.CompactRange(Range{})
+
+	CheckGet(t, "removed", db, ro, []byte("tmp/1"), nil)
+	CheckGet(t, "changed", db, ro, []byte("up/1"), []byte("SHOUT"))
+	CheckGet(t, "kept", db, ro, []byte("keep"), []byte("as is"))
+}
+
+func TestTTLFilter(t *testing.T) {
+	for _, pos := range []TimestampPosition{TimestampPrefix, TimestampSuffix} {
+		now := time.Unix(1000000, 0)
+		f := NewTTLFilter(time.Hour, pos)
+		f.Clock = func() time.Time { return now }
+
+		dbname := tempDir(t)
+		options := NewOptions()
+		options.SetCreateIfMissing(true)
+		options.SetCompactionFilter(f)
+		ro := NewReadOptions()
+		wo := NewWriteOptions()
+		db, err := Open(dbname, options)
+		if err != nil {
+			t.Fatalf("Database could not be opened: %v", err)
+		}
+
+		old := f.Stamp([]byte("old"), now.Add(-2*time.Hour))
+		fresh := f.Stamp([]byte("fresh"), now.Add(-time.Minute))
+		db.Put(wo, []byte("old"), old)
+		db.Put(wo, []byte("fresh"), fresh)
+		db.Put(wo, []byte("short"), []byte("x"))
+		if v, ts, ok := f.Split(fresh); !ok || string(v) != "fresh" || !ts.Equal(now.Add(-time.Minute)) {
+			t.Errorf("Split(%q) = %q, %v, %v", fresh, v, ts, ok)
+		}
+		db.CompactRange(Range{})
+
+		CheckGet(t, "expired", db, ro, []byte("old"), nil)
+		CheckGet(t, "fresh", db, ro, []byte("fresh"), fresh)
+		CheckGet(t, "short", db, ro, []byte("short"), []byte("x"))
+
+		now = now.Add(time.Hour)
+		db.CompactRange(Range{})
+		CheckGet(t, "expired later", db, ro, []byte("fresh"), nil)
+
+		db.Close()
+		ro.Close()
+		wo.Close()
+		options.Close()
+		deleteDBDirectory(t, dbname)
+	}
+}
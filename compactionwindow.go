@@ -0,0 +1,131 @@
+package gorocks
+
+import (
+	"context"
+	"time"
+)
+
+// DailyWindow is a period of every day, given as offsets from midnight. A
+// window whose End is before its Start wraps around midnight.
+type DailyWindow struct {
+	Start, End time.Duration
+}
+
+// contains reports whether the time of day d falls into the window.
+func (w DailyWindow) contains(d time.Duration) bool {
+	if w.Start <= w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// CompactionSchedule configures a CompactionScheduler.
+type CompactionSchedule struct {
+	// BusyHours are the windows during which automatic compactions are
+	// disabled, so that they do not compete with foreground traffic.
+	BusyHours []DailyWindow
+	// Location is the time zone of BusyHours. Nil means UTC.
+	Location *time.Location
+	// CompactOffPeak, if set, makes the scheduler compact the whole
+	// database once after every busy period, to catch up on the work that
+	// was put off.
+	CompactOffPeak bool
+	// SetBusy, if set, is called whenever the schedule switches between
+	// busy and off-peak hours, for additional throttling such as lowering a
+	// rate limiter.
+	SetBusy func(busy bool)
+	// CheckInterval is how often Run checks the schedule. Zero means once a
+	// minute.
+	CheckInterval time.Duration
+}
+
+// CompactionScheduler moves compaction work of a DB out of busy hours. It
+// disables automatic compactions with SetOptions while the schedule is busy
+// and re-enables them, optionally followed by a manual compaction,
+// afterwards.
+//
+// A CompactionScheduler is driven by Run, or by calling Tick directly, from
+// a single goroutine.
+type CompactionScheduler struct {
+	db    *DB
+	sched CompactionSchedule
+	busy  bool
+	// started is set once the first Tick has applied the schedule.
+	started bool
+}
+
+// NewCompactionScheduler returns a CompactionScheduler for db.
+func NewCompactionScheduler(db *DB, sched CompactionSchedule) *CompactionScheduler {
+	if sched.Location == nil {
+		sched.Location = time.UTC
+	}
+	if sched.CheckInterval <= 0 {
+		sched.CheckInterval = time.Minute
+	}
+	return &CompactionScheduler{db: db, sched: sched}
+}
+
+// Busy reports whether now falls into the schedule's busy hours.
+func (cs *CompactionScheduler) Busy(now time.Time) bool {
+	now = now.In(cs.sched.Location)
+	y, m, d := now.Date()
+	sinceMidnight := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, cs.sched.Location))
+	for _, w := range cs.sched.BusyHours {
+		if w.contains(sinceMidnight) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tick applies the schedule for now, switching the database between busy
+// and off-peak settings if needed.
+func (cs *CompactionScheduler) Tick(now time.Time) error {
+	busy := cs.Busy(now)
+	if cs.started && busy == cs.busy {
+		return nil
+	}
+	wasBusy := cs.started && cs.busy
+	disable := "false"
+	if busy {
+		disable = "true"
+	}
+	if err := cs.db.SetOptions(map[string]string{"disable_auto_compactions": disable}); err != nil {
+		return err
+	}
+	cs.busy, cs.started = busy, true
+	if cs.sched.SetBusy != nil {
+		cs.sched.SetBusy(busy)
+	}
+	if wasBusy && cs.sched.CompactOffPeak {
+		cs.db.CompactRange(Range{})
+	}
+	return nil
+}
+
+// Run calls Tick every CheckInterval until ctx is done, and then re-enables
+// automatic compactions. It returns ctx.Err(), or the first error from
+// Tick.
+func (cs *CompactionScheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cs.sched.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := cs.Tick(time.Now()); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if cs.busy {
+				if err := cs.db.SetOptions(map[string]string{"disable_auto_compactions": "false"}); err != nil {
+					return err
+				}
+				cs.busy = false
+				if cs.sched.SetBusy != nil {
+					cs.sched.SetBusy(false)
+				}
+			}
+			return ctx.Err()
+		}
+	}
+}
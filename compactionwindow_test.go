@@ -0,0 +1,71 @@
+package gorocks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompactionScheduler(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	var calls []bool
+	cs := NewCompactionScheduler(db, CompactionSchedule{
+		BusyHours: []DailyWindow{
+			{Start: 9 * time.Hour, End: 17 * time.Hour},
+			{Start: 23 * time.Hour, End: time.Hour},
+		},
+		CompactOffPeak: true,
+		SetBusy:        func(busy bool) { calls = append(calls, busy) },
+	})
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		at   time.Duration
+		busy bool
+	}{
+		{8 * time.Hour, false},
+		{9 * time.Hour, true},
+		{16*time.Hour + 59*time.Minute, true},
+		{17 * time.Hour, false},
+		{23*time.Hour + 30*time.Minute, true},
+		{24*time.Hour + 30*time.Minute, true},
+		{25 * time.Hour, false},
+	} {
+		if busy := cs.Busy(day.Add(c.at)); busy != c.busy {
+			t.Errorf("Busy at %v = %v, expected %v", c.at, busy, c.busy)
+		}
+	}
+
+	disabled := func() bool {
+		opts, err := db.OptionsFile()
+		if err != nil {
+			t.Fatalf("OptionsFile failed: %v", err)
+		}
+		return strings.Contains(opts, "disable_auto_compactions=true")
+	}
+	if err := cs.Tick(day.Add(10 * time.Hour)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if !disabled() {
+		t.Errorf("auto compactions should be disabled during busy hours")
+	}
+	cs.Tick(day.Add(11 * time.Hour))
+	if err := cs.Tick(day.Add(18 * time.Hour)); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if disabled() {
+		t.Errorf("auto compactions should be enabled off-peak")
+	}
+	if len(calls) != 2 || !calls[0] || calls[1] {
+		t.Errorf("unexpected SetBusy calls %v", calls)
+	}
+}
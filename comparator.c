@@ -0,0 +1,148 @@
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include "rocksdb/c.h"
+#include "_cgo_export.h"
+
+/* Comparators implemented in Go. The state is a cgo.Handle of the
+   goComparator; the callbacks forward to the exported Go functions in
+   comparator.go. */
+
+static void gorocks_comparator_destructor(void* state) {
+	gorocksComparatorDestroy((uintptr_t)state);
+}
+
+static int gorocks_comparator_compare(void* state,
+		const char* a, size_t alen, const char* b, size_t blen) {
+	return gorocksComparatorCompare((uintptr_t)state,
+		(char*)a, alen, (char*)b, blen);
+}
+
+static const char* gorocks_comparator_name(void* state) {
+	return gorocksComparatorName((uintptr_t)state);
+}
+
+rocksdb_comparator_t* gorocks_comparator_create(uintptr_t state) {
+	return rocksdb_comparator_create((void*)state,
+		gorocks_comparator_destructor,
+		gorocks_comparator_compare,
+		gorocks_comparator_name);
+}
+
+/* The reverse bytewise comparator, implemented in C to avoid calling into
+   Go for every comparison. */
+
+static int gorocks_reverse_bytewise_compare(void* state,
+		const char* a, size_t alen, const char* b, size_t blen) {
+	size_t n = alen < blen ? alen : blen;
+	int r = memcmp(a, b, n);
+	if (r == 0) {
+		if (alen < blen) {
+			r = -1;
+		} else if (alen > blen) {
+			r = 1;
+		}
+	}
+	return -r;
+}
+
+static void gorocks_reverse_bytewise_destructor(void* state) {
+}
+
+static const char* gorocks_reverse_bytewise_name(void* state) {
+	return "rocksdb.ReverseBytewiseComparator";
+}
+
+rocksdb_comparator_t* gorocks_comparator_create_reverse_bytewise(void) {
+	return rocksdb_comparator_create(NULL,
+		gorocks_reverse_bytewise_destructor,
+		gorocks_reverse_bytewise_compare,
+		gorocks_reverse_bytewise_name);
+}
+
+/* Comparators with user-defined timestamps implemented in Go. The keys
+   passed to compare include the timestamp. */
+
+static int gorocks_comparator_compare_ts(void* state,
+		const char* a_ts, size_t a_tslen, const char* b_ts, size_t b_tslen) {
+	return gorocksComparatorCompareTimestamp((uintptr_t)state,
+		(char*)a_ts, a_tslen, (char*)b_ts, b_tslen);
+}
+
+static int gorocks_comparator_compare_without_ts(void* state,
+		const char* a, size_t alen, unsigned char a_has_ts,
+		const char* b, size_t blen, unsigned char b_has_ts) {
+	return gorocksComparatorCompareWithoutTimestamp((uintptr_t)state,
+		(char*)a, alen, a_has_ts, (char*)b, blen, b_has_ts);
+}
+
+rocksdb_comparator_t* gorocks_comparator_create_with_ts(uintptr_t state, size_t timestamp_size) {
+	return rocksdb_comparator_with_ts_create((void*)state,
+		gorocks_comparator_destructor,
+		gorocks_comparator_compare,
+		gorocks_comparator_compare_ts,
+		gorocks_comparator_compare_without_ts,
+		gorocks_comparator_name,
+		timestamp_size);
+}
+
+/* The bytewise comparator with 8-byte little-endian uint64 timestamps,
+   implemented in C. Keys sort by their user key in ascending order and
+   then by timestamp in descending order, newest first. */
+
+static int gorocks_u64ts_compare_ts(void* state,
+		const char* a_ts, size_t a_tslen, const char* b_ts, size_t b_tslen) {
+	uint64_t a = 0, b = 0;
+	for (int i = 7; i >= 0; i--) {
+		a = (a << 8) | (unsigned char)a_ts[i];
+		b = (b << 8) | (unsigned char)b_ts[i];
+	}
+	return a < b ? -1 : a > b ? 1 : 0;
+}
+
+static int gorocks_u64ts_compare_without_ts(void* state,
+		const char* a, size_t alen, unsigned char a_has_ts,
+		const char* b, size_t blen, unsigned char b_has_ts) {
+	if (a_has_ts) {
+		alen -= 8;
+	}
+	if (b_has_ts) {
+		blen -= 8;
+	}
+	size_t n = alen < blen ? alen : blen;
+	int r = memcmp(a, b, n);
+	if (r == 0) {
+		if (alen < blen) {
+			r = -1;
+		} else if (alen > blen) {
+			r = 1;
+		}
+	}
+	return r;
+}
+
+static int gorocks_u64ts_compare(void* state,
+		const char* a, size_t alen, const char* b, size_t blen) {
+	int r = gorocks_u64ts_compare_without_ts(state, a, alen, 1, b, blen, 1);
+	if (r != 0) {
+		return r;
+	}
+	return -gorocks_u64ts_compare_ts(state, a + alen - 8, 8, b + blen - 8, 8);
+}
+
+static void gorocks_u64ts_destructor(void* state) {
+}
+
+static const char* gorocks_u64ts_name(void* state) {
+	return "leveldb.BytewiseComparator.u64ts";
+}
+
+rocksdb_comparator_t* gorocks_comparator_create_u64ts(void) {
+	return rocksdb_comparator_with_ts_create(NULL,
+		gorocks_u64ts_destructor,
+		gorocks_u64ts_compare,
+		gorocks_u64ts_compare_ts,
+		gorocks_u64ts_compare_without_ts,
+		gorocks_u64ts_name,
+		8);
+}
@@ -1,12 +1,216 @@
 package gorocks
 
-// #include "rocksdb/c.h"
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern rocksdb_comparator_t* gorocks_comparator_create(uintptr_t state);
+extern rocksdb_comparator_t* gorocks_comparator_create_reverse_bytewise(void);
+extern rocksdb_comparator_t* gorocks_comparator_create_with_ts(uintptr_t state, size_t timestamp_size);
+extern rocksdb_comparator_t* gorocks_comparator_create_u64ts(void);
+*/
 import "C"
 
-// DestroyComparator deallocates a *C.rocksdb_comparator_t.
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// Comparator defines the order of the keys in a database. It is installed
+// with Options.SetComparator.
+//
+// Compare is called from RocksDB's threads, for every key comparison made
+// by reads, writes, flushes and compactions, and must be safe for
+// concurrent use. Each call crosses from C into Go, so a Go comparator is
+// noticeably slower than the default bytewise one. The slices passed to
+// Compare are only valid for the duration of the call. A panic in Compare
+// crashes the program.
+type Comparator interface {
+	// Name identifies the order. It is recorded in the database, and a
+	// database must always be opened with a comparator of the same name.
+	// Change the name whenever Compare changes in a way that orders any
+	// two keys differently.
+	Name() string
+
+	// Compare returns a negative number if a sorts before b, zero if they
+	// are equal and a positive number if a sorts after b.
+	Compare(a, b []byte) int
+}
+
+// TimestampComparator is a Comparator for keys carrying a RocksDB
+// user-defined timestamp: a suffix of TimestampSize bytes that versions
+// the rest of the key, which makes it possible to read the database as of
+// a point in time. Compare is called with whole keys, and must order keys
+// first without their timestamps, then by timestamp, newest first.
+// Options.SetComparator installs it with timestamp support.
+type TimestampComparator interface {
+	Comparator
+
+	// TimestampSize is the length of the timestamps.
+	TimestampSize() int
+
+	// CompareTimestamp compares two timestamps, returning a negative number
+	// if a is older than b, zero if they are equal and a positive number if
+	// a is newer.
+	CompareTimestamp(a, b []byte) int
+
+	// CompareWithoutTimestamp compares two keys ignoring their timestamps.
+	// aHasTs and bHasTs report whether a and b end with a timestamp, which
+	// RocksDB omits in some comparisons.
+	CompareWithoutTimestamp(a []byte, aHasTs bool, b []byte, bHasTs bool) int
+}
+
+// goComparator is the state of a Comparator handed to RocksDB.
+type goComparator struct {
+	cmp  Comparator
+	name *C.char
+}
+
+// SetComparator sets the comparator to be used for all read and write
+// operations.
+//
+// The comparator that created a database must be the same one (technically,
+// one with the same name string) that is used to perform read and write
+// operations.
+//
+// If cmp is a TimestampComparator, it is installed with support for
+// user-defined timestamps of its TimestampSize.
+//
+// Unlike merge operators, RocksDB does not track the databases using a
+// comparator, so it is never freed: set comparators once, not per Open.
+//
+// The default comparator is usually sufficient.
+func (o *Options) SetComparator(cmp Comparator) {
+	var ccmp *C.rocksdb_comparator_t
+	switch c := cmp.(type) {
+	case reverseBytewiseComparator:
+		ccmp = C.gorocks_comparator_create_reverse_bytewise()
+	case u64TimestampComparator:
+		ccmp = C.gorocks_comparator_create_u64ts()
+	case TimestampComparator:
+		state := C.uintptr_t(cgo.NewHandle(&goComparator{cmp: cmp, name: C.CString(cmp.Name())}))
+		ccmp = C.gorocks_comparator_create_with_ts(state, C.size_t(c.TimestampSize()))
+	default:
+		state := C.uintptr_t(cgo.NewHandle(&goComparator{cmp: cmp, name: C.CString(cmp.Name())}))
+		ccmp = C.gorocks_comparator_create(state)
+	}
+	C.rocksdb_options_set_comparator(o.Opt, ccmp)
+	o.cmp = cmp
+}
+
+// NewReverseBytewiseComparator returns a Comparator that orders keys by
+// their bytes in descending order, the reverse of the default comparator.
+// It suits databases mostly scanned from newest to oldest key, since
+// forward iteration is faster than backward.
+//
+// When installed with Options.SetComparator, the comparator runs in C
+// without calling back into Go. Its name is that of RocksDB's own
+// ReverseBytewiseComparator, so databases created with either can be
+// opened with the other.
+func NewReverseBytewiseComparator() Comparator {
+	return reverseBytewiseComparator{}
+}
+
+type reverseBytewiseComparator struct{}
+
+func (reverseBytewiseComparator) Name() string { return "rocksdb.ReverseBytewiseComparator" }
+
+func (reverseBytewiseComparator) Compare(a, b []byte) int { return bytes.Compare(b, a) }
+
+// NewU64TimestampComparator returns a TimestampComparator for keys that end
+// with an 8-byte little-endian uint64 timestamp, made with AppendU64Timestamp.
+// Keys sort bytewise without their timestamps, then newest first.
 //
-// This is provided as a convienience to advanced users that have implemented
-// their own comparators in C in their own code.
-func DestroyComparator(cmp *C.rocksdb_comparator_t) {
-	C.rocksdb_comparator_destroy(cmp)
+// When installed with Options.SetComparator, the comparator runs in C
+// without calling back into Go. Its name is that of RocksDB's own
+// BytewiseComparatorWithU64Ts.
+func NewU64TimestampComparator() TimestampComparator {
+	return u64TimestampComparator{}
+}
+
+// AppendU64Timestamp appends ts to key in the format expected by the
+// comparator returned by NewU64TimestampComparator.
+func AppendU64Timestamp(key []byte, ts uint64) []byte {
+	return binary.LittleEndian.AppendUint64(key, ts)
+}
+
+type u64TimestampComparator struct{}
+
+func (u64TimestampComparator) Name() string { return "leveldb.BytewiseComparator.u64ts" }
+
+func (u64TimestampComparator) TimestampSize() int { return 8 }
+
+func (c u64TimestampComparator) Compare(a, b []byte) int {
+	if r := c.CompareWithoutTimestamp(a, true, b, true); r != 0 {
+		return r
+	}
+	return -c.CompareTimestamp(a[len(a)-8:], b[len(b)-8:])
+}
+
+func (u64TimestampComparator) CompareTimestamp(a, b []byte) int {
+	x, y := binary.LittleEndian.Uint64(a), binary.LittleEndian.Uint64(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (u64TimestampComparator) CompareWithoutTimestamp(a []byte, aHasTs bool, b []byte, bHasTs bool) int {
+	if aHasTs {
+		a = a[:len(a)-8]
+	}
+	if bHasTs {
+		b = b[:len(b)-8]
+	}
+	return bytes.Compare(a, b)
+}
+
+func comparatorState(state C.uintptr_t) *goComparator {
+	return cgo.Handle(state).Value().(*goComparator)
+}
+
+//export gorocksComparatorCompare
+func gorocksComparatorCompare(state C.uintptr_t, a *C.char, alen C.size_t, b *C.char, blen C.size_t) C.int {
+	return sign(comparatorState(state).cmp.Compare(cBytes(a, alen), cBytes(b, blen)))
+}
+
+//export gorocksComparatorCompareTimestamp
+func gorocksComparatorCompareTimestamp(state C.uintptr_t, a *C.char, alen C.size_t, b *C.char, blen C.size_t) C.int {
+	tc := comparatorState(state).cmp.(TimestampComparator)
+	return sign(tc.CompareTimestamp(cBytes(a, alen), cBytes(b, blen)))
+}
+
+//export gorocksComparatorCompareWithoutTimestamp
+func gorocksComparatorCompareWithoutTimestamp(state C.uintptr_t, a *C.char, alen C.size_t, aHasTs C.uchar, b *C.char, blen C.size_t, bHasTs C.uchar) C.int {
+	tc := comparatorState(state).cmp.(TimestampComparator)
+	return sign(tc.CompareWithoutTimestamp(cBytes(a, alen), aHasTs != 0, cBytes(b, blen), bHasTs != 0))
+}
+
+// sign maps the result of a comparison to -1, 0 or 1.
+func sign(c int) C.int {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	}
+	return 0
+}
+
+//export gorocksComparatorName
+func gorocksComparatorName(state C.uintptr_t) *C.char {
+	return comparatorState(state).name
+}
+
+//export gorocksComparatorDestroy
+func gorocksComparatorDestroy(state C.uintptr_t) {
+	h := cgo.Handle(state)
+	C.free(unsafe.Pointer(h.Value().(*goComparator).name))
+	h.Delete()
 }
@@ -0,0 +1,133 @@
+package gorocks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// bytewiseComparator orders keys like the default comparator, under
+// another name.
+type bytewiseComparator struct{ name string }
+
+func (c bytewiseComparator) Name() string { return c.name }
+
+func (bytewiseComparator) Compare(a, b []byte) int { return bytes.Compare(a, b) }
+
+// reverseComparator orders keys in descending byte order.
+type reverseComparator struct{}
+
+func (reverseComparator) Name() string { return "test.reverse" }
+
+func (reverseComparator) Compare(a, b []byte) int { return bytes.Compare(b, a) }
+
+func TestComparator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetComparator(reverseComparator{})
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+
+	for _, k := range []string{"b", "a", "c"} {
+		db.Put(wo, []byte(k), []byte(k))
+	}
+	db.CompactRange(Range{})
+
+	it := db.NewIterator(ro)
+	var got []string
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		got = append(got, string(it.Key()))
+	}
+	it.Close()
+	if s := strings.Join(got, " "); s != "c b a" {
+		t.Errorf("keys in order %q, want \"c b a\"", s)
+	}
+	db.Close()
+
+	other := NewOptions()
+	defer other.Close()
+	other.SetComparator(bytewiseComparator{"test.bytewise"})
+	if db, err := Open(dbname, other); err == nil {
+		db.Close()
+		t.Errorf("Open with a different comparator succeeded")
+	}
+}
+
+func TestReverseBytewiseComparator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetComparator(NewReverseBytewiseComparator())
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	for _, k := range []string{"a", "ab", "b", "\xff"} {
+		db.Put(wo, []byte(k), []byte(k))
+	}
+	db.CompactRange(Range{})
+
+	it := db.NewIterator(ro)
+	defer it.Close()
+	var got []string
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		got = append(got, string(it.Key()))
+	}
+	if s := strings.Join(got, " "); s != "\xff b ab a" {
+		t.Errorf("keys in order %q, want \"\\xff b ab a\"", s)
+	}
+	if c := NewReverseBytewiseComparator().Compare([]byte("a"), []byte("ab")); c <= 0 {
+		t.Errorf("Compare(a, ab) = %d, want > 0", c)
+	}
+}
+
+// goU64TimestampComparator is NewU64TimestampComparator under another name,
+// so that it is called through Go.
+type goU64TimestampComparator struct{ u64TimestampComparator }
+
+func (goU64TimestampComparator) Name() string { return "test.u64ts" }
+
+func TestU64TimestampComparator(t *testing.T) {
+	c := NewU64TimestampComparator()
+	key := func(k string, ts uint64) []byte { return AppendU64Timestamp([]byte(k), ts) }
+	ordered := [][]byte{key("a", 9), key("a", 2), key("ab", 5), key("b", 1)}
+	for i := 0; i+1 < len(ordered); i++ {
+		if c.Compare(ordered[i], ordered[i+1]) >= 0 {
+			t.Errorf("Compare(%q, %q) >= 0", ordered[i], ordered[i+1])
+		}
+	}
+	if c.CompareWithoutTimestamp(key("a", 9), true, []byte("a"), false) != 0 {
+		t.Errorf("CompareWithoutTimestamp did not ignore the timestamp")
+	}
+
+	for _, cmp := range []TimestampComparator{c, goU64TimestampComparator{}} {
+		dbname := tempDir(t)
+		options := NewOptions()
+		options.SetCreateIfMissing(true)
+		options.SetComparator(cmp)
+		db, err := Open(dbname, options)
+		if err != nil {
+			t.Fatalf("Database could not be opened with %s: %v", cmp.Name(), err)
+		}
+		db.Close()
+		options.Close()
+		deleteDBDirectory(t, dbname)
+	}
+}
@@ -1,8 +1,13 @@
 package gorocks
 
+// #include <stdlib.h>
 // #include "rocksdb/c.h"
 import "C"
 
+import (
+	"unsafe"
+)
+
 func boolToUchar(b bool) C.uchar {
 	if b {
 		return 1
@@ -23,3 +28,22 @@ func boolToInt(b bool) C.int {
 	}
 	return 0
 }
+
+// cSlices copies each of the given byte slices into C memory so that an
+// array of them may be handed to RocksDB. The returned pointers must be
+// released with freeCSlices.
+func cSlices(bs [][]byte) ([]*C.char, []C.size_t) {
+	ptrs := make([]*C.char, len(bs))
+	lens := make([]C.size_t, len(bs))
+	for i, b := range bs {
+		ptrs[i] = (*C.char)(C.CBytes(b))
+		lens[i] = C.size_t(len(b))
+	}
+	return ptrs, lens
+}
+
+func freeCSlices(ptrs []*C.char) {
+	for _, p := range ptrs {
+		C.free(unsafe.Pointer(p))
+	}
+}
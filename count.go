@@ -0,0 +1,70 @@
+package gorocks
+
+import (
+	"bytes"
+)
+
+// CountRange returns the exact number of keys from r.Start up to, but not
+// including, r.Limit, by scanning them without reading their values. A nil
+// Limit counts to the end of the database.
+func (db *DB) CountRange(ro *ReadOptions, r Range) (uint64, error) {
+	s := db.NewScanner(ro, r, ScanOptions{KeysOnly: true})
+	defer s.Close()
+	var n uint64
+	for s.Next() {
+		n++
+	}
+	return n, s.Err()
+}
+
+// ApproxCountRange estimates the number of keys in r without scanning the
+// SST files, for dashboards and similar uses where a rough estimate is good
+// enough. A nil Limit counts to the end of the database.
+//
+// Keys in the memtables are counted exactly, which is cheap since they are
+// in memory. For each SST file of the default column family that overlaps
+// r, the number of entries recorded in its table properties is added, less
+// its deletion markers. For a file only partly inside r, that number is
+// scaled by the share of the data between the file's smallest and largest
+// key that falls into r, which assumes keys of similar size throughout.
+// Overwritten keys and merge operands not yet compacted away count once per
+// version, as do keys present both in a memtable and in a file.
+//
+// Like Rewrite, ApproxCountRange assumes the default bytewise key order.
+func (db *DB) ApproxCountRange(r Range) (uint64, error) {
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetReadTier(MemtableTier)
+	n, err := db.CountRange(ro, r)
+	if err != nil {
+		return 0, err
+	}
+	estimate := float64(n)
+	for _, f := range db.LiveFiles() {
+		if f.ColumnFamily != DefaultColumnFamilyName ||
+			bytes.Compare(f.LargestKey, r.Start) < 0 ||
+			r.Limit != nil && bytes.Compare(f.SmallestKey, r.Limit) >= 0 {
+			continue
+		}
+		entries := float64(f.Entries - f.Deletions)
+		// span covers the file's keys, largest included.
+		span := Range{f.SmallestKey, append(f.LargestKey[:len(f.LargestKey):len(f.LargestKey)], 0)}
+		part := span
+		if bytes.Compare(r.Start, part.Start) > 0 {
+			part.Start = r.Start
+		}
+		if r.Limit != nil && bytes.Compare(r.Limit, part.Limit) < 0 {
+			part.Limit = r.Limit
+		}
+		if bytes.Equal(part.Start, span.Start) && bytes.Equal(part.Limit, span.Limit) {
+			estimate += entries
+			continue
+		}
+		sizes := db.GetApproximateSizes([]Range{span, part})
+		if sizes[0] > 0 {
+			entries *= float64(sizes[1]) / float64(sizes[0])
+		}
+		estimate += entries
+	}
+	return uint64(estimate), nil
+}
@@ -0,0 +1,69 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+// CuckooTableOptions configures the cuckoo table SST format, installed with
+// Options.SetCuckooTableFactory.
+//
+// To prevent memory leaks, Close must be called on a CuckooTableOptions
+// when the program no longer needs it.
+type CuckooTableOptions struct {
+	Opt *C.rocksdb_cuckoo_table_options_t
+}
+
+// NewCuckooTableOptions allocates a CuckooTableOptions with RocksDB's
+// defaults.
+func NewCuckooTableOptions() *CuckooTableOptions {
+	return &CuckooTableOptions{C.rocksdb_cuckoo_options_create()}
+}
+
+// Close deallocates the CuckooTableOptions, freeing its underlying C
+// struct. Options it was installed on are not affected.
+func (co *CuckooTableOptions) Close() {
+	C.rocksdb_cuckoo_options_destroy(co.Opt)
+}
+
+// SetHashRatio sets the target utilization of the hash table. Lower ratios
+// make lookups faster and files bigger. The default is 0.9.
+func (co *CuckooTableOptions) SetHashRatio(ratio float64) {
+	C.rocksdb_cuckoo_options_set_hash_ratio(co.Opt, C.double(ratio))
+}
+
+// SetMaxSearchDepth bounds the number of displacements tried when inserting
+// a key while building a file, before the table is grown instead. The
+// default is 100.
+func (co *CuckooTableOptions) SetMaxSearchDepth(depth uint32) {
+	C.rocksdb_cuckoo_options_set_max_search_depth(co.Opt, C.uint32_t(depth))
+}
+
+// SetCuckooBlockSize sets the number of consecutive buckets a key may be
+// placed in for each hash function, which lets a lookup read one cache
+// line instead of several. The default is 5.
+func (co *CuckooTableOptions) SetCuckooBlockSize(size uint32) {
+	C.rocksdb_cuckoo_options_set_cuckoo_block_size(co.Opt, C.uint32_t(size))
+}
+
+// SetIdentityAsFirstHash uses the first 8 bytes of each key, which must be
+// 8 bytes long, as its first hash, saving the cost of hashing for keys that
+// are already well distributed.
+func (co *CuckooTableOptions) SetIdentityAsFirstHash(b bool) {
+	C.rocksdb_cuckoo_options_set_identity_as_first_hash(co.Opt, boolToUchar(b))
+}
+
+// SetUseModuleHash maps hashes to buckets with a modulo rather than a bit
+// mask. It is on by default; turning it off rounds the table up to a power
+// of two, which makes lookups faster and files bigger.
+func (co *CuckooTableOptions) SetUseModuleHash(b bool) {
+	C.rocksdb_cuckoo_options_set_use_module_hash(co.Opt, boolToUchar(b))
+}
+
+// SetCuckooTableFactory makes the database write SST files in the cuckoo
+// table format, a hash table that answers most Gets with a single memory
+// probe. It suits read-mostly data held in memory: files must be read with
+// mmap, see SetAllowMmapReads, every key and every value in a file must
+// have the same length, and iteration is slow, since it sorts the file
+// first. The Options keep a copy of co.
+func (o *Options) SetCuckooTableFactory(co *CuckooTableOptions) {
+	C.rocksdb_options_set_cuckoo_table_factory(o.Opt, co.Opt)
+}
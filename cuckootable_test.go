@@ -0,0 +1,42 @@
+package gorocks
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCuckooTableFactory(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	co := NewCuckooTableOptions()
+	co.SetHashRatio(0.8)
+	co.SetMaxSearchDepth(50)
+	co.SetCuckooBlockSize(4)
+	co.SetIdentityAsFirstHash(false)
+	co.SetUseModuleHash(true)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetAllowMmapReads(true)
+	options.SetCuckooTableFactory(co)
+	co.Close()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%05d", i)), []byte(fmt.Sprintf("val%05d", i)))
+	}
+	db.CompactRange(Range{})
+
+	CheckGet(t, "cuckoo table", db, ro, []byte("key00042"), []byte("val00042"))
+	CheckGet(t, "cuckoo table", db, ro, []byte("key00420"), nil)
+	if got := tableOption(t, dbname, "CuckooTable", "max_search_depth"); got != "50" {
+		t.Errorf("max_search_depth = %q, want 50", got)
+	}
+}
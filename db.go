@@ -8,6 +8,13 @@ package gorocks
 import "C"
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -17,6 +24,12 @@ func (e DatabaseError) Error() string {
 	return string(e)
 }
 
+// Is reports whether the error is of the kind target stands for, one of
+// ErrCorruption, ErrIO and ErrIncomplete.
+func (e DatabaseError) Is(target error) bool {
+	return statusIs(string(e), target)
+}
+
 // DB is a reusable handle to a RocksDB database on disk, created by Open.
 //
 // To avoid memory and file descriptor leaks, call Close when the process no
@@ -28,6 +41,21 @@ func (e DatabaseError) Error() string {
 // course.
 type DB struct {
 	Ldb *C.rocksdb_t
+
+	name, path  string
+	updateLocks updateLocks
+	writeHook   writeHookState
+	opHook      opHookState
+	limits      writeLimits
+	snapshots   snapshotRegistry
+	iterators   iteratorRegistry
+
+	memtableLimits memtableLimits
+	degraded       degradedState
+	migrateMu      sync.Mutex
+
+	// closed is run by Close after the handle has been closed.
+	closed func()
 }
 
 // Range is a range of keys in the database. GetApproximateSizes calls with it
@@ -44,7 +72,9 @@ type Range struct {
 // returned must be released with DB.ReleaseSnapshot method on the DB that
 // created it.
 type Snapshot struct {
-	snap *C.rocksdb_snapshot_t
+	snap    *C.rocksdb_snapshot_t
+	seq     uint64
+	created time.Time
 }
 
 // Open opens a database.
@@ -63,9 +93,85 @@ func Open(dbname string, o *Options) (*DB, error) {
 	if errStr != nil {
 		gs := C.GoString(errStr)
 		C.free(unsafe.Pointer(errStr))
-		return nil, DatabaseError(gs)
+		return nil, openError(dbname, gs)
 	}
-	return &DB{rocksdb}, nil
+	return newDB(rocksdb, dbname), nil
+}
+
+// OpenForReadOnly opens a database for reading only. Any number of
+// read-only handles may be open on a database at the same time, including
+// alongside a read-write handle, each seeing the data as it was when it was
+// opened. Write methods on the returned DB return errors.
+//
+// If errorIfWALFileExists is true, opening fails if the database has data
+// in its write ahead log that has not been flushed to SST files yet.
+func OpenForReadOnly(dbname string, o *Options, errorIfWALFileExists bool) (*DB, error) {
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	rocksdb := C.rocksdb_open_for_read_only(o.Opt, ldbname, boolToUchar(errorIfWALFileExists), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, openError(dbname, gs)
+	}
+	return newDB(rocksdb, dbname), nil
+}
+
+func newDB(ldb *C.rocksdb_t, dbname string) *DB {
+	path, err := filepath.Abs(dbname)
+	if err != nil {
+		path = dbname
+	}
+	return &DB{Ldb: ldb, name: dbname, path: path}
+}
+
+// Name returns the name the database was opened with.
+func (db *DB) Name() string {
+	return db.name
+}
+
+// Path returns the absolute path of the database directory, as resolved when
+// the database was opened.
+func (db *DB) Path() string {
+	return db.path
+}
+
+// OptionsFile returns the contents of the most recent OPTIONS file RocksDB
+// wrote into the database directory. It records the effective options the
+// database was opened with, including defaults, in RocksDB's own INI format.
+func (db *DB) OptionsFile() (string, error) {
+	return latestOptionsFile(db.path)
+}
+
+// latestOptionsFile returns the contents of the most recent OPTIONS file in
+// the database directory dir.
+func latestOptionsFile(dir string) (string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "OPTIONS-*"))
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestNum uint64
+	for _, name := range names {
+		num, err := strconv.ParseUint(strings.TrimPrefix(filepath.Base(name), "OPTIONS-"), 10, 64)
+		if err != nil {
+			// Skip temporary files such as OPTIONS-000005.dbtmp.
+			continue
+		}
+		if latest == "" || num > latestNum {
+			latest, latestNum = name, num
+		}
+	}
+	if latest == "" {
+		return "", DatabaseError("no OPTIONS file in " + dir)
+	}
+	data, err := os.ReadFile(latest)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
 // DestroyDatabase removes a database entirely, removing everything from the
@@ -108,7 +214,20 @@ func RepairDatabase(dbname string, o *Options) error {
 //
 // The key and value byte slices may be reused safely. Put takes a copy of
 // them before returning.
-func (db *DB) Put(wo *WriteOptions, key, value []byte) error {
+func (db *DB) Put(wo *WriteOptions, key, value []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpPut, len(key), &value, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.Put(key, value)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
 	var errStr *C.char
 	// rocksdb_put, _get, and _delete call memcpy() (by way of Memtable::Add)
 	// when called, so we do not need to worry about these []byte being
@@ -123,13 +242,129 @@ func (db *DB) Put(wo *WriteOptions, key, value []byte) error {
 
 	lenk := len(key)
 	lenv := len(value)
+	ct := cgoStart()
 	C.rocksdb_put(
 		db.Ldb, wo.Opt, k, C.size_t(lenk), v, C.size_t(lenv), &errStr)
+	cgoDone(CgoPut, ct)
 
 	if errStr != nil {
 		gs := C.GoString(errStr)
 		C.free(unsafe.Pointer(errStr))
-		return DatabaseError(gs)
+		return db.writeError(gs)
+	}
+	return nil
+}
+
+// Merge writes a merge operand for key, to be combined with the existing
+// value of key and any other operands by the MergeOperator the database was
+// opened with. Merging into a database without a MergeOperator fails.
+func (db *DB) Merge(wo *WriteOptions, key, value []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpMerge, len(key), &value, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.Merge(key, value)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+	ct := cgoStart()
+	C.rocksdb_merge(
+		db.Ldb, wo.Opt, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+	cgoDone(CgoMerge, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return db.writeError(gs)
+	}
+	return nil
+}
+
+// PutCF is like Put, but writes the key-value pair to the given column
+// family.
+func (db *DB) PutCF(wo *WriteOptions, cf *ColumnFamilyHandle, key, value []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpPut, len(key), &value, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.PutCF(cf, key, value)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	ct := cgoStart()
+	C.rocksdb_put_cf(db.Ldb, wo.Opt, cf.Handle,
+		k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+	cgoDone(CgoPut, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return db.writeError(gs)
+	}
+	return nil
+}
+
+// MergeCF is like Merge, but writes the merge operand to the given column
+// family, which must have a MergeOperator.
+func (db *DB) MergeCF(wo *WriteOptions, cf *ColumnFamilyHandle, key, value []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpMerge, len(key), &value, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.MergeCF(cf, key, value)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	ct := cgoStart()
+	C.rocksdb_merge_cf(db.Ldb, wo.Opt, cf.Handle,
+		k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+	cgoDone(CgoMerge, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return db.writeError(gs)
 	}
 	return nil
 }
@@ -142,7 +377,10 @@ func (db *DB) Put(wo *WriteOptions, key, value []byte) error {
 //
 // The key byte slice may be reused safely. Get takes a copy of
 // them before returning.
-func (db *DB) Get(ro *ReadOptions, key []byte) ([]byte, error) {
+func (db *DB) Get(ro *ReadOptions, key []byte) (v []byte, err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpGet, len(key), &v, &err, time.Now())
+	}
 	var errStr *C.char
 	var vallen C.size_t
 	var k *C.char
@@ -150,8 +388,10 @@ func (db *DB) Get(ro *ReadOptions, key []byte) ([]byte, error) {
 		k = (*C.char)(unsafe.Pointer(&key[0]))
 	}
 
+	ct := cgoStart()
 	value := C.rocksdb_get(
 		db.Ldb, ro.Opt, k, C.size_t(len(key)), &vallen, &errStr)
+	cgoDone(CgoGet, ct)
 
 	if errStr != nil {
 		gs := C.GoString(errStr)
@@ -167,36 +407,321 @@ func (db *DB) Get(ro *ReadOptions, key []byte) ([]byte, error) {
 	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
 }
 
+// GetCF is like Get, but reads the key from the given column family.
+func (db *DB) GetCF(ro *ReadOptions, cf *ColumnFamilyHandle, key []byte) (v []byte, err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpGet, len(key), &v, &err, time.Now())
+	}
+	var errStr *C.char
+	var vallen C.size_t
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	ct := cgoStart()
+	value := C.rocksdb_get_cf(
+		db.Ldb, ro.Opt, cf.Handle, k, C.size_t(len(key)), &vallen, &errStr)
+	cgoDone(CgoGet, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	defer C.free(unsafe.Pointer(value))
+	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
+}
+
+// GetValueSize returns the length of the value associated with the key
+// without copying the value out of RocksDB. found is false if the key does
+// not exist. This lets callers decide whether to stream or chunk a large
+// value before fetching it.
+func (db *DB) GetValueSize(ro *ReadOptions, key []byte) (size int, found bool, err error) {
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	ct := cgoStart()
+	pinned := C.rocksdb_get_pinned(db.Ldb, ro.Opt, k, C.size_t(len(key)), &errStr)
+	cgoDone(CgoGet, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return 0, false, DatabaseError(gs)
+	}
+	if pinned == nil {
+		return 0, false, nil
+	}
+	defer C.rocksdb_pinnableslice_destroy(pinned)
+	var vallen C.size_t
+	C.rocksdb_pinnableslice_value(pinned, &vallen)
+	return int(vallen), true, nil
+}
+
+// MultiGet returns the data associated with each of the keys, in the same
+// order, fetching all of them in a single call into RocksDB.
+//
+// As with Get, a missing key yields a nil []byte. If looking up any of the
+// keys fails, the first such error is returned along with the values that
+// were found.
+func (db *DB) MultiGet(ro *ReadOptions, keys [][]byte) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	ks, klens := cSlices(keys)
+	defer freeCSlices(ks)
+	vs := make([]*C.char, len(keys))
+	vlens := make([]C.size_t, len(keys))
+	errStrs := make([]*C.char, len(keys))
+
+	ct := cgoStart()
+	C.rocksdb_multi_get(db.Ldb, ro.Opt, C.size_t(len(keys)),
+		&ks[0], &klens[0], &vs[0], &vlens[0], &errStrs[0])
+	cgoDone(CgoMultiGet, ct)
+
+	return collectValues(values, vs, vlens, errStrs)
+}
+
+// MultiGetCF is like MultiGet, but each key is looked up in the column
+// family at the same position in cfs, so a single call may span several
+// column families.
+func (db *DB) MultiGetCF(ro *ReadOptions, cfs []*ColumnFamilyHandle, keys [][]byte) ([][]byte, error) {
+	if len(cfs) != len(keys) {
+		return nil, DatabaseError("column families and keys must have the same length")
+	}
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	ccfs := make([]*C.rocksdb_column_family_handle_t, len(cfs))
+	for i, cf := range cfs {
+		ccfs[i] = cf.Handle
+	}
+	ks, klens := cSlices(keys)
+	defer freeCSlices(ks)
+	vs := make([]*C.char, len(keys))
+	vlens := make([]C.size_t, len(keys))
+	errStrs := make([]*C.char, len(keys))
+
+	ct := cgoStart()
+	C.rocksdb_multi_get_cf(db.Ldb, ro.Opt, &ccfs[0], C.size_t(len(keys)),
+		&ks[0], &klens[0], &vs[0], &vlens[0], &errStrs[0])
+	cgoDone(CgoMultiGet, ct)
+
+	return collectValues(values, vs, vlens, errStrs)
+}
+
+// collectValues copies the values returned by one of the rocksdb_multi_get
+// functions into Go memory and frees them.
+func collectValues(values [][]byte, vs []*C.char, vlens []C.size_t, errStrs []*C.char) ([][]byte, error) {
+	var err error
+	for i := range values {
+		if errStrs[i] != nil {
+			if err == nil {
+				err = DatabaseError(C.GoString(errStrs[i]))
+			}
+			C.free(unsafe.Pointer(errStrs[i]))
+		}
+		if vs[i] != nil {
+			values[i] = C.GoBytes(unsafe.Pointer(vs[i]), C.int(vlens[i]))
+			C.free(unsafe.Pointer(vs[i]))
+		}
+	}
+	return values, err
+}
+
+// GetMultiConsistent is like MultiGet, but guarantees that all of the values
+// returned correspond to a single point in time. Unless ro already carries a
+// Snapshot, one is taken for the duration of the call.
+//
+// ro is modified while GetMultiConsistent runs and must not be used by other
+// goroutines at the same time.
+func (db *DB) GetMultiConsistent(ro *ReadOptions, keys [][]byte) ([][]byte, error) {
+	if ro.snap != nil {
+		return db.MultiGet(ro, keys)
+	}
+	snap := db.NewSnapshot()
+	defer db.ReleaseSnapshot(snap)
+	ro.SetSnapshot(snap)
+	defer ro.SetSnapshot(nil)
+	return db.MultiGet(ro, keys)
+}
+
 // Delete removes the data associated with the key from the database.
 //
 // The key byte slice may be reused safely. Delete takes a copy of
 // them before returning.
-func (db *DB) Delete(wo *WriteOptions, key []byte) error {
+func (db *DB) Delete(wo *WriteOptions, key []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpDelete, len(key), nil, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.Delete(key)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
 	var errStr *C.char
 	var k *C.char
 	if len(key) != 0 {
 		k = (*C.char)(unsafe.Pointer(&key[0]))
 	}
 
+	ct := cgoStart()
 	C.rocksdb_delete(
 		db.Ldb, wo.Opt, k, C.size_t(len(key)), &errStr)
+	cgoDone(CgoDelete, ct)
 
 	if errStr != nil {
 		gs := C.GoString(errStr)
 		C.free(unsafe.Pointer(errStr))
-		return DatabaseError(gs)
+		return db.writeError(gs)
+	}
+	return nil
+}
+
+// DeleteCF is like Delete, but removes the key from the given column family.
+func (db *DB) DeleteCF(wo *WriteOptions, cf *ColumnFamilyHandle, key []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpDelete, len(key), nil, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.DeleteCF(cf, key)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	ct := cgoStart()
+	C.rocksdb_delete_cf(
+		db.Ldb, wo.Opt, cf.Handle, k, C.size_t(len(key)), &errStr)
+	cgoDone(CgoDelete, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return db.writeError(gs)
+	}
+	return nil
+}
+
+// DeleteRange removes all keys in the range from start up to, but not
+// including, end. It writes a single range tombstone rather than one
+// deletion per key, making it cheap regardless of the number of keys.
+func (db *DB) DeleteRange(wo *WriteOptions, start, end []byte) error {
+	cf := db.DefaultColumnFamily()
+	defer cf.Close()
+	return db.DeleteRangeCF(wo, cf, start, end)
+}
+
+// DeleteRangeCF is like DeleteRange, but removes the keys from the given
+// column family.
+func (db *DB) DeleteRangeCF(wo *WriteOptions, cf *ColumnFamilyHandle, start, end []byte) (err error) {
+	if h := db.opHook.load(); h != nil {
+		defer h.done(OpDeleteRange, len(start), &end, &err, time.Now())
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		wb := NewWriteBatch()
+		defer wb.Close()
+		wb.DeleteRangeCF(cf, start, end)
+		return db.writeHooked(hook, wo, wb)
+	}
+
+	if err := db.Degraded(); err != nil {
+		return err
+	}
+	var errStr *C.char
+	var s, e *C.char
+	if len(start) != 0 {
+		s = (*C.char)(unsafe.Pointer(&start[0]))
+	}
+	if len(end) != 0 {
+		e = (*C.char)(unsafe.Pointer(&end[0]))
+	}
+
+	ct := cgoStart()
+	C.rocksdb_delete_range_cf(db.Ldb, wo.Opt, cf.Handle,
+		s, C.size_t(len(start)), e, C.size_t(len(end)), &errStr)
+	cgoDone(CgoDeleteRange, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return db.writeError(gs)
 	}
 	return nil
 }
 
 // Write atomically writes a WriteBatch to disk.
-func (db *DB) Write(wo *WriteOptions, w *WriteBatch) error {
+//
+// If the batch exceeds the limits set by SetWriteLimits, nothing is written
+// and a *BatchTooLargeError is returned.
+func (db *DB) Write(wo *WriteOptions, w *WriteBatch) (err error) {
+	if h := db.opHook.load(); h != nil {
+		data := w.Data()
+		defer h.done(OpWrite, 0, &data, &err, time.Now())
+	}
+	if err := w.CheckLimits(db.WriteLimits()); err != nil {
+		return err
+	}
+	if hook := db.writeHook.load(); hook != nil {
+		return db.writeHooked(hook, wo, w)
+	}
+	return db.write(wo, w)
+}
+
+// WriteAsync starts writing w in the background and returns a channel
+// that receives the result of the Write once it has completed, durably so
+// if wo asks for a sync. This lets callers prepare the next batch while the
+// previous one commits. Concurrent writes are grouped into shared commits
+// by RocksDB, so many outstanding WriteAsync calls amortize the cost of
+// syncing the write ahead log.
+//
+// Neither w nor wo may be modified or closed until the result has been
+// received.
+func (db *DB) WriteAsync(wo *WriteOptions, w *WriteBatch) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Write(wo, w)
+	}()
+	return done
+}
+
+func (db *DB) write(wo *WriteOptions, w *WriteBatch) error {
+	if err := db.Degraded(); err != nil {
+		return err
+	}
 	var errStr *C.char
+	ct := cgoStart()
 	C.rocksdb_write(db.Ldb, wo.Opt, w.wbatch, &errStr)
+	cgoDone(CgoWrite, ct)
 	if errStr != nil {
 		gs := C.GoString(errStr)
 		C.free(unsafe.Pointer(errStr))
-		return DatabaseError(gs)
+		return db.writeError(gs)
 	}
 	return nil
 }
@@ -212,8 +737,34 @@ func (db *DB) Write(wo *WriteOptions, w *WriteBatch) error {
 //
 // Similiarly, ReadOptions.SetSnapshot is also useful.
 func (db *DB) NewIterator(ro *ReadOptions) *Iterator {
+	ct := cgoStart()
 	it := C.rocksdb_create_iterator(db.Ldb, ro.Opt)
-	return &Iterator{Iter: it}
+	cgoDone(CgoIteratorCreate, ct)
+	return db.newIterator(it, ro)
+}
+
+// NewIteratorCF is like NewIterator, but the returned Iterator only covers
+// the keys of the given column family.
+func (db *DB) NewIteratorCF(ro *ReadOptions, cf *ColumnFamilyHandle) *Iterator {
+	ct := cgoStart()
+	it := C.rocksdb_create_iterator_cf(db.Ldb, ro.Opt, cf.Handle)
+	cgoDone(CgoIteratorCreate, ct)
+	iter := db.newIterator(it, ro)
+	iter.cf = cf
+	return iter
+}
+
+func (db *DB) newIterator(it *C.rocksdb_iterator_t, ro *ReadOptions) *Iterator {
+	iter := &Iterator{
+		Iter:   it,
+		db:     db,
+		snap:   ro.snap,
+		lower:  ro.lower,
+		upper:  ro.upper,
+		pinned: ro.pinData,
+	}
+	db.iterators.add(iter)
+	return iter
 }
 
 // GetApproximateSizes returns the approximate number of bytes of file system
@@ -222,6 +773,20 @@ func (db *DB) NewIterator(ro *ReadOptions) *Iterator {
 // The keys counted will begin at Range.Start and end on the key before
 // Range.Limit.
 func (db *DB) GetApproximateSizes(ranges []Range) []uint64 {
+	return db.approximateSizes(nil, ranges)
+}
+
+// GetApproximateSizesCF is like GetApproximateSizes, but only counts the
+// space used by the given column family.
+func (db *DB) GetApproximateSizesCF(cf *ColumnFamilyHandle, ranges []Range) []uint64 {
+	return db.approximateSizes(cf, ranges)
+}
+
+func (db *DB) approximateSizes(cf *ColumnFamilyHandle, ranges []Range) []uint64 {
+	sizes := make([]uint64, len(ranges))
+	if len(ranges) == 0 {
+		return sizes
+	}
 	starts := make([]*C.char, len(ranges))
 	limits := make([]*C.char, len(ranges))
 	startLens := make([]C.size_t, len(ranges))
@@ -232,16 +797,21 @@ func (db *DB) GetApproximateSizes(ranges []Range) []uint64 {
 		limits[i] = C.CString(string(r.Limit))
 		limitLens[i] = C.size_t(len(r.Limit))
 	}
-	sizes := make([]uint64, len(ranges))
 	numranges := C.int(len(ranges))
 	startsPtr := &starts[0]
 	limitsPtr := &limits[0]
 	startLensPtr := &startLens[0]
 	limitLensPtr := &limitLens[0]
 	sizesPtr := (*C.uint64_t)(&sizes[0])
-	C.rocksdb_approximate_sizes(
-		db.Ldb, numranges, startsPtr, startLensPtr,
-		limitsPtr, limitLensPtr, sizesPtr)
+	if cf == nil {
+		C.rocksdb_approximate_sizes(
+			db.Ldb, numranges, startsPtr, startLensPtr,
+			limitsPtr, limitLensPtr, sizesPtr)
+	} else {
+		C.rocksdb_approximate_sizes_cf(
+			db.Ldb, cf.Handle, numranges, startsPtr, startLensPtr,
+			limitsPtr, limitLensPtr, sizesPtr)
+	}
 	for i := range ranges {
 		C.free(unsafe.Pointer(starts[i]))
 		C.free(unsafe.Pointer(limits[i]))
@@ -249,6 +819,52 @@ func (db *DB) GetApproximateSizes(ranges []Range) []uint64 {
 	return sizes
 }
 
+// SetOptions changes mutable options of the open database, such as
+// "disable_auto_compactions" or "write_buffer_size", taking the names and
+// values RocksDB uses in its OPTIONS file.
+func (db *DB) SetOptions(opts map[string]string) error {
+	return db.setOptions(nil, opts)
+}
+
+// SetOptionsCF is like SetOptions, but changes the options of the given
+// column family.
+func (db *DB) SetOptionsCF(cf *ColumnFamilyHandle, opts map[string]string) error {
+	return db.setOptions(cf, opts)
+}
+
+func (db *DB) setOptions(cf *ColumnFamilyHandle, opts map[string]string) error {
+	if len(opts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(opts))
+	for name := range opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := make([]*C.char, len(names))
+	values := make([]*C.char, len(names))
+	for i, name := range names {
+		keys[i] = C.CString(name)
+		defer C.free(unsafe.Pointer(keys[i]))
+		values[i] = C.CString(opts[name])
+		defer C.free(unsafe.Pointer(values[i]))
+	}
+
+	var errStr *C.char
+	if cf == nil {
+		C.rocksdb_set_options(db.Ldb, C.int(len(names)), &keys[0], &values[0], &errStr)
+	} else {
+		C.rocksdb_set_options_cf(db.Ldb, cf.Handle, C.int(len(names)), &keys[0], &values[0], &errStr)
+	}
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	db.memtableLimits.reset()
+	return nil
+}
+
 // PropertyValue returns the value of a database property.
 //
 // Examples of properties include "rocksdb.stats", "rocksdb.sstables",
@@ -260,6 +876,44 @@ func (db *DB) PropertyValue(propName string) string {
 	return value
 }
 
+// PropertyValueCF returns the value of a property of the given column
+// family. An empty string is returned if the property is unknown.
+func (db *DB) PropertyValueCF(cf *ColumnFamilyHandle, propName string) string {
+	cname := C.CString(propName)
+	defer C.free(unsafe.Pointer(cname))
+	cvalue := C.rocksdb_property_value_cf(db.Ldb, cf.Handle, cname)
+	if cvalue == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cvalue))
+	return C.GoString(cvalue)
+}
+
+// IntPropertyValue returns the value of a numeric database property, such as
+// "rocksdb.estimate-num-keys" or "rocksdb.total-sst-files-size". The second
+// return value is false if the property is unknown or not numeric.
+func (db *DB) IntPropertyValue(propName string) (uint64, bool) {
+	cname := C.CString(propName)
+	defer C.free(unsafe.Pointer(cname))
+	var value C.uint64_t
+	if C.rocksdb_property_int(db.Ldb, cname, &value) != 0 {
+		return 0, false
+	}
+	return uint64(value), true
+}
+
+// IntPropertyValueCF is like IntPropertyValue, but reports the property of
+// the given column family.
+func (db *DB) IntPropertyValueCF(cf *ColumnFamilyHandle, propName string) (uint64, bool) {
+	cname := C.CString(propName)
+	defer C.free(unsafe.Pointer(cname))
+	var value C.uint64_t
+	if C.rocksdb_property_int_cf(db.Ldb, cf.Handle, cname, &value) != 0 {
+		return 0, false
+	}
+	return uint64(value), true
+}
+
 // NewSnapshot creates a new snapshot of the database.
 //
 // The snapshot, when used in a ReadOptions, provides a consistent view of
@@ -271,13 +925,28 @@ func (db *DB) PropertyValue(propName string) string {
 //
 // See the RocksDB documentation for details.
 func (db *DB) NewSnapshot() *Snapshot {
-	return &Snapshot{C.rocksdb_create_snapshot(db.Ldb)}
+	start := time.Now()
+	snap := C.rocksdb_create_snapshot(db.Ldb)
+	s := &Snapshot{
+		snap:    snap,
+		seq:     uint64(C.rocksdb_snapshot_get_sequence_number(snap)),
+		created: time.Now(),
+	}
+	db.snapshots.add(s)
+	if h := db.opHook.load(); h != nil {
+		h(OpInfo{Op: OpNewSnapshot, Duration: time.Since(start)})
+	}
+	return s
 }
 
 // ReleaseSnapshot removes the snapshot from the database's list of snapshots,
 // and deallocates it.
 func (db *DB) ReleaseSnapshot(snap *Snapshot) {
+	db.snapshots.remove(snap)
 	C.rocksdb_release_snapshot(db.Ldb, snap.snap)
+	if h := db.opHook.load(); h != nil {
+		h(OpInfo{Op: OpReleaseSnapshot, Duration: time.Since(snap.created)})
+	}
 }
 
 // CompactRange runs a manual compaction on the Range of keys given. This is
@@ -300,12 +969,44 @@ func (db *DB) DeleteFile(name string) {
 	C.rocksdb_delete_file(db.Ldb, cname)
 }
 
+// DeleteFilesInRange deletes the SST files whose keys all fall into the
+// Range, without writing tombstones or compacting. Keys in the range that
+// live in files overlapping its edges, or in the memtables, are left
+// alone, so it is usually combined with DeleteRange.
+func (db *DB) DeleteFilesInRange(r Range) error {
+	var errStr *C.char
+	var start, limit *C.char
+	if len(r.Start) != 0 {
+		start = (*C.char)(unsafe.Pointer(&r.Start[0]))
+	}
+	if len(r.Limit) != 0 {
+		limit = (*C.char)(unsafe.Pointer(&r.Limit[0]))
+	}
+	C.rocksdb_delete_file_in_range(
+		db.Ldb, start, C.size_t(len(r.Start)), limit, C.size_t(len(r.Limit)), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
 type LiveFileMetadata struct {
 	Name        string
 	Level       int
 	Size        int64
 	SmallestKey []byte
 	LargestKey  []byte
+
+	// Entries is the number of entries in the file and Deletions the number
+	// of those that are deletion markers, as recorded in the table
+	// properties.
+	Entries   uint64
+	Deletions uint64
+
+	// ColumnFamily is the name of the column family the file belongs to.
+	ColumnFamily string
 }
 
 func (db *DB) LiveFiles() []LiveFileMetadata {
@@ -317,6 +1018,7 @@ func (db *DB) LiveFiles() []LiveFileMetadata {
 	for i := C.int(0); i < count; i++ {
 		var liveFile LiveFileMetadata
 		liveFile.Name = C.GoString(C.rocksdb_livefiles_name(lf, i))
+		liveFile.ColumnFamily = C.GoString(C.rocksdb_livefiles_column_family_name(lf, i))
 		liveFile.Level = int(C.rocksdb_livefiles_level(lf, i))
 		liveFile.Size = int64(C.rocksdb_livefiles_size(lf, i))
 		var size C.size_t
@@ -324,16 +1026,80 @@ func (db *DB) LiveFiles() []LiveFileMetadata {
 		liveFile.SmallestKey = C.GoBytes(unsafe.Pointer(key), C.int(size))
 		key = C.rocksdb_livefiles_largestkey(lf, i, &size)
 		liveFile.LargestKey = C.GoBytes(unsafe.Pointer(key), C.int(size))
+		liveFile.Entries = uint64(C.rocksdb_livefiles_entries(lf, i))
+		liveFile.Deletions = uint64(C.rocksdb_livefiles_deletions(lf, i))
 		liveFiles[int(i)] = liveFile
 	}
 
 	return liveFiles
 }
 
+// TombstoneStats summarizes the deletion markers held by a database.
+type TombstoneStats struct {
+	// Entries and Deletions are summed over all live SST files.
+	Entries   uint64
+	Deletions uint64
+
+	// MemtableDeletions and MemtableRangeDeletions count the point and range
+	// deletions in the active and immutable memtables.
+	MemtableDeletions      uint64
+	MemtableRangeDeletions uint64
+
+	// Files lists the live SST files that contain any deletions, so that
+	// tombstone-heavy key ranges can be located.
+	Files []LiveFileMetadata
+}
+
+// DeletionRatio returns the fraction of the SST entries that are deletions.
+func (s *TombstoneStats) DeletionRatio() float64 {
+	if s.Entries == 0 {
+		return 0
+	}
+	return float64(s.Deletions) / float64(s.Entries)
+}
+
+// TombstoneStats gathers deletion counts from the live files and memtables
+// of the database. A high DeletionRatio, or individual files made up mostly
+// of deletions, indicates ranges that will be slow to scan until compacted.
+func (db *DB) TombstoneStats() TombstoneStats {
+	var stats TombstoneStats
+	for _, f := range db.LiveFiles() {
+		stats.Entries += f.Entries
+		stats.Deletions += f.Deletions
+		if f.Deletions > 0 {
+			stats.Files = append(stats.Files, f)
+		}
+	}
+	for _, prop := range []string{
+		"rocksdb.num-deletes-active-mem-table",
+		"rocksdb.num-deletes-imm-mem-tables",
+	} {
+		n, _ := db.IntPropertyValue(prop)
+		stats.MemtableDeletions += n
+	}
+	for _, prop := range []string{
+		"rocksdb.num-range-deletes-active-mem-table",
+		"rocksdb.num-range-deletes-imm-mem-tables",
+	} {
+		n, _ := db.IntPropertyValue(prop)
+		stats.MemtableRangeDeletions += n
+	}
+	return stats
+}
+
 // Close closes the database, rendering it unusable for I/O, by deallocating
 // the underlying handle.
 //
+// Iterators created from the DB and still open are closed first, since
+// RocksDB does not allow them to outlive it. Afterwards they report being
+// invalid, with GetError returning ErrIteratorClosed. They must not be in
+// use by other goroutines while Close runs.
+//
 // Any attempts to use the DB after Close is called will panic.
 func (db *DB) Close() {
+	db.iterators.closeAll()
 	C.rocksdb_close(db.Ldb)
+	if db.closed != nil {
+		db.closed()
+	}
 }
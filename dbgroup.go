@@ -0,0 +1,239 @@
+package gorocks
+
+import (
+	"sync"
+	"time"
+)
+
+// ResourcesOptions configures the resources created by NewResources.
+type ResourcesOptions struct {
+	// CacheSize is the capacity of the shared block cache. Zero means no
+	// shared cache.
+	CacheSize int
+	// WriteBufferSize is the total memtable memory of all the databases.
+	// Zero means no shared limit.
+	WriteBufferSize int
+	// AllowWriteStall stalls writes once WriteBufferSize is exceeded,
+	// instead of only triggering flushes.
+	AllowWriteStall bool
+	// BackgroundThreads and HighPriorityBackgroundThreads size the shared
+	// Env's compaction and flush thread pools. Zero leaves RocksDB's
+	// default.
+	BackgroundThreads             int
+	HighPriorityBackgroundThreads int
+}
+
+// Resources are an Env, block cache and write buffer manager meant to be
+// shared by many databases, so that a process opening hundreds of small
+// databases runs one set of background threads and bounds their memory
+// together. Cache and WriteBufferManager are nil unless their size was set.
+//
+// To prevent memory leaks, Close must be called on Resources when the
+// program no longer needs them.
+type Resources struct {
+	Env                *Env
+	Cache              *Cache
+	WriteBufferManager *WriteBufferManager
+}
+
+// NewResources creates the shared resources described by ro.
+func NewResources(ro ResourcesOptions) *Resources {
+	r := &Resources{Env: NewDefaultEnv()}
+	if ro.BackgroundThreads > 0 {
+		r.Env.SetBackgroundThreads(ro.BackgroundThreads)
+	}
+	if ro.HighPriorityBackgroundThreads > 0 {
+		r.Env.SetHighPriorityBackgroundThreads(ro.HighPriorityBackgroundThreads)
+	}
+	if ro.CacheSize > 0 {
+		r.Cache = NewLRUCache(ro.CacheSize)
+	}
+	if ro.WriteBufferSize > 0 {
+		r.WriteBufferManager = NewWriteBufferManager(ro.WriteBufferSize, ro.AllowWriteStall)
+	}
+	return r
+}
+
+// Apply sets the resources on o, so that databases opened with o share
+// them.
+func (r *Resources) Apply(o *Options) {
+	o.SetEnv(r.Env)
+	if r.Cache != nil {
+		o.SetCache(r.Cache)
+	}
+	if r.WriteBufferManager != nil {
+		o.SetWriteBufferManager(r.WriteBufferManager)
+	}
+}
+
+// Close releases the resources. It must only be called once every
+// database using them has been closed.
+func (r *Resources) Close() {
+	if r.Cache != nil {
+		r.Cache.Close()
+	}
+	if r.WriteBufferManager != nil {
+		r.WriteBufferManager.Close()
+	}
+	r.Env.Close()
+}
+
+// DBGroup opens databases that share one set of Resources, keeps track of
+// them for aggregate accounting, and closes them all together. Unlike a
+// Manager, it opens exactly the databases it is asked to and never closes
+// them on its own.
+//
+// A DBGroup may be shared between goroutines.
+type DBGroup struct {
+	res  *Resources
+	opts *Options
+
+	mu      sync.Mutex
+	dbs     map[*DB]struct{}
+	opening sync.WaitGroup // Opens in progress, which Close waits for
+}
+
+// NewDBGroup creates a DBGroup with resources described by ro. Databases
+// are opened with a clone of o by default, so the caller keeps ownership
+// of it.
+func NewDBGroup(o *Options, ro ResourcesOptions) *DBGroup {
+	g := &DBGroup{
+		res:  NewResources(ro),
+		opts: o.Clone(),
+		dbs:  make(map[*DB]struct{}),
+	}
+	g.res.Apply(g.opts)
+	return g
+}
+
+// Resources returns the resources shared by the group.
+func (g *DBGroup) Resources() *Resources {
+	return g.res
+}
+
+// Open opens the database at path as part of the group, with the group's
+// Options. The DB may be closed on its own, or left to DBGroup.Close.
+func (g *DBGroup) Open(path string) (*DB, error) {
+	return g.open(path, g.opts)
+}
+
+// OpenWithOptions is like Open with options of the caller's own, to which
+// the shared resources are applied. o is not modified.
+func (g *DBGroup) OpenWithOptions(path string, o *Options) (*DB, error) {
+	opts := o.Clone()
+	defer opts.Close()
+	g.res.Apply(opts)
+	return g.open(path, opts)
+}
+
+// errDBGroupClosed is returned when opening a database in a closed DBGroup.
+var errDBGroupClosed = DatabaseError("gorocks: DBGroup is closed")
+
+func (g *DBGroup) open(path string, o *Options) (*DB, error) {
+	g.mu.Lock()
+	if g.dbs == nil {
+		g.mu.Unlock()
+		return nil, errDBGroupClosed
+	}
+	// Open without holding mu, so that a slow open does not hold up
+	// others. Close waits for it before closing the resources.
+	g.opening.Add(1)
+	defer g.opening.Done()
+	g.mu.Unlock()
+
+	db, err := Open(path, o)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.dbs == nil {
+		db.Close()
+		return nil, errDBGroupClosed
+	}
+	g.dbs[db] = struct{}{}
+	db.closed = func() {
+		g.mu.Lock()
+		delete(g.dbs, db)
+		g.mu.Unlock()
+	}
+	return db, nil
+}
+
+// DBGroupStats is the aggregate accounting of a DBGroup.
+type DBGroupStats struct {
+	// Open is the number of databases open in the group.
+	Open int
+	// CacheUsage, CachePinnedUsage and CacheCapacity describe the shared
+	// block cache, if any.
+	CacheUsage       int
+	CachePinnedUsage int
+	CacheCapacity    int
+	// MemtableUsage and MemtableLimit describe the shared write buffer
+	// manager, if any.
+	MemtableUsage int
+	MemtableLimit int
+	// Snapshots is the number of live Snapshots of the open databases, and
+	// OldestSnapshotAge the age of the oldest of them, as reported by
+	// DB.SnapshotStats.
+	Snapshots         int
+	OldestSnapshotAge time.Duration
+}
+
+// Stats returns the aggregate accounting of the group.
+func (g *DBGroup) Stats() DBGroupStats {
+	g.mu.Lock()
+	s := DBGroupStats{Open: len(g.dbs)}
+	for db := range g.dbs {
+		ss := db.SnapshotStats()
+		s.Snapshots += ss.Count
+		if ss.OldestAge > s.OldestSnapshotAge {
+			s.OldestSnapshotAge = ss.OldestAge
+		}
+	}
+	g.mu.Unlock()
+	if c := g.res.Cache; c != nil {
+		s.CacheUsage = c.GetUsage()
+		s.CachePinnedUsage = c.GetPinnedUsage()
+		s.CacheCapacity = c.GetCapacity()
+	}
+	if m := g.res.WriteBufferManager; m != nil {
+		s.MemtableUsage = m.MemoryUsage()
+		s.MemtableLimit = m.BufferSize()
+	}
+	return s
+}
+
+// DiskUsage returns the sum of the DiskUsage of the open databases.
+func (g *DBGroup) DiskUsage() (DiskUsage, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var total DiskUsage
+	for db := range g.dbs {
+		u, err := db.DiskUsage()
+		if err != nil {
+			return DiskUsage{}, err
+		}
+		total.LiveSST += u.LiveSST
+		total.WAL += u.WAL
+		total.Blob += u.Blob
+		total.Obsolete += u.Obsolete
+		total.Other += u.Other
+	}
+	return total, nil
+}
+
+// Close closes every database still open in the group, then the shared
+// resources. The group cannot be used afterwards.
+func (g *DBGroup) Close() {
+	g.mu.Lock()
+	dbs := g.dbs
+	g.dbs = nil
+	g.mu.Unlock()
+	g.opening.Wait()
+	for db := range dbs {
+		db.Close()
+	}
+	g.opts.Close()
+	g.res.Close()
+}
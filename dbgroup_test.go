@@ -0,0 +1,64 @@
+package gorocks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDBGroup(t *testing.T) {
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	g := NewDBGroup(options, ResourcesOptions{
+		CacheSize:       1 << 20,
+		WriteBufferSize: 8 << 20,
+	})
+	options.Close()
+
+	wo := NewWriteOptions()
+	defer wo.Close()
+	var dbs []*DB
+	for _, name := range []string{"a", "b", "c"} {
+		db, err := g.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", name, err)
+		}
+		db.Put(wo, []byte("tenant"), []byte(name))
+		dbs = append(dbs, db)
+	}
+	if _, err := g.Open(filepath.Join(dir, "a")); err == nil {
+		t.Errorf("opening a database twice succeeded")
+	}
+
+	s := g.Stats()
+	if s.Open != 3 || s.CacheCapacity != 1<<20 || s.MemtableLimit != 8<<20 || s.MemtableUsage == 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	snap := dbs[1].NewSnapshot()
+	if s := g.Stats(); s.Snapshots != 1 || s.OldestSnapshotAge <= 0 {
+		t.Errorf("unexpected snapshot stats %+v", s)
+	}
+	dbs[1].ReleaseSnapshot(snap)
+	if u, err := g.DiskUsage(); err != nil || u.Total() == 0 {
+		t.Errorf("DiskUsage = %+v, %v", u, err)
+	}
+
+	dbs[0].Close()
+	if n := g.Stats().Open; n != 2 {
+		t.Errorf("%d databases open after closing one, want 2", n)
+	}
+	g.Close()
+
+	o := NewOptions()
+	defer o.Close()
+	db, err := Open(filepath.Join(dir, "b"), o)
+	if err != nil {
+		t.Fatalf("database was not closed by the group: %v", err)
+	}
+	db.Close()
+}
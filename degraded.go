@@ -0,0 +1,82 @@
+package gorocks
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDegraded is matched by the error returned by writes to a DB handle
+// that has degraded to read-only serving. The returned error is a
+// *DegradedError with more detail.
+var ErrDegraded = errors.New("database degraded to read-only")
+
+// DegradedError is returned by writes to a degraded DB handle.
+type DegradedError struct {
+	// Cause is the message of the write error that caused the degradation.
+	Cause string
+	// Since is when the handle degraded.
+	Since time.Time
+}
+
+func (e *DegradedError) Error() string {
+	return fmt.Sprintf("%s since %s: %s", ErrDegraded, e.Since.Format(time.RFC3339), e.Cause)
+}
+
+// Is reports whether target is ErrDegraded.
+func (e *DegradedError) Is(target error) bool {
+	return target == ErrDegraded
+}
+
+type degradedState struct {
+	enabled atomic.Bool
+	err     atomic.Pointer[DegradedError]
+}
+
+// SetDegradeOnBackgroundError turns degraded mode on or off for this
+// handle. Once RocksDB hits an unrecoverable error in the background, such
+// as a flush or compaction failing on a full or broken disk, it refuses all
+// further writes but keeps serving reads. In degraded mode, the first write
+// failing that way puts the handle into a degraded state in which writes
+// are rejected up front with a *DegradedError, without calling into
+// RocksDB, so that callers can tell "this node is read-only now" apart from
+// ordinary write errors and keep serving reads.
+func (db *DB) SetDegradeOnBackgroundError(on bool) {
+	db.degraded.enabled.Store(on)
+}
+
+// Degraded returns the *DegradedError writes are rejected with while the
+// handle is degraded, or nil if it is not.
+func (db *DB) Degraded() error {
+	if e := db.degraded.err.Load(); e != nil {
+		return e
+	}
+	return nil
+}
+
+// ExitDegraded lets writes through to RocksDB again, for example after an
+// operator has freed disk space. If RocksDB still refuses writes, the next
+// failing write degrades the handle again.
+func (db *DB) ExitDegraded() {
+	db.degraded.err.Store(nil)
+}
+
+// degrade puts the handle into the degraded state, unless it already is,
+// and returns the resulting error.
+func (db *DB) degrade(cause string) error {
+	db.degraded.err.CompareAndSwap(nil, &DegradedError{Cause: cause, Since: time.Now()})
+	return db.degraded.err.Load()
+}
+
+// writeError turns the message of a failed write into an error, degrading
+// the handle if degraded mode is on and the failure comes from a background
+// error, which RocksDB reports to every write until it is cleared.
+func (db *DB) writeError(msg string) error {
+	if db.degraded.enabled.Load() && (statusIs(msg, ErrIO) || statusIs(msg, ErrCorruption)) {
+		if n, _ := db.IntPropertyValue("rocksdb.background-errors"); n > 0 {
+			return db.degrade(msg)
+		}
+	}
+	return DatabaseError(msg)
+}
@@ -0,0 +1,71 @@
+package gorocks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiskUsage breaks down the space a database takes up on disk, in bytes.
+type DiskUsage struct {
+	// LiveSST is the size of the SST files that make up the current
+	// version of the database.
+	LiveSST int64
+	// WAL is the size of the write ahead log files.
+	WAL int64
+	// Blob is the size of the blob files holding separated values.
+	Blob int64
+	// Obsolete is the size of the SST files that are no longer live but
+	// have not been deleted yet, for example because a Checkpoint, backup
+	// or Iterator still uses them.
+	Obsolete int64
+	// Other is the size of everything else in the directory, such as the
+	// MANIFEST, OPTIONS and info log files.
+	Other int64
+}
+
+// Total returns the total size of the database directory.
+func (u DiskUsage) Total() int64 {
+	return u.LiveSST + u.WAL + u.Blob + u.Obsolete + u.Other
+}
+
+// DiskUsage reports the space the database takes up on disk, combining the
+// live file metadata with a scan of the database directory. Write ahead
+// logs kept in a separate WAL directory are not counted.
+func (db *DB) DiskUsage() (DiskUsage, error) {
+	live := make(map[string]bool)
+	var usage DiskUsage
+	for _, f := range db.LiveFiles() {
+		live[filepath.Base(f.Name)] = true
+	}
+	entries, err := os.ReadDir(db.path)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if os.IsNotExist(err) {
+			// Deleted by a compaction or flush since the directory was read.
+			continue
+		} else if err != nil {
+			return DiskUsage{}, err
+		}
+		name, size := e.Name(), info.Size()
+		switch {
+		case strings.HasSuffix(name, ".sst") && live[name]:
+			usage.LiveSST += size
+		case strings.HasSuffix(name, ".sst"):
+			usage.Obsolete += size
+		case strings.HasSuffix(name, ".log") && !strings.HasPrefix(name, "LOG"):
+			usage.WAL += size
+		case strings.HasSuffix(name, ".blob"):
+			usage.Blob += size
+		default:
+			usage.Other += size
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,40 @@
+package gorocks
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors matched, with errors.Is, by the DatabaseErrors and IteratorErrors
+// RocksDB reports, according to the kind of failure.
+var (
+	// ErrCorruption means RocksDB found corrupted data.
+	ErrCorruption = errors.New("corruption")
+	// ErrIO means a read or write of the underlying files failed.
+	ErrIO = errors.New("IO error")
+	// ErrIncomplete means the operation could not complete without doing
+	// I/O that was ruled out, for example by ReadOptions.SetReadTier.
+	ErrIncomplete = errors.New("result incomplete")
+)
+
+// statusPrefixes maps the prefix RocksDB gives the message of each kind of
+// failed Status to the error matching it.
+var statusPrefixes = []struct {
+	prefix string
+	err    error
+}{
+	{"Corruption: ", ErrCorruption},
+	{"IO error: ", ErrIO},
+	{"Result incomplete: ", ErrIncomplete},
+}
+
+// statusIs reports whether msg is the message of a failed Status of the
+// kind target stands for.
+func statusIs(msg string, target error) bool {
+	for _, p := range statusPrefixes {
+		if target == p.err {
+			return strings.HasPrefix(msg, p.prefix)
+		}
+	}
+	return false
+}
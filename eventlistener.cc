@@ -0,0 +1,100 @@
+// EventListeners implemented in Go, which the C API cannot install. The
+// listener's state is a cgo.Handle of the Go EventListener; the callbacks
+// forward to the exported Go functions in eventlistener.go.
+
+#include <memory>
+#include <string>
+#include <vector>
+
+#include "cinternal.h"
+#include "eventlistener.h"
+#include "rocksdb/listener.h"
+
+extern "C" {
+void gorocksEventListenerFlush(uintptr_t state, unsigned char completed,
+		gorocks_flush_info_t* info);
+void gorocksEventListenerCompaction(uintptr_t state, unsigned char completed,
+		gorocks_compaction_info_t* info);
+void gorocksEventListenerDestroy(uintptr_t state);
+}
+
+namespace {
+
+class GoEventListener : public rocksdb::EventListener {
+ public:
+	explicit GoEventListener(uintptr_t state) : state_(state) {}
+
+	~GoEventListener() override {
+		gorocksEventListenerDestroy(state_);
+	}
+
+	void OnFlushBegin(rocksdb::DB* db, const rocksdb::FlushJobInfo& info) override {
+		Flush(db, info, false);
+	}
+
+	void OnFlushCompleted(rocksdb::DB* db, const rocksdb::FlushJobInfo& info) override {
+		Flush(db, info, true);
+	}
+
+	void OnCompactionBegin(rocksdb::DB* db, const rocksdb::CompactionJobInfo& info) override {
+		Compaction(db, info, false);
+	}
+
+	void OnCompactionCompleted(rocksdb::DB* db, const rocksdb::CompactionJobInfo& info) override {
+		Compaction(db, info, true);
+	}
+
+ private:
+	void Flush(rocksdb::DB* db, const rocksdb::FlushJobInfo& info, bool completed) {
+		gorocks_flush_info_t i;
+		i.db_name = db->GetName().c_str();
+		i.cf_name = info.cf_name.c_str();
+		i.job_id = info.job_id;
+		i.file_path = info.file_path.c_str();
+		i.smallest_seqno = info.smallest_seqno;
+		i.largest_seqno = info.largest_seqno;
+		i.triggered_writes_slowdown = info.triggered_writes_slowdown;
+		i.triggered_writes_stop = info.triggered_writes_stop;
+		gorocksEventListenerFlush(state_, completed, &i);
+	}
+
+	void Compaction(rocksdb::DB* db, const rocksdb::CompactionJobInfo& info, bool completed) {
+		std::vector<const char*> inputs, outputs;
+		for (const std::string& f : info.input_files) {
+			inputs.push_back(f.c_str());
+		}
+		for (const std::string& f : info.output_files) {
+			outputs.push_back(f.c_str());
+		}
+		std::string status;
+		if (!info.status.ok()) {
+			status = info.status.ToString();
+		}
+
+		gorocks_compaction_info_t i;
+		i.db_name = db->GetName().c_str();
+		i.cf_name = info.cf_name.c_str();
+		i.job_id = info.job_id;
+		i.status = info.status.ok() ? nullptr : status.c_str();
+		i.base_input_level = info.base_input_level;
+		i.output_level = info.output_level;
+		i.input_files = inputs.data();
+		i.num_input_files = static_cast<int>(inputs.size());
+		i.output_files = outputs.data();
+		i.num_output_files = static_cast<int>(outputs.size());
+		i.elapsed_micros = info.stats.elapsed_micros;
+		i.input_records = info.stats.num_input_records;
+		i.output_records = info.stats.num_output_records;
+		i.input_bytes = info.stats.total_input_bytes;
+		i.output_bytes = info.stats.total_output_bytes;
+		gorocksEventListenerCompaction(state_, completed, &i);
+	}
+
+	uintptr_t state_;
+};
+
+}  // namespace
+
+extern "C" void gorocks_options_add_eventlistener(rocksdb_options_t* opt, uintptr_t state) {
+	opt->rep.listeners.push_back(std::make_shared<GoEventListener>(state));
+}
@@ -0,0 +1,135 @@
+package gorocks
+
+/*
+#include <stdint.h>
+#include "rocksdb/c.h"
+#include "eventlistener.h"
+
+extern void gorocks_options_add_eventlistener(rocksdb_options_t* opt, uintptr_t state);
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"time"
+	"unsafe"
+)
+
+// EventListener is notified of the flushes and compactions RocksDB runs in
+// the background, for example to trace or log them. It is installed with
+// Options.AddEventListener.
+//
+// The methods are called from RocksDB's background threads, which they
+// block, and must be safe for concurrent use. A panic in a method crashes
+// the program.
+type EventListener interface {
+	// OnFlushBegin and OnFlushCompleted are called before and after a
+	// memtable is written to an SST file.
+	OnFlushBegin(info FlushJobInfo)
+	OnFlushCompleted(info FlushJobInfo)
+	// OnCompactionBegin and OnCompactionCompleted are called before and
+	// after a compaction, including failed ones.
+	OnCompactionBegin(info CompactionJobInfo)
+	OnCompactionCompleted(info CompactionJobInfo)
+}
+
+// FlushJobInfo describes a flush. JobID tells the flushes of a database
+// apart and matches the begin and completion of one.
+type FlushJobInfo struct {
+	DBName       string
+	ColumnFamily string
+	JobID        int
+	// FilePath is the SST file written.
+	FilePath                    string
+	SmallestSeqno, LargestSeqno uint64
+	TriggeredWritesSlowdown     bool
+	TriggeredWritesStop         bool
+}
+
+// CompactionJobInfo describes a compaction. JobID tells the compactions of
+// a database apart and matches the begin and completion of one.
+type CompactionJobInfo struct {
+	DBName       string
+	ColumnFamily string
+	JobID        int
+	// Err is the error the compaction failed with, if any.
+	Err                         error
+	BaseInputLevel, OutputLevel int
+	InputFiles, OutputFiles     []string
+	// The statistics are only set on completion.
+	Elapsed                     time.Duration
+	InputRecords, OutputRecords uint64
+	InputBytes, OutputBytes     uint64
+}
+
+// AddEventListener adds l to the listeners notified by databases opened
+// with the Options. RocksDB holds on to the listener until the Options and
+// every database opened with them have been closed.
+func (o *Options) AddEventListener(l EventListener) {
+	C.gorocks_options_add_eventlistener(o.Opt, C.uintptr_t(cgo.NewHandle(l)))
+}
+
+func eventListener(state C.uintptr_t) EventListener {
+	return cgo.Handle(state).Value().(EventListener)
+}
+
+//export gorocksEventListenerFlush
+func gorocksEventListenerFlush(state C.uintptr_t, completed C.uchar, info *C.gorocks_flush_info_t) {
+	fi := FlushJobInfo{
+		DBName:                  C.GoString(info.db_name),
+		ColumnFamily:            C.GoString(info.cf_name),
+		JobID:                   int(info.job_id),
+		FilePath:                C.GoString(info.file_path),
+		SmallestSeqno:           uint64(info.smallest_seqno),
+		LargestSeqno:            uint64(info.largest_seqno),
+		TriggeredWritesSlowdown: info.triggered_writes_slowdown != 0,
+		TriggeredWritesStop:     info.triggered_writes_stop != 0,
+	}
+	if completed != 0 {
+		eventListener(state).OnFlushCompleted(fi)
+	} else {
+		eventListener(state).OnFlushBegin(fi)
+	}
+}
+
+//export gorocksEventListenerCompaction
+func gorocksEventListenerCompaction(state C.uintptr_t, completed C.uchar, info *C.gorocks_compaction_info_t) {
+	ci := CompactionJobInfo{
+		DBName:         C.GoString(info.db_name),
+		ColumnFamily:   C.GoString(info.cf_name),
+		JobID:          int(info.job_id),
+		BaseInputLevel: int(info.base_input_level),
+		OutputLevel:    int(info.output_level),
+		InputFiles:     goStrings(info.input_files, info.num_input_files),
+		OutputFiles:    goStrings(info.output_files, info.num_output_files),
+		Elapsed:        time.Duration(info.elapsed_micros) * time.Microsecond,
+		InputRecords:   uint64(info.input_records),
+		OutputRecords:  uint64(info.output_records),
+		InputBytes:     uint64(info.input_bytes),
+		OutputBytes:    uint64(info.output_bytes),
+	}
+	if info.status != nil {
+		ci.Err = DatabaseError(C.GoString(info.status))
+	}
+	if completed != 0 {
+		eventListener(state).OnCompactionCompleted(ci)
+	} else {
+		eventListener(state).OnCompactionBegin(ci)
+	}
+}
+
+//export gorocksEventListenerDestroy
+func gorocksEventListenerDestroy(state C.uintptr_t) {
+	cgo.Handle(state).Delete()
+}
+
+func goStrings(p **C.char, n C.int) []string {
+	if n == 0 {
+		return nil
+	}
+	strs := make([]string, n)
+	for i, s := range unsafe.Slice(p, n) {
+		strs[i] = C.GoString(s)
+	}
+	return strs
+}
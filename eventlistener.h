@@ -0,0 +1,40 @@
+// The parts of RocksDB's FlushJobInfo and CompactionJobInfo passed to the Go
+// EventListener by eventlistener.cc. The strings are only valid for the
+// duration of the callback.
+
+#ifndef GOROCKS_EVENTLISTENER_H
+#define GOROCKS_EVENTLISTENER_H
+
+#include <stdint.h>
+
+typedef struct {
+	const char* db_name;
+	const char* cf_name;
+	int job_id;
+	const char* file_path;
+	uint64_t smallest_seqno;
+	uint64_t largest_seqno;
+	unsigned char triggered_writes_slowdown;
+	unsigned char triggered_writes_stop;
+} gorocks_flush_info_t;
+
+typedef struct {
+	const char* db_name;
+	const char* cf_name;
+	int job_id;
+	// status is NULL unless the compaction failed.
+	const char* status;
+	int base_input_level;
+	int output_level;
+	const char* const* input_files;
+	int num_input_files;
+	const char* const* output_files;
+	int num_output_files;
+	uint64_t elapsed_micros;
+	uint64_t input_records;
+	uint64_t output_records;
+	uint64_t input_bytes;
+	uint64_t output_bytes;
+} gorocks_compaction_info_t;
+
+#endif  // GOROCKS_EVENTLISTENER_H
@@ -0,0 +1,94 @@
+package gorocks
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingListener struct {
+	mu                    sync.Mutex
+	flushes, compactions  []int
+	flushInfo             FlushJobInfo
+	compactionInfo        CompactionJobInfo
+	flushBegun, compBegun int
+}
+
+func (l *recordingListener) OnFlushBegin(info FlushJobInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushBegun++
+}
+
+func (l *recordingListener) OnFlushCompleted(info FlushJobInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushes = append(l.flushes, info.JobID)
+	l.flushInfo = info
+}
+
+func (l *recordingListener) OnCompactionBegin(info CompactionJobInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.compBegun++
+}
+
+func (l *recordingListener) OnCompactionCompleted(info CompactionJobInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.compactions = append(l.compactions, info.JobID)
+	l.compactionInfo = info
+}
+
+func TestEventListener(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	l := &recordingListener{}
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.AddEventListener(l)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	// The second flush overlaps the first file, so compacting them is not
+	// a trivial move.
+	for _, v := range []string{"1", "2"} {
+		db.Put(wo, []byte("a"), []byte(v))
+		db.Put(wo, []byte("z"), []byte(v))
+		db.CompactRange(Range{})
+	}
+
+	// Listeners may still be running when CompactRange returns.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		l.mu.Lock()
+		done := len(l.flushes) >= 2 && len(l.compactions) >= 1
+		l.mu.Unlock()
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.flushes) < 2 || l.flushBegun < len(l.flushes) {
+		t.Errorf("got %d flushes begun and %d completed, want at least 2", l.flushBegun, len(l.flushes))
+	}
+	if fi := l.flushInfo; fi.DBName != dbname || fi.ColumnFamily != DefaultColumnFamilyName || fi.FilePath == "" {
+		t.Errorf("unexpected flush info %+v", fi)
+	}
+	if len(l.compactions) < 1 || l.compBegun < len(l.compactions) {
+		t.Fatalf("got %d compactions begun and %d completed, want at least 1", l.compBegun, len(l.compactions))
+	}
+	ci := l.compactionInfo
+	if ci.Err != nil || len(ci.InputFiles) < 2 || len(ci.OutputFiles) == 0 || ci.InputRecords != 4 || ci.OutputRecords != 2 {
+		t.Errorf("unexpected compaction info %+v", ci)
+	}
+}
@@ -0,0 +1,282 @@
+package gorocks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ExportFileSize is the size at which ExportColumnFamily starts a new SST
+// file.
+const ExportFileSize = 64 << 20
+
+// ExportColumnFamily writes the contents of the column family, as of a
+// Snapshot taken when it is called, into SST files in dir, which is created
+// if necessary. The files can be added to another database with
+// ImportColumnFamily, which makes this the building block for moving
+// shards between databases. o must have the comparator of the column
+// family. It returns the paths of the files written, in key order.
+//
+// RocksDB's C API does not expose its checkpoint-based export, so the data
+// is read back and rewritten with an SstFileWriter rather than hard-linked.
+func (db *DB) ExportColumnFamily(cf *ColumnFamilyHandle, dir string, o *Options) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	snap := db.NewSnapshot()
+	defer db.ReleaseSnapshot(snap)
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetSnapshot(snap)
+	ro.SetFillCache(false)
+
+	w := NewSstFileWriter(o)
+	defer w.Close()
+	it := db.NewIteratorCF(ro, cf)
+	defer it.Close()
+
+	var paths []string
+	var open bool
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if !open {
+			path := filepath.Join(dir, fmt.Sprintf("%06d.sst", len(paths)+1))
+			if err := w.Open(path); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+			open = true
+		}
+		if err := w.Put(it.Key(), it.Value()); err != nil {
+			return paths, err
+		}
+		if w.FileSize() >= ExportFileSize {
+			if err := w.Finish(); err != nil {
+				return paths, err
+			}
+			open = false
+		}
+	}
+	if err := it.GetError(); err != nil {
+		return paths, err
+	}
+	if open {
+		if err := w.Finish(); err != nil {
+			return paths, err
+		}
+	}
+	return paths, nil
+}
+
+// ImportColumnFamily creates a column family called name with o and adds
+// to it the SST files found in dir, as written by ExportColumnFamily. The
+// files are moved into the database, leaving dir empty of them, when dir
+// is on the same filesystem.
+func (db *DB) ImportColumnFamily(o *Options, name, dir string) (*ColumnFamilyHandle, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sst"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	cf, err := db.CreateColumnFamily(o, name)
+	if err != nil {
+		return nil, err
+	}
+	opts := NewIngestExternalFileOptions()
+	defer opts.Close()
+	opts.SetMoveFiles(true)
+	if err := db.IngestExternalFileCF(cf, paths, opts); err != nil {
+		db.DropColumnFamily(cf)
+		cf.Close()
+		return nil, err
+	}
+	return cf, nil
+}
+
+// ExportOptions configures DB.Export.
+type ExportOptions struct {
+	// Pieces is the number of ranges the keyspace is split into, using
+	// SuggestSplitKeys. Zero means Parallelism. Fewer ranges are exported
+	// when the database is too small to split that finely.
+	Pieces int
+	// Parallelism is the number of ranges exported at once. Zero means
+	// runtime.GOMAXPROCS(0).
+	Parallelism int
+	// Compress gzips the output of every range.
+	Compress bool
+	// Scan configures the scans of the ranges, for example to rate-limit
+	// them. KeysOnly is ignored.
+	Scan ScanOptions
+}
+
+// exportCheckInterval is the number of keys Export writes between checks
+// of its context.
+const exportCheckInterval = 1024
+
+// Export streams the whole database, as of snap, to writers for analytics
+// jobs such as nightly dumps to a data lake. The keyspace is split into
+// ranges that are exported in parallel, each to the writer newWriter
+// returns for it, which Export closes when the range is done. If snap is
+// nil, a Snapshot is taken for the duration of the export.
+//
+// Each range is written as a sequence of records: the uvarint length of the
+// key, the key, the uvarint length of the value and the value, gzipped if
+// opts.Compress is set. ReadExport reads it back.
+//
+// Export stops at the first error, or when ctx is done, and returns it.
+// Ranges already written are left as they are.
+//
+// Only the default column family is exported, and, as the ranges come from
+// SuggestSplitKeys, the database must use the default bytewise key order.
+func (db *DB) Export(ctx context.Context, snap *Snapshot, newWriter func(piece int, r Range) (io.WriteCloser, error), opts ExportOptions) error {
+	if snap == nil {
+		snap = db.NewSnapshot()
+		defer db.ReleaseSnapshot(snap)
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	pieces := opts.Pieces
+	if pieces <= 0 {
+		pieces = parallelism
+	}
+	splits := db.SuggestSplitKeys(nil, nil, pieces)
+	ranges := make([]Range, 0, len(splits)+1)
+	var start []byte
+	for _, k := range splits {
+		ranges = append(ranges, Range{start, k})
+		start = k
+	}
+	ranges = append(ranges, Range{start, nil})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, parallelism)
+	for i, r := range ranges {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, r Range) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := db.exportRange(ctx, snap, i, r, newWriter, opts); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (db *DB) exportRange(ctx context.Context, snap *Snapshot, piece int, r Range, newWriter func(int, Range) (io.WriteCloser, error), opts ExportOptions) (err error) {
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetSnapshot(snap)
+	ro.SetFillCache(false)
+	scan := opts.Scan
+	scan.KeysOnly = false
+	s := db.NewScanner(ro, r, scan)
+	defer s.Close()
+
+	out, err := newWriter(piece, r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	var w io.Writer = out
+	var zw *gzip.Writer
+	if opts.Compress {
+		zw = gzip.NewWriter(out)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+
+	var n int
+	var lenBuf [binary.MaxVarintLen64]byte
+	for s.Next() {
+		if n++; n%exportCheckInterval == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, b := range [][]byte{s.Key(), s.Value()} {
+			bw.Write(lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(b)))])
+			if _, err := bw.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if zw != nil {
+		return zw.Close()
+	}
+	return nil
+}
+
+// ReadExport reads a range written by Export from r, calling fn for every
+// key-value pair in order. The slices passed to fn are only valid during
+// the call. compressed must match ExportOptions.Compress.
+func ReadExport(r io.Reader, compressed bool, fn func(key, value []byte) error) error {
+	if compressed {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	br := bufio.NewReader(r)
+	var kv [2][]byte
+	for {
+		for i := range kv {
+			n, err := binary.ReadUvarint(br)
+			if err == io.EOF && i == 0 {
+				return nil
+			}
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if uint64(cap(kv[i])) < n {
+				kv[i] = make([]byte, n)
+			}
+			kv[i] = kv[i][:n]
+			if _, err := io.ReadFull(br, kv[i]); err != nil {
+				return io.ErrUnexpectedEOF
+			}
+		}
+		if err := fn(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package gorocks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+)
+
+type exportBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *exportBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetWriteBufferSize(64 << 10)
+	options.SetTargetFileSizeBase(64 << 10)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	const n = 5000
+	for i := 0; i < n; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%05d", i)), bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	db.CompactRange(Range{})
+	snap := db.NewSnapshot()
+	defer db.ReleaseSnapshot(snap)
+	db.Put(wo, []byte("key99999"), []byte("after the snapshot"))
+
+	var mu sync.Mutex
+	pieces := make(map[int]*exportBuffer)
+	newWriter := func(piece int, r Range) (io.WriteCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		b := &exportBuffer{}
+		pieces[piece] = b
+		return b, nil
+	}
+	err = db.Export(context.Background(), snap, newWriter, ExportOptions{Pieces: 4, Parallelism: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(pieces) < 2 {
+		t.Errorf("expected the export to be split, got %d pieces", len(pieces))
+	}
+
+	order := make([]int, 0, len(pieces))
+	for i := range pieces {
+		order = append(order, i)
+	}
+	sort.Ints(order)
+	var i int
+	for _, p := range order {
+		if !pieces[p].closed {
+			t.Errorf("writer of piece %d not closed", p)
+		}
+		err := ReadExport(&pieces[p].Buffer, true, func(key, value []byte) error {
+			if want := fmt.Sprintf("key%05d", i); string(key) != want {
+				return fmt.Errorf("got key %q, want %q", key, want)
+			}
+			if len(value) != 100 || value[0] != byte(i) {
+				return fmt.Errorf("wrong value for %q", key)
+			}
+			i++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("piece %d: %v", p, err)
+		}
+	}
+	if i != n {
+		t.Errorf("exported %d keys, want %d", i, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.Export(ctx, nil, newWriter, ExportOptions{}); err != context.Canceled {
+		t.Errorf("Export with a canceled context: got %v", err)
+	}
+}
@@ -20,12 +20,26 @@ type FilterPolicy struct {
 // NewBloomFilter creates a filter policy that will create a bloom filter when
 // necessary with the given number of bits per key.
 //
-// See the FilterPolicy documentation for more.
+// RocksDB builds full filters, one per SST file, for it; it is equivalent
+// to NewBloomFilterFull. See the FilterPolicy documentation for more.
 func NewBloomFilter(bitsPerKey int) *FilterPolicy {
 	policy := C.rocksdb_filterpolicy_create_bloom(C.int(bitsPerKey))
 	return &FilterPolicy{policy}
 }
 
+// NewBloomFilterFull creates a filter policy that builds a single bloom
+// filter per SST file, with the given number of bits per key. It is
+// equivalent to NewBloomFilter; lookups are fastest with
+// BlockBasedTableOptions.SetFormatVersion(5) or later.
+func NewBloomFilterFull(bitsPerKey int) *FilterPolicy {
+	policy := C.rocksdb_filterpolicy_create_bloom_full(C.int(bitsPerKey))
+	return &FilterPolicy{policy}
+}
+
+// Close deallocates the FilterPolicy. It does nothing if the policy was
+// handed over to a BlockBasedTableOptions.
 func (fp *FilterPolicy) Close() {
-	C.rocksdb_filterpolicy_destroy(fp.Policy)
+	if fp.Policy != nil {
+		C.rocksdb_filterpolicy_destroy(fp.Policy)
+	}
 }
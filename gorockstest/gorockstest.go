@@ -0,0 +1,74 @@
+// Package gorockstest provides helpers for tests of code using gorocks.
+package gorockstest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alberts/gorocks"
+)
+
+// Fork opens a writable copy of db, for tests that need to mutate
+// realistic data without affecting the original. The copy is made in a
+// temporary directory by checkpointing, which hard-links the SST files, and
+// is closed and removed when the test finishes.
+func Fork(tb testing.TB, db *gorocks.DB, o *gorocks.Options) *gorocks.DB {
+	tb.Helper()
+	fork, err := db.Fork(filepath.Join(tb.TempDir(), "fork"), o)
+	if err != nil {
+		tb.Fatalf("gorockstest: forking database: %v", err)
+	}
+	tb.Cleanup(fork.Close)
+	return fork
+}
+
+// ForkDir is like Fork for a database that is not open, such as a copy of
+// production data kept as a test fixture, which is never modified. The SST
+// files are hard-linked into the copy where possible, since RocksDB never
+// changes them once written; all other files are copied.
+func ForkDir(tb testing.TB, dir string, o *gorocks.Options) *gorocks.DB {
+	tb.Helper()
+	forkDir := tb.TempDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		tb.Fatalf("gorockstest: reading %s: %v", dir, err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == "LOCK" {
+			continue
+		}
+		src, dst := filepath.Join(dir, e.Name()), filepath.Join(forkDir, e.Name())
+		if strings.HasSuffix(e.Name(), ".sst") && os.Link(src, dst) == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			tb.Fatalf("gorockstest: copying %s: %v", src, err)
+		}
+	}
+	fork, err := gorocks.Open(forkDir, o)
+	if err != nil {
+		tb.Fatalf("gorockstest: opening fork of %s: %v", dir, err)
+	}
+	tb.Cleanup(fork.Close)
+	return fork
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,43 @@
+package gorockstest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alberts/gorocks"
+)
+
+func TestFork(t *testing.T) {
+	dir := t.TempDir()
+	options := gorocks.NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := gorocks.NewReadOptions()
+	defer ro.Close()
+	wo := gorocks.NewWriteOptions()
+	defer wo.Close()
+	db, err := gorocks.Open(dir, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	db.Put(wo, []byte("key"), []byte("original"))
+
+	fork := Fork(t, db, options)
+	if err := fork.Put(wo, []byte("key"), []byte("forked")); err != nil {
+		t.Fatalf("Put to fork failed: %v", err)
+	}
+	if v, _ := db.Get(ro, []byte("key")); !bytes.Equal(v, []byte("original")) {
+		t.Errorf("original changed by write to fork: %q", v)
+	}
+	db.Close()
+
+	fork2 := ForkDir(t, dir, options)
+	if v, _ := fork2.Get(ro, []byte("key")); !bytes.Equal(v, []byte("original")) {
+		t.Errorf("ForkDir got %q, want original", v)
+	}
+	fork2.Delete(wo, []byte("key"))
+	check := ForkDir(t, dir, options)
+	if v, _ := check.Get(ro, []byte("key")); !bytes.Equal(v, []byte("original")) {
+		t.Errorf("fixture changed by write to fork: %q", v)
+	}
+}
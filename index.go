@@ -0,0 +1,182 @@
+package gorocks
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// IndexFunc extracts the secondary index keys for a primary key and value.
+// It may return any number of index keys, including none.
+type IndexFunc func(key, value []byte) [][]byte
+
+type secondaryIndex struct {
+	name string
+	fn   IndexFunc
+}
+
+// Indexer maintains secondary indexes for the data in the default column
+// family of a DB. The index entries are kept in a dedicated column family
+// and are updated in the same atomic WriteBatch as the primary data, so the
+// two never disagree.
+//
+// Writes to indexed data must go through the Indexer's Put, Delete and Write
+// methods. Writes made directly on the DB bypass the indexes.
+//
+// To prevent memory leaks, Close must be called on an Indexer when the
+// program no longer needs it.
+type Indexer struct {
+	db      *DB
+	cf      *ColumnFamilyHandle
+	ro      *ReadOptions
+	indexes []secondaryIndex
+}
+
+// NewIndexer creates an Indexer storing its index entries in the column
+// family cf of db. The column family should not be used for anything else.
+func NewIndexer(db *DB, cf *ColumnFamilyHandle) *Indexer {
+	return &Indexer{db: db, cf: cf, ro: NewReadOptions()}
+}
+
+// AddIndex registers an index under the given name. Indexes must be added
+// before the Indexer is used and always in the same way for a database;
+// entries written before an index was added are not backfilled.
+func (ix *Indexer) AddIndex(name string, fn IndexFunc) {
+	ix.indexes = append(ix.indexes, secondaryIndex{name, fn})
+}
+
+// Close releases the resources held by the Indexer. The DB and the column
+// family handle are not closed.
+func (ix *Indexer) Close() {
+	ix.ro.Close()
+}
+
+// An index entry key is the index name, a zero byte, the varint length of
+// the index key, the index key and finally the primary key. This groups the
+// entries of an index key together and lets the primary key be recovered
+// from the entry without a separate value.
+func indexEntryPrefix(name string, indexKey []byte) []byte {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(indexKey)))
+	prefix := make([]byte, 0, len(name)+1+n+len(indexKey))
+	prefix = append(prefix, name...)
+	prefix = append(prefix, 0)
+	prefix = append(prefix, lenBuf[:n]...)
+	return append(prefix, indexKey...)
+}
+
+// Put writes a key-value pair and updates the index entries derived from
+// it.
+func (ix *Indexer) Put(wo *WriteOptions, key, value []byte) error {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Put(key, value)
+	return ix.Write(wo, wb)
+}
+
+// Delete removes a key and the index entries derived from it.
+func (ix *Indexer) Delete(wo *WriteOptions, key []byte) error {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Delete(key)
+	return ix.Write(wo, wb)
+}
+
+// Write atomically writes the Puts and Deletes of w, which must all target
+// the default column family, together with the index updates they imply.
+// w itself is left unmodified. LogData, such as schema tags, is kept in
+// place among the records. Batches with merges or range deletions are
+// rejected, since the index entries they affect cannot be determined from
+// the batch.
+func (ix *Indexer) Write(wo *WriteOptions, w *WriteBatch) error {
+	var keys [][]byte
+	it := w.NewIterator()
+	for it.Next() {
+		rec := it.Record()
+		if rec.ColumnFamilyID != 0 || rec.Type == RecordTypeMerge || rec.Type == RecordTypeRangeDeletion {
+			return DatabaseError("indexer: only default column family puts and deletes can be indexed")
+		}
+		if rec.Type != RecordTypeLogData {
+			keys = append(keys, rec.Key)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	// Hold the keys' stripes from reading the old values until the write
+	// is done, so concurrent writers cannot leave stale index entries.
+	unlock := ix.db.updateLocks.lockKeys(keys)
+	defer unlock()
+
+	out := NewWriteBatch()
+	defer out.Close()
+	// pending tracks values written earlier in the same batch, which
+	// later records must treat as the old value.
+	pending := make(map[string][]byte)
+	it = w.NewIterator()
+	for it.Next() {
+		rec := it.Record()
+		switch rec.Type {
+		case RecordTypeValue:
+			if err := ix.unindex(out, pending, rec.Key); err != nil {
+				return err
+			}
+			out.Put(rec.Key, rec.Value)
+			ix.index(out, rec.Key, rec.Value)
+			pending[string(rec.Key)] = rec.Value
+		case RecordTypeDeletion:
+			if err := ix.unindex(out, pending, rec.Key); err != nil {
+				return err
+			}
+			out.Delete(rec.Key)
+			pending[string(rec.Key)] = nil
+		case RecordTypeLogData:
+			out.PutLogData(rec.Key)
+		}
+	}
+	return ix.db.Write(wo, out)
+}
+
+func (ix *Indexer) index(out *WriteBatch, key, value []byte) {
+	for _, idx := range ix.indexes {
+		for _, ik := range idx.fn(key, value) {
+			out.PutCF(ix.cf, append(indexEntryPrefix(idx.name, ik), key...), nil)
+		}
+	}
+}
+
+func (ix *Indexer) unindex(out *WriteBatch, pending map[string][]byte, key []byte) error {
+	old, ok := pending[string(key)]
+	if !ok {
+		var err error
+		if old, err = ix.db.Get(ix.ro, key); err != nil {
+			return err
+		}
+	}
+	if old == nil {
+		return nil
+	}
+	for _, idx := range ix.indexes {
+		for _, ik := range idx.fn(key, old) {
+			out.DeleteCF(ix.cf, append(indexEntryPrefix(idx.name, ik), key...))
+		}
+	}
+	return nil
+}
+
+// Lookup returns the primary keys whose values map to indexKey in the index
+// with the given name, in key order.
+func (ix *Indexer) Lookup(ro *ReadOptions, name string, indexKey []byte) ([][]byte, error) {
+	prefix := indexEntryPrefix(name, indexKey)
+	it := ix.db.NewIteratorCF(ro, ix.cf)
+	defer it.Close()
+	var keys [][]byte
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		k := it.Key()
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		keys = append(keys, k[len(prefix):])
+	}
+	return keys, it.GetError()
+}
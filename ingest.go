@@ -0,0 +1,110 @@
+package gorocks
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"unsafe"
+)
+
+// IngestExternalFileOptions represent the options for adding SST files
+// built with SstFileWriter to a database with IngestExternalFile.
+//
+// To prevent memory leaks, Close must be called on an
+// IngestExternalFileOptions when the program no longer needs it.
+type IngestExternalFileOptions struct {
+	Opt *C.rocksdb_ingestexternalfileoptions_t
+}
+
+// NewIngestExternalFileOptions allocates a new IngestExternalFileOptions
+// with the default settings.
+func NewIngestExternalFileOptions() *IngestExternalFileOptions {
+	return &IngestExternalFileOptions{C.rocksdb_ingestexternalfileoptions_create()}
+}
+
+// SetMoveFiles, if true, moves the files into the database instead of
+// copying them. The files are hard-linked, so they must be on the same
+// filesystem as the database.
+func (o *IngestExternalFileOptions) SetMoveFiles(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_move_files(o.Opt, boolToUchar(b))
+}
+
+// SetSnapshotConsistency, if true, the default, keeps the ingested keys
+// invisible to Snapshots taken before the ingestion.
+func (o *IngestExternalFileOptions) SetSnapshotConsistency(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_snapshot_consistency(o.Opt, boolToUchar(b))
+}
+
+// SetAllowGlobalSeqNo, if true, the default, allows ingesting files whose
+// keys overlap with keys already in the database, by assigning them a new
+// sequence number. If false, such ingestions fail.
+func (o *IngestExternalFileOptions) SetAllowGlobalSeqNo(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_allow_global_seqno(o.Opt, boolToUchar(b))
+}
+
+// SetAllowBlockingFlush, if true, the default, lets the ingestion flush the
+// memtable when it overlaps with the files, blocking writes meanwhile. If
+// false, such ingestions fail.
+func (o *IngestExternalFileOptions) SetAllowBlockingFlush(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_allow_blocking_flush(o.Opt, boolToUchar(b))
+}
+
+// SetIngestBehind, if true, places the files at the bottom of the LSM tree,
+// so that keys already in the database take precedence over them. It
+// requires a database opened with Options.SetAllowIngestBehind, and implies
+// SetAllowGlobalSeqNo.
+func (o *IngestExternalFileOptions) SetIngestBehind(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_ingest_behind(o.Opt, boolToUchar(b))
+}
+
+// SetFailIfNotBottommostLevel, if true, fails the ingestion if the files
+// cannot be placed in the bottommost level.
+func (o *IngestExternalFileOptions) SetFailIfNotBottommostLevel(b bool) {
+	C.rocksdb_ingestexternalfileoptions_set_fail_if_not_bottommost_level(o.Opt, boolToUchar(b))
+}
+
+// Close deallocates the IngestExternalFileOptions, freeing its underlying C
+// struct.
+func (o *IngestExternalFileOptions) Close() {
+	C.rocksdb_ingestexternalfileoptions_destroy(o.Opt)
+}
+
+// IngestExternalFile adds the SST files at paths, built with SstFileWriter,
+// to the default column family. This is far faster than writing the same
+// keys with Put, since the files are added to the LSM tree as they are.
+// The key ranges of the files must not overlap each other.
+func (db *DB) IngestExternalFile(paths []string, o *IngestExternalFileOptions) error {
+	return db.ingestExternalFile(nil, paths, o)
+}
+
+// IngestExternalFileCF is like IngestExternalFile for the given column
+// family.
+func (db *DB) IngestExternalFileCF(cf *ColumnFamilyHandle, paths []string, o *IngestExternalFileOptions) error {
+	return db.ingestExternalFile(cf, paths, o)
+}
+
+func (db *DB) ingestExternalFile(cf *ColumnFamilyHandle, paths []string, o *IngestExternalFileOptions) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	var errStr *C.char
+	cpaths := make([]*C.char, len(paths))
+	for i, p := range paths {
+		cpaths[i] = C.CString(p)
+		defer C.free(unsafe.Pointer(cpaths[i]))
+	}
+	list := (**C.char)(unsafe.Pointer(&cpaths[0]))
+
+	if cf == nil {
+		C.rocksdb_ingest_external_file(db.Ldb, list, C.size_t(len(paths)), o.Opt, &errStr)
+	} else {
+		C.rocksdb_ingest_external_file_cf(db.Ldb, cf.Handle, list, C.size_t(len(paths)), o.Opt, &errStr)
+	}
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
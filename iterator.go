@@ -5,15 +5,28 @@ package gorocks
 import "C"
 
 import (
+	"iter"
+	"sync"
+	"time"
 	"unsafe"
 )
 
 type IteratorError string
 
+// ErrIteratorClosed is returned by GetError once an Iterator has been
+// closed, including when its DB was closed underneath it.
+const ErrIteratorClosed = IteratorError("gorocks: iterator is closed")
+
 func (e IteratorError) Error() string {
 	return string(e)
 }
 
+// Is reports whether the error is of the kind target stands for, one of
+// ErrCorruption, ErrIO and ErrIncomplete.
+func (e IteratorError) Is(target error) bool {
+	return statusIs(string(e), target)
+}
+
 // Iterator is a read-only iterator through a LevelDB database. It provides a
 // way to seek to specific keys and iterate through the keyspace from that
 // point, as well as access the values of those keys.
@@ -45,20 +58,65 @@ func (e IteratorError) Error() string {
 // is no longer needed by the program.
 type Iterator struct {
 	Iter *C.rocksdb_iterator_t
+
+	db           *DB
+	cf           *ColumnFamilyHandle
+	snap         *Snapshot
+	lower, upper []byte
+	backward     bool
+	pinned       bool // created with ReadOptions.SetPinData
+
+	// err is the error that ended the last range loop over All.
+	err error
+}
+
+// IteratorState describes where an Iterator stands. See Iterator.Status.
+type IteratorState int
+
+const (
+	// IteratorValid means the Iterator is positioned at a key.
+	IteratorValid IteratorState = iota
+	// IteratorExhausted means the Iterator moved past the first or last key
+	// in the database.
+	IteratorExhausted
+	// IteratorAtBound means the Iterator stopped at its lower or upper bound
+	// while more keys exist beyond it.
+	IteratorAtBound
+	// IteratorFailed means the Iterator encountered an error.
+	IteratorFailed
+)
+
+// IteratorStatus is the structured status of an Iterator.
+type IteratorStatus struct {
+	State IteratorState
+	// Err is set when State is IteratorFailed.
+	Err error
 }
 
 // Valid returns false only when an Iterator has iterated past either the
 // first or the last key in the database.
 func (it *Iterator) Valid() bool {
-	return ucharToBool(C.rocksdb_iter_valid(it.Iter))
+	if it.Iter == nil {
+		return false
+	}
+	ct := cgoStart()
+	valid := C.rocksdb_iter_valid(it.Iter)
+	cgoDone(CgoIteratorValid, ct)
+	return ucharToBool(valid)
 }
 
 // Key returns a copy the key in the database the iterator currently holds.
 //
-// If Valid returns false, this method will panic.
+// If Valid returns false, this method will panic. Once the Iterator is
+// closed, it returns nil.
 func (it *Iterator) Key() []byte {
+	if it.Iter == nil {
+		return nil
+	}
 	var klen C.size_t
+	ct := cgoStart()
 	kdata := C.rocksdb_iter_key(it.Iter, &klen)
+	cgoDone(CgoIteratorKey, ct)
 	if kdata == nil {
 		return nil
 	}
@@ -69,13 +127,98 @@ func (it *Iterator) Key() []byte {
 	return C.GoBytes(unsafe.Pointer(kdata), C.int(klen))
 }
 
+// AppendKey appends the key the iterator currently holds to dst and returns
+// the extended slice. Unlike Key, it allocates only when dst is too small,
+// which makes it cheaper for scans that look at many keys.
+//
+// If Valid returns false, this method will panic.
+func (it *Iterator) AppendKey(dst []byte) []byte {
+	if it.Iter == nil {
+		return dst
+	}
+	var klen C.size_t
+	ct := cgoStart()
+	kdata := C.rocksdb_iter_key(it.Iter, &klen)
+	cgoDone(CgoIteratorKey, ct)
+	if kdata == nil {
+		return dst
+	}
+	return append(dst, (*[1 << 30]byte)(unsafe.Pointer(kdata))[:klen:klen]...)
+}
+
+// AppendValue is like AppendKey, but appends the current value.
+//
+// If Valid returns false, this method will panic.
+func (it *Iterator) AppendValue(dst []byte) []byte {
+	if it.Iter == nil {
+		return dst
+	}
+	var vlen C.size_t
+	ct := cgoStart()
+	vdata := C.rocksdb_iter_value(it.Iter, &vlen)
+	cgoDone(CgoIteratorValue, ct)
+	if vdata == nil {
+		return dst
+	}
+	return append(dst, (*[1 << 30]byte)(unsafe.Pointer(vdata))[:vlen:vlen]...)
+}
+
+// PinnedKey returns the key the iterator currently holds without copying
+// it. The slice points into RocksDB's memory and remains valid, and must
+// not be modified, until the Iterator is closed, which releases it. It must
+// not be used after that.
+//
+// PinnedKey panics unless the Iterator was created with a ReadOptions on
+// which SetPinData was set, or if Valid returns false.
+func (it *Iterator) PinnedKey() []byte {
+	if !it.pinned {
+		panic("gorocks: PinnedKey needs an Iterator created with ReadOptions.SetPinData")
+	}
+	if it.Iter == nil {
+		return nil
+	}
+	var klen C.size_t
+	ct := cgoStart()
+	kdata := C.rocksdb_iter_key(it.Iter, &klen)
+	cgoDone(CgoIteratorKey, ct)
+	if kdata == nil {
+		return nil
+	}
+	return (*[1 << 30]byte)(unsafe.Pointer(kdata))[:klen:klen]
+}
+
+// PinnedValue is like PinnedKey for the current value. Values computed by
+// a merge operator or read from blob files are not pinned, though, and are
+// only valid until the Iterator moves.
+func (it *Iterator) PinnedValue() []byte {
+	if !it.pinned {
+		panic("gorocks: PinnedValue needs an Iterator created with ReadOptions.SetPinData")
+	}
+	if it.Iter == nil {
+		return nil
+	}
+	var vlen C.size_t
+	ct := cgoStart()
+	vdata := C.rocksdb_iter_value(it.Iter, &vlen)
+	cgoDone(CgoIteratorValue, ct)
+	if vdata == nil {
+		return nil
+	}
+	return (*[1 << 30]byte)(unsafe.Pointer(vdata))[:vlen:vlen]
+}
+
 // Value returns a copy of the value in the database the iterator currently
 // holds.
 //
 // If Valid returns false, this method will panic.
 func (it *Iterator) Value() []byte {
+	if it.Iter == nil {
+		return nil
+	}
 	var vlen C.size_t
+	ct := cgoStart()
 	vdata := C.rocksdb_iter_value(it.Iter, &vlen)
+	cgoDone(CgoIteratorValue, ct)
 	if vdata == nil {
 		return nil
 	}
@@ -91,7 +234,16 @@ func (it *Iterator) Value() []byte {
 //
 // If Valid returns false, this method will panic.
 func (it *Iterator) Next() {
+	if it.Iter == nil {
+		return
+	}
+	if h := it.opHook(); h != nil {
+		defer it.iteratorStep(h, OpIteratorNext, time.Now())
+	}
+	it.backward = false
+	ct := cgoStart()
 	C.rocksdb_iter_next(it.Iter)
+	cgoDone(CgoIteratorStep, ct)
 }
 
 // Prev moves the iterator to the previous sequential key in the database, as
@@ -99,7 +251,16 @@ func (it *Iterator) Next() {
 //
 // If Valid returns false, this method will panic.
 func (it *Iterator) Prev() {
+	if it.Iter == nil {
+		return
+	}
+	if h := it.opHook(); h != nil {
+		defer it.iteratorStep(h, OpIteratorPrev, time.Now())
+	}
+	it.backward = true
+	ct := cgoStart()
 	C.rocksdb_iter_prev(it.Iter)
+	cgoDone(CgoIteratorStep, ct)
 }
 
 // SeekToFirst moves the iterator to the first key in the database, as defined
@@ -107,7 +268,16 @@ func (it *Iterator) Prev() {
 //
 // This method is safe to call when Valid returns false.
 func (it *Iterator) SeekToFirst() {
+	if it.Iter == nil {
+		return
+	}
+	if h := it.opHook(); h != nil {
+		defer it.iteratorStep(h, OpIteratorSeek, time.Now())
+	}
+	it.backward = false
+	ct := cgoStart()
 	C.rocksdb_iter_seek_to_first(it.Iter)
+	cgoDone(CgoIteratorSeek, ct)
 }
 
 // SeekToLast moves the iterator to the last key in the database, as defined
@@ -115,7 +285,16 @@ func (it *Iterator) SeekToFirst() {
 //
 // This method is safe to call when Valid returns false.
 func (it *Iterator) SeekToLast() {
+	if it.Iter == nil {
+		return
+	}
+	if h := it.opHook(); h != nil {
+		defer it.iteratorStep(h, OpIteratorSeek, time.Now())
+	}
+	it.backward = true
+	ct := cgoStart()
 	C.rocksdb_iter_seek_to_last(it.Iter)
+	cgoDone(CgoIteratorSeek, ct)
 }
 
 // Seek moves the iterator the position of the key given or, if the key
@@ -124,14 +303,35 @@ func (it *Iterator) SeekToLast() {
 //
 // This method is safe to call when Valid returns false.
 func (it *Iterator) Seek(key []byte) {
-	C.rocksdb_iter_seek(it.Iter, (*C.char)(unsafe.Pointer(&key[0])), C.size_t(len(key)))
+	if it.Iter == nil {
+		return
+	}
+	if h := it.opHook(); h != nil {
+		defer it.iteratorStep(h, OpIteratorSeek, time.Now())
+	}
+	it.backward = false
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	ct := cgoStart()
+	C.rocksdb_iter_seek(it.Iter, k, C.size_t(len(key)))
+	cgoDone(CgoIteratorSeek, ct)
 }
 
 // GetError returns an IteratorError from LevelDB if it had one during
-// iteration.
+// iteration. The error can be matched against ErrCorruption, ErrIO and
+// ErrIncomplete with errors.Is. If the last range loop over All ended on an
+// error, that error is returned, even after Close.
 //
 // This method is safe to call when Valid returns false.
 func (it *Iterator) GetError() error {
+	if it.err != nil {
+		return it.err
+	}
+	if it.Iter == nil {
+		return ErrIteratorClosed
+	}
 	var errStr *C.char
 	C.rocksdb_iter_get_error(it.Iter, &errStr)
 	if errStr != nil {
@@ -142,8 +342,157 @@ func (it *Iterator) GetError() error {
 	return nil
 }
 
+// LowerBound returns the inclusive lower bound the Iterator was created
+// with, or nil if it has none. See ReadOptions.SetIterateLowerBound.
+func (it *Iterator) LowerBound() []byte {
+	return it.lower
+}
+
+// UpperBound returns the exclusive upper bound the Iterator was created
+// with, or nil if it has none. See ReadOptions.SetIterateUpperBound.
+func (it *Iterator) UpperBound() []byte {
+	return it.upper
+}
+
+// Status reports whether the Iterator is positioned at a key, has failed,
+// or, if it is simply invalid, whether it ran out of data or stopped at the
+// bound in the direction it was last moving. This lets pagination code tell
+// a finished range apart from a finished database.
+//
+// Telling the two invalid states apart requires looking past the bound with
+// a second, unbounded iterator. Unless the Iterator was created with a
+// Snapshot, keys written since may be taken into account.
+//
+// This method is safe to call when Valid returns false.
+func (it *Iterator) Status() IteratorStatus {
+	if it.Valid() {
+		return IteratorStatus{State: IteratorValid}
+	}
+	if err := it.GetError(); err != nil {
+		return IteratorStatus{State: IteratorFailed, Err: err}
+	}
+	bound := it.upper
+	if it.backward {
+		bound = it.lower
+	}
+	if bound == nil || it.db == nil || !it.existsBeyond(bound) {
+		return IteratorStatus{State: IteratorExhausted}
+	}
+	return IteratorStatus{State: IteratorAtBound}
+}
+
+// existsBeyond reports whether there is a key at or after bound when moving
+// forward, or before bound when moving backward.
+func (it *Iterator) existsBeyond(bound []byte) bool {
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetSnapshot(it.snap)
+	var probe *Iterator
+	if it.cf != nil {
+		probe = it.db.NewIteratorCF(ro, it.cf)
+	} else {
+		probe = it.db.NewIterator(ro)
+	}
+	defer probe.Close()
+	if it.backward {
+		probe.Seek(bound)
+		if probe.Valid() {
+			probe.Prev()
+		} else {
+			probe.SeekToLast()
+		}
+	} else {
+		probe.Seek(bound)
+	}
+	return probe.Valid()
+}
+
+// All returns the key-value pairs from the current position of the
+// Iterator onwards, for use in a range loop. The keys and values are
+// copies, as returned by Key and Value. Position the Iterator first, for
+// example with SeekToFirst or Seek.
+//
+// A range loop cannot tell running out of keys apart from failing, so
+// callers must check Err after it; nothing else reports the error.
+//
+//	it.SeekToFirst()
+//	for k, v := range it.All() {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (it *Iterator) All() iter.Seq2[[]byte, []byte] {
+	return func(yield func(key, value []byte) bool) {
+		it.err = nil
+		for ; it.Valid(); it.Next() {
+			if !yield(it.Key(), it.Value()) {
+				return
+			}
+		}
+		if err := it.GetError(); err != nil {
+			it.err = err
+		}
+	}
+}
+
+// Err returns the error that ended the last range loop over All, or
+// otherwise the error returned by GetError. After Close it returns only the
+// error of the last loop, nil if the loop ended cleanly, so it can be
+// checked after a deferred Close has run; GetError returns
+// ErrIteratorClosed instead.
+//
+// This method is safe to call when Valid returns false.
+func (it *Iterator) Err() error {
+	if it.Iter == nil {
+		return it.err
+	}
+	return it.GetError()
+}
+
 // Close deallocates the given Iterator, freeing the underlying C struct.
+// Closing an Iterator again, or after its DB was closed, does nothing.
 func (it *Iterator) Close() {
-	C.rocksdb_iter_destroy(it.Iter)
-	it.Iter = nil
+	if it.Iter != nil {
+		C.rocksdb_iter_destroy(it.Iter)
+		it.Iter = nil
+		if it.db != nil {
+			it.db.iterators.remove(it)
+		}
+	}
+}
+
+// iteratorRegistry tracks the open Iterators of a DB, so that Close can
+// close them before the DB.
+type iteratorRegistry struct {
+	mu   sync.Mutex
+	live map[*Iterator]struct{}
+}
+
+func (r *iteratorRegistry) add(it *Iterator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.live == nil {
+		r.live = make(map[*Iterator]struct{})
+	}
+	r.live[it] = struct{}{}
+}
+
+func (r *iteratorRegistry) remove(it *Iterator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.live, it)
+}
+
+// closeAll destroys all open Iterators. Their methods check for the nil Iter
+// it leaves behind, so that using them afterwards reports them invalid
+// rather than calling into freed memory.
+func (r *iteratorRegistry) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for it := range r.live {
+		C.rocksdb_iter_destroy(it.Iter)
+		it.Iter = nil
+	}
+	r.live = nil
 }
@@ -0,0 +1,72 @@
+package gorocks
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrBatchTooLarge is matched by the error returned when a WriteBatch
+// exceeds the WriteLimits of the DB it is written to. The returned error is
+// a *BatchTooLargeError with more detail.
+var ErrBatchTooLarge = errors.New("write batch too large")
+
+// WriteLimits caps the size of the WriteBatches accepted by DB.Write, so
+// that a buggy producer cannot submit a batch large enough to stall the
+// write path. A zero field means no limit.
+type WriteLimits struct {
+	// MaxBatchBytes limits the size of the batch's serialized data.
+	MaxBatchBytes int
+	// MaxBatchOps limits the number of records in the batch.
+	MaxBatchOps int
+}
+
+// BatchTooLargeError reports a WriteBatch rejected by WriteLimits.
+type BatchTooLargeError struct {
+	Bytes  int
+	Ops    int
+	Limits WriteLimits
+}
+
+func (e *BatchTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %d bytes, %d ops (limits: %d bytes, %d ops)",
+		ErrBatchTooLarge, e.Bytes, e.Ops, e.Limits.MaxBatchBytes, e.Limits.MaxBatchOps)
+}
+
+// Is reports whether target is ErrBatchTooLarge.
+func (e *BatchTooLargeError) Is(target error) bool {
+	return target == ErrBatchTooLarge
+}
+
+type writeLimits struct {
+	maxBatchBytes int64
+	maxBatchOps   int64
+}
+
+// CheckLimits returns a *BatchTooLargeError if the batch exceeds l.
+func (w *WriteBatch) CheckLimits(l WriteLimits) error {
+	if l.MaxBatchBytes == 0 && l.MaxBatchOps == 0 {
+		return nil
+	}
+	bytes, ops := len(w.Data()), w.Count()
+	if (l.MaxBatchBytes > 0 && bytes > l.MaxBatchBytes) || (l.MaxBatchOps > 0 && ops > l.MaxBatchOps) {
+		return &BatchTooLargeError{Bytes: bytes, Ops: ops, Limits: l}
+	}
+	return nil
+}
+
+// SetWriteLimits sets the limits enforced by Write on this DB handle.
+// Batches over the limits are rejected with a *BatchTooLargeError before
+// reaching RocksDB. Single-key writes are not checked.
+func (db *DB) SetWriteLimits(l WriteLimits) {
+	atomic.StoreInt64(&db.limits.maxBatchBytes, int64(l.MaxBatchBytes))
+	atomic.StoreInt64(&db.limits.maxBatchOps, int64(l.MaxBatchOps))
+}
+
+// WriteLimits returns the limits set by SetWriteLimits.
+func (db *DB) WriteLimits() WriteLimits {
+	return WriteLimits{
+		MaxBatchBytes: int(atomic.LoadInt64(&db.limits.maxBatchBytes)),
+		MaxBatchOps:   int(atomic.LoadInt64(&db.limits.maxBatchOps)),
+	}
+}
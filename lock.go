@@ -0,0 +1,84 @@
+package gorocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrDBLocked is matched by the error returned when a database cannot be
+// opened because another DB handle, in this or another process, holds its
+// LOCK file. The returned error is a *DBLockedError with more detail.
+var ErrDBLocked = errors.New("database is locked")
+
+// DBLockedError is returned by Open and its variants when the database's
+// LOCK file is held by someone else.
+type DBLockedError struct {
+	// Path is the path of the LOCK file.
+	Path string
+	// PID is the process holding the lock, or 0 if it could not be found.
+	// It is the current process when the database is already open here.
+	PID int
+	// Msg is the error message reported by RocksDB.
+	Msg string
+}
+
+func (e *DBLockedError) Error() string {
+	if e.PID != 0 {
+		return fmt.Sprintf("%s: held by pid %d: %s", ErrDBLocked, e.PID, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", ErrDBLocked, e.Msg)
+}
+
+// Is reports whether target is ErrDBLocked.
+func (e *DBLockedError) Is(target error) bool {
+	return target == ErrDBLocked
+}
+
+// openError turns the error message of a failed open into an error,
+// recognizing lock conflicts.
+func openError(dbname, msg string) error {
+	if !isLockConflict(msg) {
+		return DatabaseError(msg)
+	}
+	path := filepath.Join(dbname, "LOCK")
+	return &DBLockedError{Path: path, PID: lockHolder(path), Msg: msg}
+}
+
+// isLockConflict reports whether msg is the message RocksDB gives when the
+// LOCK file is already locked, by this process ("lock hold by current
+// process") or by another one (flock failing with EAGAIN). Other failures
+// involving the LOCK file, such as being unable to create it, are not
+// conflicts and retrying them would not help.
+func isLockConflict(msg string) bool {
+	return strings.Contains(msg, "lock hold by current process") ||
+		strings.Contains(msg, "While lock file") &&
+			strings.Contains(msg, "Resource temporarily unavailable")
+}
+
+// openRetryInterval is how long OpenContext waits between attempts while
+// the database is locked.
+const openRetryInterval = 50 * time.Millisecond
+
+// OpenContext is like Open, but while the database is locked by another
+// handle it keeps retrying until ctx is done instead of failing at once.
+// This lets a process restarting on the same database wait for its
+// predecessor to exit. Other errors are returned immediately.
+//
+// When ctx is done first, the last *DBLockedError is returned.
+func OpenContext(ctx context.Context, dbname string, o *Options) (*DB, error) {
+	for {
+		db, err := Open(dbname, o)
+		if !errors.Is(err, ErrDBLocked) {
+			return db, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(openRetryInterval):
+		}
+	}
+}
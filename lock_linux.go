@@ -0,0 +1,46 @@
+package gorocks
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockHolder returns the pid holding a POSIX lock on the file at path, as
+// listed in /proc/locks, or 0 if it cannot be determined.
+func lockHolder(path string) int {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	f, err := os.Open("/proc/locks")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	// Lines look like "1: POSIX  ADVISORY  WRITE 1234 08:01:5678 0 EOF",
+	// the fields after the pid being major:minor:inode of the file.
+	suffix := fmt.Sprintf(":%d", st.Ino)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 6 || fields[1] == "->" {
+			continue
+		}
+		if !strings.HasSuffix(fields[5], suffix) {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil {
+			return pid
+		}
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package gorocks
+
+// lockHolder is only implemented on Linux.
+func lockHolder(path string) int {
+	return 0
+}
@@ -0,0 +1,203 @@
+package gorocks
+
+import (
+	"container/list"
+	"path/filepath"
+	"sync"
+)
+
+// ErrManagerFull is returned by Manager.Acquire when MaxOpen databases are
+// open and all of them are in use.
+var ErrManagerFull = DatabaseError("manager: all open databases are in use")
+
+// ManagerOptions configures a Manager.
+type ManagerOptions struct {
+	// Dir is the directory holding one database per name.
+	Dir string
+	// Options is the template the databases are opened with. The Manager
+	// works on a clone, so the caller keeps ownership of it.
+	Options *Options
+	// MaxOpen is the maximum number of databases open at once. Idle
+	// databases are closed, least recently used first, to stay below it.
+	MaxOpen int
+	// MaxOpenFiles is the budget of SST files kept open by all open
+	// databases together. Each database gets MaxOpenFiles/MaxOpen, but no
+	// less than 20, the minimum RocksDB accepts, so a budget below
+	// 20*MaxOpen is exceeded. Each database also keeps a few files, such as
+	// its write ahead log and MANIFEST, open outside the budget. Zero
+	// leaves the template's setting alone.
+	MaxOpenFiles int
+	// CacheSize is the capacity of the block cache shared by all databases.
+	// Zero leaves the template's setting alone.
+	CacheSize int
+	// WriteBufferSize is the total memtable memory shared by all databases.
+	// Zero leaves the template's setting alone.
+	WriteBufferSize int
+}
+
+// minMaxOpenFiles is the smallest max_open_files RocksDB accepts; it
+// raises smaller settings to it.
+const minMaxOpenFiles = 20
+
+type managedDB struct {
+	name string
+	db   *DB
+	err  error
+	// ready is closed when the attempt to open the database is over, and
+	// closed when the database has been closed after being evicted.
+	ready, closed chan struct{}
+	refs          int
+	idle          *list.Element // position in Manager.idle while refs == 0
+}
+
+// Manager opens and tracks many databases, for example one per tenant, in a
+// DBGroup, so that they share one set of Resources. Databases are opened
+// lazily by Acquire and closed again once idle when the MaxOpen budget is
+// needed for others.
+//
+// A Manager may be shared between goroutines. Databases are opened and
+// closed without holding up Acquire calls for other databases.
+type Manager struct {
+	dir     string
+	maxOpen int
+	group   *DBGroup
+
+	mu      sync.Mutex
+	dbs     map[string]*managedDB // including those being opened
+	closing map[string]*managedDB // evicted, but not closed yet
+	idle    *list.List            // of *managedDB, least recently released first
+}
+
+// NewManager creates a Manager with the given options.
+func NewManager(mo ManagerOptions) *Manager {
+	m := &Manager{
+		dir:     mo.Dir,
+		maxOpen: mo.MaxOpen,
+		group:   NewDBGroup(mo.Options, ResourcesOptions{CacheSize: mo.CacheSize, WriteBufferSize: mo.WriteBufferSize}),
+		dbs:     make(map[string]*managedDB),
+		closing: make(map[string]*managedDB),
+		idle:    list.New(),
+	}
+	if m.maxOpen <= 0 {
+		m.maxOpen = 1
+	}
+	if mo.MaxOpenFiles > 0 {
+		perDB := mo.MaxOpenFiles / m.maxOpen
+		if perDB < minMaxOpenFiles {
+			perDB = minMaxOpenFiles
+		}
+		m.group.opts.SetMaxOpenFiles(perDB)
+	}
+	return m
+}
+
+// Stats returns the aggregate accounting of the open databases and the
+// resources they share.
+func (m *Manager) Stats() DBGroupStats {
+	return m.group.Stats()
+}
+
+// Acquire returns the database with the given name, opening it if needed.
+// Every successful Acquire must be paired with a Release once the caller is
+// done with the DB; the DB must not be closed by the caller.
+func (m *Manager) Acquire(name string) (*DB, error) {
+	m.mu.Lock()
+	// A database being closed after eviction holds its LOCK file until
+	// it is done.
+	for old, ok := m.closing[name]; ok; old, ok = m.closing[name] {
+		m.mu.Unlock()
+		<-old.closed
+		m.mu.Lock()
+	}
+	if mdb, ok := m.dbs[name]; ok {
+		if mdb.idle != nil {
+			m.idle.Remove(mdb.idle)
+			mdb.idle = nil
+		}
+		mdb.refs++
+		m.mu.Unlock()
+		<-mdb.ready
+		return mdb.db, mdb.err
+	}
+
+	var evicted []*managedDB
+	for len(m.dbs) >= m.maxOpen {
+		oldest := m.idle.Front()
+		if oldest == nil {
+			m.mu.Unlock()
+			m.closeEvicted(evicted)
+			return nil, ErrManagerFull
+		}
+		evicted = append(evicted, m.evict(oldest.Value.(*managedDB)))
+	}
+	mdb := &managedDB{name: name, refs: 1, ready: make(chan struct{})}
+	m.dbs[name] = mdb
+	m.mu.Unlock()
+
+	m.closeEvicted(evicted)
+	db, err := m.group.Open(filepath.Join(m.dir, name))
+	m.mu.Lock()
+	if err != nil {
+		// Callers waiting on ready get the error too; the next Acquire
+		// tries again.
+		delete(m.dbs, name)
+	}
+	mdb.db, mdb.err = db, err
+	close(mdb.ready)
+	m.mu.Unlock()
+	return db, err
+}
+
+// Release gives back a DB obtained from Acquire. Once a DB is no longer
+// acquired by anyone it becomes eligible for closing.
+func (m *Manager) Release(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mdb, ok := m.dbs[name]
+	if !ok || mdb.refs == 0 {
+		panic("gorocks: Release of a database that is not acquired: " + name)
+	}
+	mdb.refs--
+	if mdb.refs == 0 {
+		mdb.idle = m.idle.PushBack(mdb)
+	}
+}
+
+// evict removes an idle database, which must then be passed to
+// closeEvicted. m.mu must be held.
+func (m *Manager) evict(mdb *managedDB) *managedDB {
+	m.idle.Remove(mdb.idle)
+	mdb.idle = nil
+	delete(m.dbs, mdb.name)
+	mdb.closed = make(chan struct{})
+	m.closing[mdb.name] = mdb
+	return mdb
+}
+
+// closeEvicted closes the databases removed by evict. m.mu must not be held.
+func (m *Manager) closeEvicted(evicted []*managedDB) {
+	for _, mdb := range evicted {
+		mdb.db.Close()
+		m.mu.Lock()
+		delete(m.closing, mdb.name)
+		m.mu.Unlock()
+		close(mdb.closed)
+	}
+}
+
+// NumOpen returns the number of databases currently open or being opened.
+func (m *Manager) NumOpen() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.dbs)
+}
+
+// Close closes all databases and the shared resources. It must only be
+// called once no database is acquired anymore and no Acquire is in
+// progress.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.dbs = nil
+	m.mu.Unlock()
+	m.group.Close()
+}
@@ -0,0 +1,110 @@
+package gorocks
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	m := NewManager(ManagerOptions{
+		Dir:             dir,
+		Options:         options,
+		MaxOpen:         2,
+		MaxOpenFiles:    100,
+		CacheSize:       1 << 20,
+		WriteBufferSize: 8 << 20,
+	})
+	options.Close()
+	defer m.Close()
+
+	wo := NewWriteOptions()
+	defer wo.Close()
+	for _, name := range []string{"a", "b"} {
+		db, err := m.Acquire(name)
+		if err != nil {
+			t.Fatalf("Acquire(%q) failed: %v", name, err)
+		}
+		db.Put(wo, []byte("tenant"), []byte(name))
+	}
+	if _, err := m.Acquire("c"); err != ErrManagerFull {
+		t.Errorf("expected ErrManagerFull, got %v", err)
+	}
+
+	m.Release("a")
+	db, err := m.Acquire("c")
+	if err != nil {
+		t.Fatalf("Acquire should evict the idle database: %v", err)
+	}
+	if m.NumOpen() != 2 {
+		t.Errorf("expected 2 open databases, got %d", m.NumOpen())
+	}
+	m.Release("c")
+	m.Release("b")
+
+	db, err = m.Acquire("a")
+	if err != nil {
+		t.Fatalf("reacquiring an evicted database failed: %v", err)
+	}
+	ro := NewReadOptions()
+	defer ro.Close()
+	CheckGet(t, "reopened tenant", db, ro, []byte("tenant"), []byte("a"))
+	m.Release("a")
+}
+
+func TestManagerConcurrentAcquire(t *testing.T) {
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	m := NewManager(ManagerOptions{Dir: dir, Options: options, MaxOpen: 1, MaxOpenFiles: 5})
+	options.Close()
+	defer m.Close()
+
+	const n = 8
+	dbs := make([]*DB, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := m.Acquire("shared")
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			dbs[i] = db
+		}(i)
+	}
+	wg.Wait()
+	for i := 1; i < n; i++ {
+		if dbs[i] != dbs[0] {
+			t.Fatalf("concurrent Acquires opened the database more than once")
+		}
+	}
+	if s := m.Stats(); s.Open != 1 {
+		t.Errorf("expected 1 open database, got %+v", s)
+	}
+	for i := 0; i < n; i++ {
+		m.Release("shared")
+	}
+
+	// Evicting "shared" for "other" and taking it back must wait for the
+	// evicted handle to release its LOCK file.
+	for _, name := range []string{"other", "shared"} {
+		if _, err := m.Acquire(name); err != nil {
+			t.Fatalf("Acquire(%q) failed: %v", name, err)
+		}
+		m.Release(name)
+	}
+}
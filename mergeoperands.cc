@@ -0,0 +1,62 @@
+// DB::GetMergeOperands through RocksDB's C++ API, which the C API does not
+// expose.
+
+#include <stdlib.h>
+#include <string.h>
+
+#include <vector>
+
+#include "cinternal.h"
+
+struct gorocks_merge_operands_t {
+	std::vector<rocksdb::PinnableSlice> operands;
+};
+
+// gorocks_get_merge_operands returns the operands of key, or NULL if the key
+// does not exist or on error, in which case *errptr is set. cf may be NULL
+// for the default column family.
+extern "C" gorocks_merge_operands_t* gorocks_get_merge_operands(rocksdb_t* db,
+		const rocksdb_readoptions_t* options,
+		rocksdb_column_family_handle_t* cf, const char* key, size_t keylen,
+		char** errptr) {
+	rocksdb::ColumnFamilyHandle* handle =
+		cf != nullptr ? cf->rep : db->rep->DefaultColumnFamily();
+	gorocks_merge_operands_t* result = new gorocks_merge_operands_t;
+	rocksdb::GetMergeOperandsOptions opts;
+	opts.expected_max_number_of_operands = 16;
+	for (;;) {
+		result->operands.clear();
+		result->operands.resize(opts.expected_max_number_of_operands);
+		int n = 0;
+		rocksdb::Status s = db->rep->GetMergeOperands(options->rep, handle,
+			rocksdb::Slice(key, keylen), result->operands.data(), &opts, &n);
+		if (s.IsIncomplete() && n > opts.expected_max_number_of_operands) {
+			// Too many operands for the array; n is how many there are.
+			opts.expected_max_number_of_operands = n;
+			continue;
+		}
+		if (s.ok()) {
+			result->operands.resize(n);
+			return result;
+		}
+		delete result;
+		if (!s.IsNotFound()) {
+			*errptr = strdup(s.ToString().c_str());
+		}
+		return nullptr;
+	}
+}
+
+extern "C" int gorocks_merge_operands_count(const gorocks_merge_operands_t* m) {
+	return static_cast<int>(m->operands.size());
+}
+
+extern "C" const char* gorocks_merge_operands_get(const gorocks_merge_operands_t* m,
+		int i, size_t* len) {
+	*len = m->operands[i].size();
+	return m->operands[i].data();
+}
+
+extern "C" void gorocks_merge_operands_destroy(gorocks_merge_operands_t* m) {
+	delete m;
+}
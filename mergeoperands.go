@@ -0,0 +1,65 @@
+package gorocks
+
+/*
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+typedef struct gorocks_merge_operands_t gorocks_merge_operands_t;
+
+extern gorocks_merge_operands_t* gorocks_get_merge_operands(rocksdb_t* db, const rocksdb_readoptions_t* options, rocksdb_column_family_handle_t* cf, const char* key, size_t keylen, char** errptr);
+extern int gorocks_merge_operands_count(const gorocks_merge_operands_t* m);
+extern const char* gorocks_merge_operands_get(const gorocks_merge_operands_t* m, int i, size_t* len);
+extern void gorocks_merge_operands_destroy(gorocks_merge_operands_t* m);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// GetMergeOperands returns the operands merged into key, oldest first,
+// without running the merge operator on them. If the key has a value
+// written by Put underneath its merges, that value is the first operand;
+// operands older than the latest Delete of the key are not returned.
+//
+// For read-heavy workloads where a key accumulates many merges, resolving
+// the operands in the application is cheaper than having every Get merge
+// them. A missing key yields nil operands and no error.
+func (db *DB) GetMergeOperands(ro *ReadOptions, key []byte) ([][]byte, error) {
+	return db.getMergeOperands(ro, nil, key)
+}
+
+// GetMergeOperandsCF is like GetMergeOperands, but reads the key from the
+// given column family.
+func (db *DB) GetMergeOperandsCF(ro *ReadOptions, cf *ColumnFamilyHandle, key []byte) ([][]byte, error) {
+	return db.getMergeOperands(ro, cf.Handle, key)
+}
+
+func (db *DB) getMergeOperands(ro *ReadOptions, cf *C.rocksdb_column_family_handle_t, key []byte) ([][]byte, error) {
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	ct := cgoStart()
+	m := C.gorocks_get_merge_operands(db.Ldb, ro.Opt, cf, k, C.size_t(len(key)), &errStr)
+	cgoDone(CgoGet, ct)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	if m == nil {
+		return nil, nil
+	}
+	defer C.gorocks_merge_operands_destroy(m)
+	operands := make([][]byte, C.gorocks_merge_operands_count(m))
+	for i := range operands {
+		var n C.size_t
+		p := C.gorocks_merge_operands_get(m, C.int(i), &n)
+		operands[i] = C.GoBytes(unsafe.Pointer(p), C.int(n))
+	}
+	return operands, nil
+}
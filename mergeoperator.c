@@ -0,0 +1,127 @@
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+#include "_cgo_export.h"
+
+/* Merge operators implemented in Go. The state is a cgo.Handle of the
+   goMergeOperator; the callbacks forward to the exported Go functions in
+   mergeoperator.go. */
+
+static void gorocks_mergeoperator_destructor(void* state) {
+	gorocksMergeOperatorDestroy((uintptr_t)state);
+}
+
+static char* gorocks_mergeoperator_full_merge(void* state,
+		const char* key, size_t key_length,
+		const char* existing_value, size_t existing_value_length,
+		const char* const* operands_list, const size_t* operands_list_length,
+		int num_operands, unsigned char* success, size_t* new_value_length) {
+	return gorocksMergeOperatorFullMerge((uintptr_t)state,
+		(char*)key, key_length,
+		(char*)existing_value, existing_value_length,
+		(char**)operands_list, (size_t*)operands_list_length,
+		num_operands, success, new_value_length);
+}
+
+static char* gorocks_mergeoperator_partial_merge(void* state,
+		const char* key, size_t key_length,
+		const char* const* operands_list, const size_t* operands_list_length,
+		int num_operands, unsigned char* success, size_t* new_value_length) {
+	return gorocksMergeOperatorPartialMerge((uintptr_t)state,
+		(char*)key, key_length,
+		(char**)operands_list, (size_t*)operands_list_length,
+		num_operands, success, new_value_length);
+}
+
+static void gorocks_mergeoperator_delete_value(void* state, const char* value, size_t value_length) {
+	free((void*)value);
+}
+
+static const char* gorocks_mergeoperator_name(void* state) {
+	return gorocksMergeOperatorName((uintptr_t)state);
+}
+
+rocksdb_mergeoperator_t* gorocks_mergeoperator_create(uintptr_t state) {
+	return rocksdb_mergeoperator_create((void*)state,
+		gorocks_mergeoperator_destructor,
+		gorocks_mergeoperator_full_merge,
+		gorocks_mergeoperator_partial_merge,
+		gorocks_mergeoperator_delete_value,
+		gorocks_mergeoperator_name);
+}
+
+/* The uint64 add operator, implemented in C to avoid calling into Go for
+   every merge. Values and operands are 8-byte little-endian integers. */
+
+static int gorocks_uint64_decode(const char* p, size_t n, uint64_t* v) {
+	if (n != 8) {
+		return 0;
+	}
+	*v = 0;
+	for (int i = 7; i >= 0; i--) {
+		*v = (*v << 8) | (unsigned char)p[i];
+	}
+	return 1;
+}
+
+static char* gorocks_uint64_encode(uint64_t v, size_t* n) {
+	char* p = malloc(8);
+	for (int i = 0; i < 8; i++) {
+		p[i] = (char)(v >> (8 * i));
+	}
+	*n = 8;
+	return p;
+}
+
+static char* gorocks_uint64add_sum(uint64_t sum,
+		const char* const* operands_list, const size_t* operands_list_length,
+		int num_operands, unsigned char* success, size_t* new_value_length) {
+	for (int i = 0; i < num_operands; i++) {
+		uint64_t v;
+		if (!gorocks_uint64_decode(operands_list[i], operands_list_length[i], &v)) {
+			*success = 0;
+			return NULL;
+		}
+		sum += v;
+	}
+	*success = 1;
+	return gorocks_uint64_encode(sum, new_value_length);
+}
+
+static char* gorocks_uint64add_full_merge(void* state,
+		const char* key, size_t key_length,
+		const char* existing_value, size_t existing_value_length,
+		const char* const* operands_list, const size_t* operands_list_length,
+		int num_operands, unsigned char* success, size_t* new_value_length) {
+	uint64_t sum = 0;
+	if (existing_value != NULL && !gorocks_uint64_decode(existing_value, existing_value_length, &sum)) {
+		*success = 0;
+		return NULL;
+	}
+	return gorocks_uint64add_sum(sum, operands_list, operands_list_length,
+		num_operands, success, new_value_length);
+}
+
+static char* gorocks_uint64add_partial_merge(void* state,
+		const char* key, size_t key_length,
+		const char* const* operands_list, const size_t* operands_list_length,
+		int num_operands, unsigned char* success, size_t* new_value_length) {
+	return gorocks_uint64add_sum(0, operands_list, operands_list_length,
+		num_operands, success, new_value_length);
+}
+
+static void gorocks_uint64add_destructor(void* state) {
+}
+
+static const char* gorocks_uint64add_name(void* state) {
+	return "gorocks.Uint64AddOperator";
+}
+
+rocksdb_mergeoperator_t* gorocks_mergeoperator_create_uint64add(void) {
+	return rocksdb_mergeoperator_create(NULL,
+		gorocks_uint64add_destructor,
+		gorocks_uint64add_full_merge,
+		gorocks_uint64add_partial_merge,
+		gorocks_mergeoperator_delete_value,
+		gorocks_uint64add_name);
+}
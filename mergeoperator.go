@@ -0,0 +1,222 @@
+package gorocks
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern rocksdb_mergeoperator_t* gorocks_mergeoperator_create(uintptr_t state);
+extern rocksdb_mergeoperator_t* gorocks_mergeoperator_create_uint64add(void);
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// MergeOperator combines merge operands written with DB.Merge into values,
+// which lets counters, appends and similar read-modify-write updates be
+// done with a single blind write instead of a Get and a Put racing with
+// other writers. It is installed with Options.SetMergeOperator.
+//
+// The methods are called from RocksDB's threads, during reads, flushes and
+// compactions, and must be safe for concurrent use. The slices passed to
+// them are only valid for the duration of the call. A panic in a method
+// crashes the program.
+type MergeOperator interface {
+	// Name identifies the operator. It is recorded in the database, and a
+	// database must always be opened with an operator of the same name.
+	Name() string
+
+	// FullMerge applies the operands, oldest first, to the existing value
+	// of key, which is nil if the key has no value, and returns the new
+	// value. It returns false if the operands are invalid, which makes the
+	// read or compaction that triggered the merge fail with an error.
+	FullMerge(key, existingValue []byte, operands [][]byte) ([]byte, bool)
+
+	// PartialMerge combines several operands, oldest first, into a single
+	// operand with the same effect, if possible. It returns false if the
+	// operands cannot be combined without the existing value, in which
+	// case RocksDB keeps them as they are.
+	PartialMerge(key []byte, operands [][]byte) ([]byte, bool)
+}
+
+// goMergeOperator is the state of a MergeOperator handed to RocksDB.
+type goMergeOperator struct {
+	mo   MergeOperator
+	name *C.char
+}
+
+// SetMergeOperator sets the MergeOperator used to combine the values
+// written with DB.Merge. RocksDB holds on to the operator until the Options
+// and every database opened with them have been closed.
+func (o *Options) SetMergeOperator(mo MergeOperator) {
+	if _, ok := mo.(uint64AddOperator); ok {
+		C.rocksdb_options_set_merge_operator(o.Opt, C.gorocks_mergeoperator_create_uint64add())
+		return
+	}
+	state := &goMergeOperator{mo: mo, name: C.CString(mo.Name())}
+	cmo := C.gorocks_mergeoperator_create(C.uintptr_t(cgo.NewHandle(state)))
+	C.rocksdb_options_set_merge_operator(o.Opt, cmo)
+}
+
+// NewUint64AddOperator returns a MergeOperator for counters: values and
+// merge operands are 8-byte little-endian unsigned integers, and merging
+// adds the operands to the value, wrapping around on overflow. A key
+// without a value counts as 0. Merges with values or operands of any other
+// size fail.
+//
+// When installed with Options.SetMergeOperator, the operator runs in C
+// without calling back into Go. It is named gorocks.Uint64AddOperator:
+// RocksDB's own UInt64AddOperator treats malformed operands differently,
+// so the two must not be mistaken for each other in OPTIONS files or
+// schema manifests.
+func NewUint64AddOperator() MergeOperator {
+	return uint64AddOperator{}
+}
+
+// Uint64Operand encodes delta as a merge operand for the operator returned
+// by NewUint64AddOperator.
+func Uint64Operand(delta uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, delta)
+}
+
+// uint64AddOperatorName must match gorocks_uint64add_name in
+// mergeoperator.c.
+const uint64AddOperatorName = "gorocks.Uint64AddOperator"
+
+type uint64AddOperator struct{}
+
+func (uint64AddOperator) Name() string { return uint64AddOperatorName }
+
+func (op uint64AddOperator) FullMerge(key, existingValue []byte, operands [][]byte) ([]byte, bool) {
+	var sum uint64
+	if existingValue != nil {
+		if len(existingValue) != 8 {
+			return nil, false
+		}
+		sum = binary.LittleEndian.Uint64(existingValue)
+	}
+	return op.add(sum, operands)
+}
+
+func (op uint64AddOperator) PartialMerge(key []byte, operands [][]byte) ([]byte, bool) {
+	return op.add(0, operands)
+}
+
+func (uint64AddOperator) add(sum uint64, operands [][]byte) ([]byte, bool) {
+	for _, o := range operands {
+		if len(o) != 8 {
+			return nil, false
+		}
+		sum += binary.LittleEndian.Uint64(o)
+	}
+	return Uint64Operand(sum), true
+}
+
+// NewStringAppendOperator returns a MergeOperator for list or log style
+// values: merging appends the operand to the value, separated by delimiter,
+// so an element can be added with a single DB.Merge instead of a Get and a
+// Put. A key without a value becomes the operand alone. It is the Go
+// counterpart of RocksDB's StringAppendOperator.
+func NewStringAppendOperator(delimiter string) MergeOperator {
+	return stringAppendOperator{delimiter: []byte(delimiter)}
+}
+
+type stringAppendOperator struct {
+	delimiter []byte
+}
+
+func (stringAppendOperator) Name() string { return "StringAppendOperator" }
+
+func (op stringAppendOperator) FullMerge(key, existingValue []byte, operands [][]byte) ([]byte, bool) {
+	if existingValue == nil {
+		return op.PartialMerge(key, operands)
+	}
+	return op.join(existingValue, operands), true
+}
+
+func (op stringAppendOperator) PartialMerge(key []byte, operands [][]byte) ([]byte, bool) {
+	if len(operands) == 0 {
+		return nil, false
+	}
+	return op.join(operands[0], operands[1:]), true
+}
+
+func (op stringAppendOperator) join(first []byte, rest [][]byte) []byte {
+	n := len(first)
+	for _, o := range rest {
+		n += len(op.delimiter) + len(o)
+	}
+	b := make([]byte, 0, n)
+	b = append(b, first...)
+	for _, o := range rest {
+		b = append(b, op.delimiter...)
+		b = append(b, o...)
+	}
+	return b
+}
+
+func mergeOperatorState(state C.uintptr_t) *goMergeOperator {
+	return cgo.Handle(state).Value().(*goMergeOperator)
+}
+
+// cOperands returns the operands of a merge as slices of the C memory
+// holding them.
+func cOperands(list **C.char, lengths *C.size_t, n C.int) [][]byte {
+	ptrs := unsafe.Slice(list, int(n))
+	lens := unsafe.Slice(lengths, int(n))
+	operands := make([][]byte, n)
+	for i := range operands {
+		operands[i] = cBytes(ptrs[i], lens[i])
+	}
+	return operands
+}
+
+// cBytes returns a slice of the C memory at p, or nil if p is nil.
+func cBytes(p *C.char, n C.size_t) []byte {
+	if p == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), int(n))
+}
+
+// mergeResult hands a merge result to RocksDB, which frees it with the
+// operator's delete_value callback.
+func mergeResult(value []byte, ok bool, success *C.uchar, newLen *C.size_t) *C.char {
+	if !ok {
+		*success = 0
+		return nil
+	}
+	*success = 1
+	*newLen = C.size_t(len(value))
+	return (*C.char)(C.CBytes(value))
+}
+
+//export gorocksMergeOperatorFullMerge
+func gorocksMergeOperatorFullMerge(state C.uintptr_t, key *C.char, keyLen C.size_t, existing *C.char, existingLen C.size_t, operands **C.char, operandLens *C.size_t, n C.int, success *C.uchar, newLen *C.size_t) *C.char {
+	s := mergeOperatorState(state)
+	value, ok := s.mo.FullMerge(cBytes(key, keyLen), cBytes(existing, existingLen), cOperands(operands, operandLens, n))
+	return mergeResult(value, ok, success, newLen)
+}
+
+//export gorocksMergeOperatorPartialMerge
+func gorocksMergeOperatorPartialMerge(state C.uintptr_t, key *C.char, keyLen C.size_t, operands **C.char, operandLens *C.size_t, n C.int, success *C.uchar, newLen *C.size_t) *C.char {
+	s := mergeOperatorState(state)
+	value, ok := s.mo.PartialMerge(cBytes(key, keyLen), cOperands(operands, operandLens, n))
+	return mergeResult(value, ok, success, newLen)
+}
+
+//export gorocksMergeOperatorName
+func gorocksMergeOperatorName(state C.uintptr_t) *C.char {
+	return mergeOperatorState(state).name
+}
+
+//export gorocksMergeOperatorDestroy
+func gorocksMergeOperatorDestroy(state C.uintptr_t) {
+	h := cgo.Handle(state)
+	C.free(unsafe.Pointer(h.Value().(*goMergeOperator).name))
+	h.Delete()
+}
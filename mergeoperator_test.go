@@ -0,0 +1,226 @@
+package gorocks
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// appendOperator joins operands to the existing value with commas.
+type appendOperator struct{}
+
+func (appendOperator) Name() string { return "test.append" }
+
+func (appendOperator) FullMerge(key, existing []byte, operands [][]byte) ([]byte, bool) {
+	parts := operands
+	if existing != nil {
+		parts = append([][]byte{existing}, operands...)
+	}
+	return bytes.Join(parts, []byte(",")), true
+}
+
+func (appendOperator) PartialMerge(key []byte, operands [][]byte) ([]byte, bool) {
+	return bytes.Join(operands, []byte(",")), true
+}
+
+func TestMergeOperator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetMergeOperator(appendOperator{})
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("list"), []byte("a"))
+	for _, v := range []string{"b", "c"} {
+		if err := db.Merge(wo, []byte("list"), []byte(v)); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+	}
+	db.Merge(wo, []byte("new"), []byte("x"))
+	CheckGet(t, "merged", db, ro, []byte("list"), []byte("a,b,c"))
+	CheckGet(t, "no existing value", db, ro, []byte("new"), []byte("x"))
+
+	db.CompactRange(Range{})
+	CheckGet(t, "after compaction", db, ro, []byte("list"), []byte("a,b,c"))
+}
+
+func TestUint64AddOperator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetMergeOperator(NewUint64AddOperator())
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := db.Merge(wo, []byte("counter"), Uint64Operand(3)); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+	}
+	CheckGet(t, "counter", db, ro, []byte("counter"), Uint64Operand(30))
+	db.Merge(wo, []byte("bad"), []byte("x"))
+	if _, err := db.Get(ro, []byte("bad")); err == nil {
+		t.Errorf("merging a malformed operand should fail")
+	}
+
+	op := NewUint64AddOperator()
+	v, ok := op.FullMerge(nil, Uint64Operand(1), [][]byte{Uint64Operand(2)})
+	if !ok || !bytes.Equal(v, Uint64Operand(3)) {
+		t.Errorf("FullMerge = %v, %v", v, ok)
+	}
+
+	// The C operator must record the same name as the Go one, which is
+	// not RocksDB's.
+	contents, err := db.OptionsFile()
+	if err != nil {
+		t.Fatalf("OptionsFile failed: %v", err)
+	}
+	recorded := parseOptionsFile(contents)[`CFOptions "default"`+"\tmerge_operator"]
+	if !strings.Contains(recorded, op.Name()) || op.Name() == "UInt64AddOperator" {
+		t.Errorf("merge_operator recorded as %q, operator named %q", recorded, op.Name())
+	}
+}
+
+func TestMergeCF(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	counters := NewOptions()
+	counters.SetMergeOperator(NewUint64AddOperator())
+	defer counters.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	cf, err := db.CreateColumnFamily(counters, "counters")
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	defer cf.Close()
+
+	if err := db.MergeCF(wo, cf, []byte("hits"), Uint64Operand(2)); err != nil {
+		t.Fatalf("MergeCF failed: %v", err)
+	}
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.MergeCF(cf, []byte("hits"), Uint64Operand(3))
+	if err := db.Write(wo, wb); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if v, err := db.GetCF(ro, cf, []byte("hits")); err != nil || !bytes.Equal(v, Uint64Operand(5)) {
+		t.Errorf("GetCF = %v, %v; want 5", v, err)
+	}
+	if err := db.Merge(wo, []byte("hits"), Uint64Operand(1)); err == nil {
+		t.Errorf("Merge into a column family without a merge operator should fail")
+	}
+}
+
+func TestStringAppendOperator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetMergeOperator(NewStringAppendOperator("\n"))
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	for _, line := range []string{"first", "second", "third"} {
+		if err := db.Merge(wo, []byte("log"), []byte(line)); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+	}
+	CheckGet(t, "appended", db, ro, []byte("log"), []byte("first\nsecond\nthird"))
+	db.CompactRange(Range{})
+	db.Merge(wo, []byte("log"), []byte("fourth"))
+	CheckGet(t, "appended after compaction", db, ro, []byte("log"), []byte("first\nsecond\nthird\nfourth"))
+}
+
+func TestGetMergeOperands(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetMergeOperator(appendOperator{})
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	check := func(key string, want ...string) {
+		t.Helper()
+		got, err := db.GetMergeOperands(ro, []byte(key))
+		if err != nil {
+			t.Fatalf("GetMergeOperands(%q) failed: %v", key, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("GetMergeOperands(%q) = %q, want %q", key, got, want)
+		}
+		for i := range want {
+			if string(got[i]) != want[i] {
+				t.Fatalf("GetMergeOperands(%q) = %q, want %q", key, got, want)
+			}
+		}
+	}
+
+	db.Put(wo, []byte("list"), []byte("a"))
+	db.Merge(wo, []byte("list"), []byte("b"))
+	db.Merge(wo, []byte("list"), []byte("c"))
+	check("list", "a", "b", "c")
+	check("missing")
+
+	db.Delete(wo, []byte("list"))
+	db.Merge(wo, []byte("list"), []byte("d"))
+	check("list", "d")
+
+	// More operands than the first attempt makes room for.
+	var many []string
+	for i := 0; i < 40; i++ {
+		v := strconv.Itoa(i)
+		db.Merge(wo, []byte("many"), []byte(v))
+		many = append(many, v)
+	}
+	check("many", many...)
+	db.CompactRange(Range{})
+	check("list", "d")
+}
@@ -0,0 +1,203 @@
+package gorocks
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// migrationsPrefix starts the reserved keys under which RunMigrations
+// records its state. The leading zero byte sorts them before most
+// application keys.
+const migrationsPrefix = "\x00gorocks.migrations\x00"
+
+var (
+	migrationVersionKey = []byte(migrationsPrefix + "version")
+	migrationCursorKey  = []byte(migrationsPrefix + "cursor")
+)
+
+// DefaultRewriteBatchKeys is the number of keys a Rewrite reads between
+// writes when its BatchKeys is not set.
+const DefaultRewriteBatchKeys = 1000
+
+// Migration is one step of a database's schema or data history, applied by
+// RunMigrations. Either of Rewrite and Apply may be nil.
+type Migration struct {
+	// Version orders the migrations. It must be greater than zero and than
+	// the versions of the migrations before it.
+	Version uint64
+	Name    string
+
+	// Rewrite, if set, goes over a range of keys in bounded batches first.
+	Rewrite *Rewrite
+
+	// Apply adds the writes of the migration to wb, which is written
+	// together with the record that the migration is done, so they take
+	// effect exactly once.
+	Apply func(ctx context.Context, db *DB, wb *WriteBatch) error
+}
+
+// Rewrite is a long-running part of a Migration that reads a range of keys
+// from a snapshot and writes changes in batches. The position reached is
+// written with each batch, so that a Rewrite interrupted by an error or a
+// crash resumes after the last key of the last batch written, from a new
+// snapshot, when RunMigrations runs again. Func therefore sees each key at
+// least once: the keys of a batch that was not written are passed to it
+// again on resumption, so it must be idempotent. It may also see keys
+// written after the first run started.
+//
+// Resuming assumes the default bytewise key order.
+type Rewrite struct {
+	Range Range
+
+	// BatchKeys is the number of keys read between writes. It defaults to
+	// DefaultRewriteBatchKeys.
+	BatchKeys int
+
+	// Func adds the writes for one key to wb, if any. It may be called
+	// more than once for a key, as described above. The slices are only
+	// valid for the duration of the call.
+	Func func(key, value []byte, wb *WriteBatch) error
+}
+
+// MigrationVersion returns the version of the last migration applied to db
+// by RunMigrations, or 0 if there was none.
+func MigrationVersion(db *DB) (uint64, error) {
+	ro := NewReadOptions()
+	defer ro.Close()
+	v, err := db.Get(ro, migrationVersionKey)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, fmt.Errorf("gorocks: invalid migration version %q", v)
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// RunMigrations applies the migrations with a version greater than that of
+// the last one applied to db, in order, recording each as it completes.
+// It stops at the first error, leaving the failed migration to be retried,
+// or resumed if it has a Rewrite, by the next call. The reserved keys used
+// for the bookkeeping start with "\x00gorocks.migrations\x00" and are
+// skipped by Rewrites.
+//
+// Calls for the same DB are serialized, so migrations can be run from
+// every place that opens the database.
+func RunMigrations(ctx context.Context, db *DB, migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Version == 0 || i > 0 && m.Version <= migrations[i-1].Version {
+			return fmt.Errorf("gorocks: migration %q has version %d, which is not increasing", m.Name, m.Version)
+		}
+	}
+	db.migrateMu.Lock()
+	defer db.migrateMu.Unlock()
+
+	current, err := MigrationVersion(db)
+	if err != nil {
+		return err
+	}
+	wo := NewWriteOptions()
+	defer wo.Close()
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := runMigration(ctx, db, wo, m); err != nil {
+			return fmt.Errorf("gorocks: migration %d %q: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func runMigration(ctx context.Context, db *DB, wo *WriteOptions, m Migration) error {
+	wb := NewWriteBatch()
+	defer wb.Close()
+	if m.Rewrite != nil {
+		if err := runRewrite(ctx, db, wo, m.Version, m.Rewrite, wb); err != nil {
+			return err
+		}
+	}
+	if m.Apply != nil {
+		if err := m.Apply(ctx, db, wb); err != nil {
+			return err
+		}
+	}
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], m.Version)
+	wb.Put(migrationVersionKey, v[:])
+	wb.Delete(migrationCursorKey)
+	return db.Write(wo, wb)
+}
+
+// runRewrite runs rw, writing all but its last batch, which is left in wb
+// to be written with the rest of the migration.
+func runRewrite(ctx context.Context, db *DB, wo *WriteOptions, version uint64, rw *Rewrite, wb *WriteBatch) error {
+	batchKeys := rw.BatchKeys
+	if batchKeys <= 0 {
+		batchKeys = DefaultRewriteBatchKeys
+	}
+	r := rw.Range
+	cursor, err := migrationCursor(db, version)
+	if err != nil {
+		return err
+	}
+	if cursor != nil {
+		r.Start = append(cursor, 0)
+	}
+
+	snap := db.NewSnapshot()
+	defer db.ReleaseSnapshot(snap)
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetSnapshot(snap)
+	ro.SetFillCache(false)
+	s := db.NewScanner(ro, r, ScanOptions{})
+	defer s.Close()
+
+	n := 0
+	for s.Next() {
+		k := s.Key()
+		if len(k) >= len(migrationsPrefix) && string(k[:len(migrationsPrefix)]) == migrationsPrefix {
+			continue
+		}
+		if err := rw.Func(k, s.Value(), wb); err != nil {
+			return err
+		}
+		if n++; n < batchKeys {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		wb.Put(migrationCursorKey, encodeMigrationCursor(version, k))
+		if err := db.Write(wo, wb); err != nil {
+			return err
+		}
+		wb.Clear()
+		n = 0
+	}
+	return s.Err()
+}
+
+func encodeMigrationCursor(version uint64, key []byte) []byte {
+	c := make([]byte, 8+len(key))
+	binary.BigEndian.PutUint64(c, version)
+	copy(c[8:], key)
+	return c
+}
+
+// migrationCursor returns the last key the Rewrite of the given migration
+// version wrote a batch for, or nil if it has not written any.
+func migrationCursor(db *DB, version uint64) ([]byte, error) {
+	ro := NewReadOptions()
+	defer ro.Close()
+	c, err := db.Get(ro, migrationCursorKey)
+	if err != nil || len(c) < 8 || binary.BigEndian.Uint64(c) != version {
+		return nil, err
+	}
+	return c[8:], nil
+}
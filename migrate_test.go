@@ -0,0 +1,103 @@
+package gorocks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRunMigrations(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 5; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("user/%d", i)), []byte("name"))
+	}
+
+	seen := map[string]int{}
+	failOn := "user/3"
+	errFail := errors.New("injected failure")
+	migrations := []Migration{
+		{
+			Version: 1,
+			Name:    "add schema key",
+			Apply: func(ctx context.Context, db *DB, wb *WriteBatch) error {
+				wb.Put([]byte("schema"), []byte("1"))
+				return nil
+			},
+		},
+		{
+			Version: 2,
+			Name:    "upper-case names",
+			Rewrite: &Rewrite{
+				Range:     Range{[]byte("user/"), []byte("user0")},
+				BatchKeys: 2,
+				Func: func(key, value []byte, wb *WriteBatch) error {
+					if string(key) == failOn {
+						return errFail
+					}
+					seen[string(key)]++
+					wb.Put(key, bytes.ToUpper(value))
+					return nil
+				},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	if err := RunMigrations(ctx, db, migrations); !errors.Is(err, errFail) {
+		t.Fatalf("RunMigrations returned %v, want the injected failure", err)
+	}
+	if v, err := MigrationVersion(db); err != nil || v != 1 {
+		t.Errorf("MigrationVersion after failure = %d, %v; want 1", v, err)
+	}
+	CheckGet(t, "first batch", db, ro, []byte("user/1"), []byte("NAME"))
+	CheckGet(t, "unwritten batch", db, ro, []byte("user/2"), []byte("name"))
+
+	failOn = ""
+	if err := RunMigrations(ctx, db, migrations); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+	if v, err := MigrationVersion(db); err != nil || v != 2 {
+		t.Errorf("MigrationVersion = %d, %v; want 2", v, err)
+	}
+	for i := 0; i < 5; i++ {
+		k := fmt.Sprintf("user/%d", i)
+		CheckGet(t, "rewritten", db, ro, []byte(k), []byte("NAME"))
+		// user/2 was passed to Func in the batch that failed, and again
+		// when the migration resumed after user/1.
+		want := 1
+		if i == 2 {
+			want = 2
+		}
+		if seen[k] != want {
+			t.Errorf("%s rewritten %d times, want %d", k, seen[k], want)
+		}
+	}
+	CheckGet(t, "applied", db, ro, []byte("schema"), []byte("1"))
+
+	if err := RunMigrations(ctx, db, migrations); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+	if seen["user/0"] != 1 {
+		t.Errorf("completed migration ran again")
+	}
+
+	bad := []Migration{{Version: 3}, {Version: 3}}
+	if err := RunMigrations(ctx, db, bad); err == nil {
+		t.Errorf("RunMigrations accepted repeated versions")
+	}
+}
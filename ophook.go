@@ -0,0 +1,124 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OpType identifies the kind of operation reported to an OpHook.
+type OpType int
+
+const (
+	OpGet OpType = iota
+	OpPut
+	OpDelete
+	OpWrite
+	OpIteratorSeek
+	OpIteratorNext
+	OpIteratorPrev
+	OpMerge
+	OpDeleteRange
+	OpNewSnapshot
+	OpReleaseSnapshot
+)
+
+var opTypeNames = [...]string{
+	OpGet:             "get",
+	OpPut:             "put",
+	OpDelete:          "delete",
+	OpWrite:           "write",
+	OpIteratorSeek:    "iterator_seek",
+	OpIteratorNext:    "iterator_next",
+	OpIteratorPrev:    "iterator_prev",
+	OpMerge:           "merge",
+	OpDeleteRange:     "delete_range",
+	OpNewSnapshot:     "new_snapshot",
+	OpReleaseSnapshot: "release_snapshot",
+}
+
+func (t OpType) String() string {
+	if t < 0 || int(t) >= len(opTypeNames) {
+		return "unknown"
+	}
+	return opTypeNames[t]
+}
+
+// OpInfo describes a completed operation.
+type OpInfo struct {
+	Op OpType
+	// KeySize and ValueSize are the sizes of the key and value written or
+	// read. For iterator steps they are those of the entry the Iterator
+	// moved to, and zero if it became invalid. For Write, KeySize is zero
+	// and ValueSize is the size of the WriteBatch's data. For DeleteRange,
+	// they are the sizes of the start and end of the range. For snapshots
+	// both are zero.
+	KeySize, ValueSize int
+	// Duration is how long the operation took, except for
+	// ReleaseSnapshot, where it is how long the Snapshot was held.
+	Duration time.Duration
+	Err      error
+}
+
+// OpHook is called after every operation made through a DB handle it is
+// installed on, from the goroutine that made it.
+type OpHook func(OpInfo)
+
+type opHookState struct {
+	hook atomic.Value // of opHookHolder
+}
+
+type opHookHolder struct {
+	hook OpHook
+}
+
+func (s *opHookState) load() OpHook {
+	h, _ := s.hook.Load().(opHookHolder)
+	return h.hook
+}
+
+// SetOpHook installs hook to be called after every Get, Put, Delete and
+// Write, their column family variants, every NewSnapshot and
+// ReleaseSnapshot, and every Seek, Next and Prev of Iterators created from
+// this DB handle, or removes the hook if nil is passed. It is the place to plug in tracing spans or latency histograms
+// without wrapping every call.
+//
+// The hook runs synchronously and adds its own cost to every operation.
+// Without a hook installed, the overhead is a single atomic load.
+func (db *DB) SetOpHook(hook OpHook) {
+	db.opHook.hook.Store(opHookHolder{hook})
+}
+
+// done reports an operation that started at start. It is meant to be
+// deferred, with value and err pointing at the operation's results.
+func (h OpHook) done(op OpType, keySize int, value *[]byte, err *error, start time.Time) {
+	info := OpInfo{Op: op, KeySize: keySize, Duration: time.Since(start), Err: *err}
+	if value != nil {
+		info.ValueSize = len(*value)
+	}
+	h(info)
+}
+
+// iteratorStep reports an Iterator step that started at start.
+func (it *Iterator) iteratorStep(h OpHook, op OpType, start time.Time) {
+	info := OpInfo{Op: op}
+	if ucharToBool(C.rocksdb_iter_valid(it.Iter)) {
+		var klen, vlen C.size_t
+		C.rocksdb_iter_key(it.Iter, &klen)
+		C.rocksdb_iter_value(it.Iter, &vlen)
+		info.KeySize, info.ValueSize = int(klen), int(vlen)
+	} else {
+		info.Err = it.GetError()
+	}
+	info.Duration = time.Since(start)
+	h(info)
+}
+
+func (it *Iterator) opHook() OpHook {
+	if it.db == nil {
+		return nil
+	}
+	return it.db.opHook.load()
+}
@@ -1,10 +1,12 @@
 package gorocks
 
+// #include <stdlib.h>
 // #include "rocksdb/c.h"
 import "C"
 
 import (
 	"time"
+	"unsafe"
 )
 
 // CompressionOpt is a value for Options.SetCompression.
@@ -23,16 +25,45 @@ const (
 	UniversalStyleCompaction = CompactionStyle(1)
 )
 
+// ReadTier selects, with ReadOptions.SetReadTier, where reads may look for
+// data.
+type ReadTier int
+
+const (
+	// ReadAllTier reads from memtables, the block cache and disk. It is the
+	// default.
+	ReadAllTier = ReadTier(0)
+	// BlockCacheTier reads only from memtables and the block cache, so a
+	// read never waits on disk.
+	BlockCacheTier = ReadTier(1)
+	// PersistedTier reads only data that has been persisted, skipping
+	// memtables when the write-ahead log is disabled.
+	PersistedTier = ReadTier(2)
+	// MemtableTier reads only from memtables. Only Iterators support it.
+	MemtableTier = ReadTier(3)
+)
+
 // Options represent all of the available options when opening a database with
 // Open. Options should be created with NewOptions.
 //
 // It is usually with to call SetCache with a cache object. Otherwise, all
 // data will be read off disk.
 //
+// An Options may be used as a template for opening many databases. Open does
+// not retain the Options, and Clone produces an independent copy that can be
+// modified without affecting the original.
+//
 // To prevent memory leaks, Close must be called on an Options when the
-// program no longer needs it.
+// program no longer needs it. Close also frees any sub-objects the Options
+// created on its own behalf, such as the universal compaction options set up
+// by SetCompactionStyle.
 type Options struct {
 	Opt *C.rocksdb_options_t
+
+	// uco is owned by the Options and freed by Close.
+	uco *C.rocksdb_universal_compaction_options_t
+	// cmp is the Comparator set with SetComparator, if any.
+	cmp Comparator
 }
 
 // ReadOptions represent all of the available options when reading from a
@@ -42,6 +73,14 @@ type Options struct {
 // program no longer needs it.
 type ReadOptions struct {
 	Opt *C.rocksdb_readoptions_t
+
+	snap         *Snapshot
+	lower, upper []byte
+	pinData      bool
+
+	// lowerC and upperC are C copies of lower and upper, which RocksDB
+	// points at rather than copying. They are freed by Close.
+	lowerC, upperC unsafe.Pointer
 }
 
 // WriteOptions represent all of the available options when writeing from a
@@ -56,13 +95,13 @@ type WriteOptions struct {
 // NewOptions allocates a new Options object.
 func NewOptions() *Options {
 	opt := C.rocksdb_options_create()
-	return &Options{opt}
+	return &Options{Opt: opt}
 }
 
 // NewReadOptions allocates a new ReadOptions object.
 func NewReadOptions() *ReadOptions {
 	opt := C.rocksdb_readoptions_create()
-	return &ReadOptions{opt}
+	return &ReadOptions{Opt: opt}
 }
 
 // NewWriteOptions allocates a new WriteOptions object.
@@ -71,21 +110,23 @@ func NewWriteOptions() *WriteOptions {
 	return &WriteOptions{opt}
 }
 
-// Close deallocates the Options, freeing its underlying C struct.
-func (o *Options) Close() {
-	C.rocksdb_options_destroy(o.Opt)
+// Clone returns a deep copy of the Options. The copy shares objects that
+// were handed to the Options by the caller, such as a Cache or Env, but owns
+// everything else and must be closed separately.
+func (o *Options) Clone() *Options {
+	// rocksdb_options_create_copy copies the universal compaction options by
+	// value, so the clone does not need its own uco.
+	return &Options{Opt: C.rocksdb_options_create_copy(o.Opt), cmp: o.cmp}
 }
 
-// SetComparator sets the comparator to be used for all read and write
-// operations.
-//
-// The comparator that created a database must be the same one (technically,
-// one with the same name string) that is used to perform read and write
-// operations.
-//
-// The default comparator is usually sufficient.
-func (o *Options) SetComparator(cmp *C.rocksdb_comparator_t) {
-	C.rocksdb_options_set_comparator(o.Opt, cmp)
+// Close deallocates the Options, freeing its underlying C struct and any
+// sub-objects owned by it.
+func (o *Options) Close() {
+	C.rocksdb_options_destroy(o.Opt)
+	if o.uco != nil {
+		C.rocksdb_universal_compaction_options_destroy(o.uco)
+		o.uco = nil
+	}
 }
 
 // SetErrorIfExists, if passed true, will cause the opening of a database that
@@ -102,6 +143,32 @@ func (o *Options) SetCache(cache *Cache) {
 	C.rocksdb_options_set_cache(o.Opt, cache.Cache)
 }
 
+// SetBlockBasedTableFactory makes the database write SST files in the
+// block-based format configured by bo, in place of the table settings made
+// with SetCache, SetBlockSize, SetBlockRestartInterval and SetFilterPolicy.
+// The Options keep a copy of bo.
+func (o *Options) SetBlockBasedTableFactory(bo *BlockBasedTableOptions) {
+	C.rocksdb_options_set_block_based_table_factory(o.Opt, bo.Opt)
+}
+
+// SetWriteBufferManager makes the memtables of the database count against
+// the limit of the given WriteBufferManager, which may be shared between
+// many databases.
+func (o *Options) SetWriteBufferManager(m *WriteBufferManager) {
+	C.rocksdb_options_set_write_buffer_manager(o.Opt, m.Manager)
+}
+
+// SetRateLimiter throttles the background I/O of the database with rl.
+func (o *Options) SetRateLimiter(rl *RateLimiter) {
+	C.rocksdb_options_set_ratelimiter(o.Opt, rl.Limiter)
+}
+
+// SetSstFileManager makes m track the SST files of the database, enforcing
+// its space limit and deletion rate.
+func (o *Options) SetSstFileManager(m *SstFileManager) {
+	C.rocksdb_options_set_sst_file_manager(o.Opt, m.Manager)
+}
+
 // SetEnv sets the Env object for the new database handle.
 func (o *Options) SetEnv(env *Env) {
 	C.rocksdb_options_set_env(o.Opt, env.Env)
@@ -137,6 +204,29 @@ func (o *Options) SetParanoidChecks(pc bool) {
 	C.rocksdb_options_set_paranoid_checks(o.Opt, boolToUchar(pc))
 }
 
+// SetMemtableProtectionBytesPerKey makes RocksDB store a checksum of n
+// bytes, which must be 0, 1, 2, 4 or 8, with every entry in the memtables
+// and verify it when the entry is read or flushed. It defaults to 0, which
+// stores none.
+func (o *Options) SetMemtableProtectionBytesPerKey(n int) {
+	C.rocksdb_options_set_memtable_protection_bytes_per_key(o.Opt, C.uint32_t(n))
+}
+
+// SetParanoidReads configures the database for deployments that want
+// every read checked for corruption, at the cost of some CPU and memory.
+//
+// Reads already verify the checksum of every block they load from an SST
+// file, unless ReadOptions.SetVerifyChecksums(false) turns that off. This
+// adds 8-byte checksums to memtable entries, which block checksums do not
+// cover, so that recent writes corrupted in memory fail to read instead of
+// being returned, and turns on SetParanoidChecks. RocksDB keeps no separate
+// checksum per value, and its C API does not expose the per-key protection
+// of blocks held in the block cache, so neither is enabled.
+func (o *Options) SetParanoidReads() {
+	o.SetParanoidChecks(true)
+	o.SetMemtableProtectionBytesPerKey(8)
+}
+
 // SetMaxOpenFiles sets the number of files than can be used at once by the
 // database.
 //
@@ -180,6 +270,60 @@ func (o *Options) SetCreateIfMissing(b bool) {
 	C.rocksdb_options_set_create_if_missing(o.Opt, boolToUchar(b))
 }
 
+// SetCreateMissingColumnFamilies causes OpenColumnFamilies to create any of
+// the column families it is asked to open that do not already exist.
+func (o *Options) SetCreateMissingColumnFamilies(b bool) {
+	C.rocksdb_options_set_create_missing_column_families(o.Opt, boolToUchar(b))
+}
+
+// SetAllowIngestBehind reserves the bottommost level of the LSM tree for
+// files ingested with IngestExternalFileOptions.SetIngestBehind, so that cold
+// historical data can be loaded underneath the live data without being
+// compacted together with it. It must be set when the database is created,
+// and keeps compaction out of the bottommost level.
+func (o *Options) SetAllowIngestBehind(b bool) {
+	C.rocksdb_options_set_allow_ingest_behind(o.Opt, boolToUchar(b))
+}
+
+// SetPrefixExtractorFixed makes the first n bytes of each key its prefix,
+// for composite keys such as a fixed-size tenant or table id followed by
+// the rest of the key. With a filter policy set, filters are then built
+// over prefixes, so that a Seek within a prefix skips the files that hold
+// no key with it.
+//
+// Seeks then only reliably find keys with the same prefix as the sought
+// key: iterate with ReadOptions.SetPrefixSameAsStart, or use
+// ReadOptions.SetTotalOrderSeek for iterations that cross prefixes. Keys
+// shorter than n bytes have no prefix and are never skipped.
+//
+// It is short for SetPrefixExtractor(NewFixedPrefixTransform(n)).
+func (o *Options) SetPrefixExtractorFixed(n int) {
+	o.SetPrefixExtractor(NewFixedPrefixTransform(n))
+}
+
+// SetMemtablePrefixBloomSizeRatio gives each memtable a bloom filter over
+// key prefixes taking ratio times the write buffer size, so that point
+// lookups and prefix seeks skip memtables without their prefix. It needs a
+// prefix extractor, see SetPrefixExtractor, and is off at the default of 0.
+// RocksDB caps ratio at 0.25.
+func (o *Options) SetMemtablePrefixBloomSizeRatio(ratio float64) {
+	C.rocksdb_options_set_memtable_prefix_bloom_size_ratio(o.Opt, C.double(ratio))
+}
+
+// SetMemtableWholeKeyFiltering adds whole keys to the memtable bloom filter
+// set up with SetMemtablePrefixBloomSizeRatio, in addition to prefixes, so
+// that point lookups can skip memtables without the key. It works without a
+// prefix extractor.
+func (o *Options) SetMemtableWholeKeyFiltering(b bool) {
+	C.rocksdb_options_set_memtable_whole_key_filtering(o.Opt, boolToUchar(b))
+}
+
+// SetMemtableHugePageSize allocates the memtables, and their bloom filters,
+// from huge pages of the given size, if the system has them reserved.
+func (o *Options) SetMemtableHugePageSize(size int) {
+	C.rocksdb_options_set_memtable_huge_page_size(o.Opt, C.size_t(size))
+}
+
 // SetFilterPolicy causes Open to create a new database that will uses filter
 // created from the filter policy passed in.
 func (o *Options) SetFilterPolicy(fp *FilterPolicy) {
@@ -266,10 +410,25 @@ func (o *Options) EnableStatistics() {
 	C.rocksdb_options_enable_statistics(o.Opt)
 }
 
+// StatisticsString returns a dump of the statistics collected since
+// EnableStatistics was called, or an empty string if statistics are not
+// enabled.
+func (o *Options) StatisticsString() string {
+	cs := C.rocksdb_options_statistics_get_string(o.Opt)
+	if cs == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cs))
+	return C.GoString(cs)
+}
+
 func (o *Options) SetCompactionStyle(style CompactionStyle) {
 	C.rocksdb_options_set_compaction_style(o.Opt, C.int(style))
-	// TODO this will leak if Options is discarded
+	if o.uco != nil {
+		C.rocksdb_universal_compaction_options_destroy(o.uco)
+	}
 	uco := C.rocksdb_universal_compaction_options_create()
+	o.uco = uco
 
 	//C.rocksdb_universal_compaction_options_set_size_ratio(uco, ratio)
 
@@ -295,12 +454,14 @@ func (o *Options) SetMinLevelToCompress(level int) {
 // Close deallocates the ReadOptions, freeing its underlying C struct.
 func (ro *ReadOptions) Close() {
 	C.rocksdb_readoptions_destroy(ro.Opt)
+	C.free(ro.lowerC)
+	C.free(ro.upperC)
 }
 
 // SetVerifyChecksums controls whether all data read with this ReadOptions
 // will be verified against corresponding checksums.
 //
-// It defaults to false. See the LevelDB documentation for details.
+// It defaults to true.
 func (ro *ReadOptions) SetVerifyChecksums(b bool) {
 	C.rocksdb_readoptions_set_verify_checksums(ro.Opt, boolToUchar(b))
 }
@@ -317,6 +478,71 @@ func (ro *ReadOptions) SetFillCache(b bool) {
 	C.rocksdb_readoptions_set_fill_cache(ro.Opt, boolToUchar(b))
 }
 
+// SetReadTier limits where reads performed with this ReadOptions look for
+// data. With BlockCacheTier, a DB.Get whose answer is not in memory fails
+// with an error matching ErrIncomplete rather than reading from disk, and an
+// Iterator stops with such an error from GetError. Callers on
+// latency-critical paths can then retry the read in the background with a
+// ReadOptions using the default ReadAllTier.
+func (ro *ReadOptions) SetReadTier(tier ReadTier) {
+	C.rocksdb_readoptions_set_read_tier(ro.Opt, C.int(tier))
+}
+
+// SetReadaheadSize makes Iterators created with this ReadOptions read
+// ahead n bytes at a time from the files they scan, which speeds up long
+// sequential scans on storage with high latency per request. Zero, the
+// default, leaves readahead to RocksDB's automatic tuning.
+//
+// Readahead in the megabytes suits spinning disks and network storage,
+// where long scans are otherwise dominated by per-request latency.
+func (ro *ReadOptions) SetReadaheadSize(n int) {
+	C.rocksdb_readoptions_set_readahead_size(ro.Opt, C.size_t(n))
+}
+
+// SetAsyncIO makes Iterators created with this ReadOptions prefetch the
+// next readahead asynchronously while the current one is being consumed,
+// instead of waiting for each in turn. It needs a file system supporting
+// asynchronous reads, such as the default one on Linux with io_uring, and
+// otherwise reads synchronously as before.
+func (ro *ReadOptions) SetAsyncIO(b bool) {
+	C.rocksdb_readoptions_set_async_io(ro.Opt, boolToUchar(b))
+}
+
+// SetPrefixSameAsStart makes Iterators created with this ReadOptions stop,
+// becoming invalid, at the first key whose prefix differs from that of the
+// key they were positioned with by Seek. It only has an effect if the
+// database has a prefix extractor, see Options.SetPrefixExtractorFixed.
+func (ro *ReadOptions) SetPrefixSameAsStart(b bool) {
+	C.rocksdb_readoptions_set_prefix_same_as_start(ro.Opt, boolToUchar(b))
+}
+
+// SetTotalOrderSeek makes reads with this ReadOptions ignore the prefix
+// extractor and its filters, so that Iterators see every key in order,
+// across prefixes.
+func (ro *ReadOptions) SetTotalOrderSeek(b bool) {
+	C.rocksdb_readoptions_set_total_order_seek(ro.Opt, boolToUchar(b))
+}
+
+// SetPinData makes Iterators created with this ReadOptions keep the blocks
+// they read pinned in memory until they are closed, which lets
+// Iterator.PinnedKey and Iterator.PinnedValue return slices of them
+// instead of copies. This avoids a copy per key in scans that hold on to
+// many keys at once, at the cost of the memory of every block read.
+func (ro *ReadOptions) SetPinData(b bool) {
+	C.rocksdb_readoptions_set_pin_data(ro.Opt, boolToUchar(b))
+	ro.pinData = b
+}
+
+// SetTailing makes Iterators created with this ReadOptions tailing
+// iterators, which see keys written after they were created instead of
+// reading from an implicit snapshot. Next and Seek may return keys written
+// at any time before the call, so an Iterator that ran past the last key
+// picks up new ones by seeking to the key following the last one it
+// returned. This lets consumers follow a log or queue, see QueueConsumer,
+// without creating an Iterator per poll.
+//
+// Tailing Iterators only move forward: Prev and SeekToLast fail, leaving
+// an error in GetError. They ignore SetSnapshot.
 func (ro *ReadOptions) SetTailing(b bool) {
 	C.rocksdb_readoptions_set_tailing(ro.Opt, boolToUchar(b))
 }
@@ -332,6 +558,59 @@ func (ro *ReadOptions) SetSnapshot(snap *Snapshot) {
 		s = snap.snap
 	}
 	C.rocksdb_readoptions_set_snapshot(ro.Opt, s)
+	ro.snap = snap
+}
+
+// SetIterateLowerBound causes Iterators created with this ReadOptions to
+// stop, becoming invalid, when moving backward past key. The bound is
+// inclusive. Passing nil removes it.
+//
+// The bound is checked inside RocksDB, which is cheaper than comparing
+// keys in Go on every step, and lets it stop without reading deleted keys
+// beyond the bound, which an unbounded iteration would have to skip over.
+// A Seek to a key below the bound acts as a Seek to the bound.
+//
+// key is copied, so it may be reused. Iterators read the bound from the
+// ReadOptions, which must not be closed, nor its bounds changed, while
+// Iterators created with it are open.
+func (ro *ReadOptions) SetIterateLowerBound(key []byte) {
+	ro.lower = copyBound(key)
+	old := ro.lowerC
+	ro.lowerC = cBound(key)
+	C.rocksdb_readoptions_set_iterate_lower_bound(ro.Opt, (*C.char)(ro.lowerC), C.size_t(len(key)))
+	C.free(old)
+}
+
+// SetIterateUpperBound causes Iterators created with this ReadOptions to
+// stop, becoming invalid, when reaching key. The bound is exclusive. Passing
+// nil removes it.
+//
+// As with SetIterateLowerBound, the bound is checked inside RocksDB, which
+// also uses it to skip files and, with a prefix extractor, filters. key is
+// copied, and the same restrictions on closing the ReadOptions apply.
+func (ro *ReadOptions) SetIterateUpperBound(key []byte) {
+	ro.upper = copyBound(key)
+	old := ro.upperC
+	ro.upperC = cBound(key)
+	C.rocksdb_readoptions_set_iterate_upper_bound(ro.Opt, (*C.char)(ro.upperC), C.size_t(len(key)))
+	C.free(old)
+}
+
+func copyBound(key []byte) []byte {
+	if key == nil {
+		return nil
+	}
+	return append([]byte{}, key...)
+}
+
+// cBound copies key into C memory for the iterate bound setters, which keep
+// a pointer to it and treat a NULL pointer as "no bound". An empty key gets
+// a non-NULL pointer, since C.CBytes never returns NULL.
+func cBound(key []byte) unsafe.Pointer {
+	if key == nil {
+		return nil
+	}
+	return C.CBytes(key)
 }
 
 // Close deallocates the WriteOptions, freeing its underlying C struct.
@@ -0,0 +1,120 @@
+package otel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alberts/gorocks"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys set on the flush and compaction spans.
+const (
+	attrJobID         = attribute.Key("db.rocksdb.job_id")
+	attrFilePath      = attribute.Key("db.rocksdb.file_path")
+	attrWritesStalled = attribute.Key("db.rocksdb.triggered_writes_stop")
+	attrInputLevel    = attribute.Key("db.rocksdb.input_level")
+	attrOutputLevel   = attribute.Key("db.rocksdb.output_level")
+	attrInputFiles    = attribute.Key("db.rocksdb.input_files")
+	attrOutputFiles   = attribute.Key("db.rocksdb.output_files")
+	attrInputBytes    = attribute.Key("db.rocksdb.input_bytes")
+	attrOutputBytes   = attribute.Key("db.rocksdb.output_bytes")
+	attrInputRecords  = attribute.Key("db.rocksdb.input_records")
+	attrOutputRecords = attribute.Key("db.rocksdb.output_records")
+)
+
+// EventListener traces the flushes and compactions of databases as
+// "rocksdb.Flush" and "rocksdb.Compaction" spans, from the moment RocksDB
+// starts the job until it completes. As background work, the spans have no
+// parent. Install it on the Options before opening the database:
+//
+//	opts.AddEventListener(otel.NewEventListener(nil))
+type EventListener struct {
+	tracer trace.Tracer
+
+	mu    sync.Mutex
+	spans map[jobKey]trace.Span
+}
+
+// jobKey identifies a running job. Job IDs are only unique within a
+// database.
+type jobKey struct {
+	db         string
+	job        int
+	compaction bool
+}
+
+// NewEventListener returns an EventListener creating spans with a tracer
+// from tp, or from the global TracerProvider if tp is nil.
+func NewEventListener(tp trace.TracerProvider) *EventListener {
+	if tp == nil {
+		tp = otelapi.GetTracerProvider()
+	}
+	return &EventListener{
+		tracer: tp.Tracer(instrumentationName),
+		spans:  make(map[jobKey]trace.Span),
+	}
+}
+
+func (l *EventListener) start(key jobKey, name, cf string) trace.Span {
+	_, span := l.tracer.Start(context.Background(), name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attrDBSystem.String("rocksdb"),
+			attrDBName.String(key.db),
+			attrCF.String(cf),
+			attrJobID.Int(key.job)))
+	return span
+}
+
+func (l *EventListener) begin(key jobKey, name, cf string) {
+	span := l.start(key, name, cf)
+	l.mu.Lock()
+	l.spans[key] = span
+	l.mu.Unlock()
+}
+
+// completed returns the span started for key, or a new one if the job began
+// before the listener saw it.
+func (l *EventListener) completed(key jobKey, name, cf string) trace.Span {
+	l.mu.Lock()
+	span, ok := l.spans[key]
+	delete(l.spans, key)
+	l.mu.Unlock()
+	if !ok {
+		span = l.start(key, name, cf)
+	}
+	return span
+}
+
+func (l *EventListener) OnFlushBegin(info gorocks.FlushJobInfo) {
+	l.begin(jobKey{info.DBName, info.JobID, false}, "rocksdb.Flush", info.ColumnFamily)
+}
+
+func (l *EventListener) OnFlushCompleted(info gorocks.FlushJobInfo) {
+	span := l.completed(jobKey{info.DBName, info.JobID, false}, "rocksdb.Flush", info.ColumnFamily)
+	span.SetAttributes(
+		attrFilePath.String(info.FilePath),
+		attrWritesStalled.Bool(info.TriggeredWritesStop))
+	span.End()
+}
+
+func (l *EventListener) OnCompactionBegin(info gorocks.CompactionJobInfo) {
+	l.begin(jobKey{info.DBName, info.JobID, true}, "rocksdb.Compaction", info.ColumnFamily)
+}
+
+func (l *EventListener) OnCompactionCompleted(info gorocks.CompactionJobInfo) {
+	span := l.completed(jobKey{info.DBName, info.JobID, true}, "rocksdb.Compaction", info.ColumnFamily)
+	span.SetAttributes(
+		attrInputLevel.Int(info.BaseInputLevel),
+		attrOutputLevel.Int(info.OutputLevel),
+		attrInputFiles.Int(len(info.InputFiles)),
+		attrOutputFiles.Int(len(info.OutputFiles)),
+		attrInputBytes.Int64(int64(info.InputBytes)),
+		attrOutputBytes.Int64(int64(info.OutputBytes)),
+		attrInputRecords.Int64(int64(info.InputRecords)),
+		attrOutputRecords.Int64(int64(info.OutputRecords)))
+	end(span, info.Err)
+}
@@ -0,0 +1,186 @@
+// Package otel traces gorocks database operations with OpenTelemetry.
+//
+// Wrap a DB with New and use the returned DB's methods, which take a
+// context.Context, in place of the gorocks.DB ones. Every call creates a
+// span, as a child of the span in the context, recording the operation,
+// the key and value sizes and any error.
+//
+//	tdb := otel.New(db, nil)
+//	value, err := tdb.Get(ctx, ro, []byte("key"))
+//
+// Flushes and compactions run in the background, outside of any call, and
+// are traced by an EventListener installed on the database's Options.
+package otel
+
+import (
+	"context"
+
+	"github.com/alberts/gorocks"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package as the tracer's
+// instrumentation library.
+const instrumentationName = "github.com/alberts/gorocks/otel"
+
+// Attribute keys set on the spans.
+const (
+	attrDBName    = attribute.Key("db.name")
+	attrDBSystem  = attribute.Key("db.system")
+	attrKeySize   = attribute.Key("db.rocksdb.key_size")
+	attrValueSize = attribute.Key("db.rocksdb.value_size")
+	attrBatchOps  = attribute.Key("db.rocksdb.batch_ops")
+	attrNumKeys   = attribute.Key("db.rocksdb.num_keys")
+	attrCF        = attribute.Key("db.rocksdb.column_family")
+)
+
+// DB wraps a gorocks.DB, tracing the operations made through it. The
+// wrapped DB remains owned by the caller and may still be used directly,
+// untraced.
+type DB struct {
+	db     *gorocks.DB
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// New returns a DB tracing operations on db with a tracer from tp, or from
+// the global TracerProvider if tp is nil.
+func New(db *gorocks.DB, tp trace.TracerProvider) *DB {
+	if tp == nil {
+		tp = otelapi.GetTracerProvider()
+	}
+	return &DB{
+		db:     db,
+		tracer: tp.Tracer(instrumentationName),
+		attrs: []attribute.KeyValue{
+			attrDBSystem.String("rocksdb"),
+			attrDBName.String(db.Name()),
+		},
+	}
+}
+
+// Unwrap returns the wrapped gorocks.DB.
+func (d *DB) Unwrap() *gorocks.DB {
+	return d.db
+}
+
+func (d *DB) start(ctx context.Context, name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := d.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(d.attrs...),
+		trace.WithAttributes(attrs...))
+	return span
+}
+
+// end records err, if any, and ends span.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Get is like gorocks.DB.Get, traced as a "rocksdb.Get" span.
+func (d *DB) Get(ctx context.Context, ro *gorocks.ReadOptions, key []byte) ([]byte, error) {
+	span := d.start(ctx, "rocksdb.Get", attrKeySize.Int(len(key)))
+	value, err := d.db.Get(ro, key)
+	span.SetAttributes(attrValueSize.Int(len(value)))
+	end(span, err)
+	return value, err
+}
+
+// GetCF is like gorocks.DB.GetCF, traced as a "rocksdb.Get" span.
+func (d *DB) GetCF(ctx context.Context, ro *gorocks.ReadOptions, cf *gorocks.ColumnFamilyHandle, key []byte) ([]byte, error) {
+	span := d.start(ctx, "rocksdb.Get", attrKeySize.Int(len(key)), attrCF.String(cf.Name()))
+	value, err := d.db.GetCF(ro, cf, key)
+	span.SetAttributes(attrValueSize.Int(len(value)))
+	end(span, err)
+	return value, err
+}
+
+// MultiGet is like gorocks.DB.MultiGet, traced as a "rocksdb.MultiGet"
+// span.
+func (d *DB) MultiGet(ctx context.Context, ro *gorocks.ReadOptions, keys [][]byte) ([][]byte, error) {
+	span := d.start(ctx, "rocksdb.MultiGet", attrNumKeys.Int(len(keys)))
+	values, err := d.db.MultiGet(ro, keys)
+	end(span, err)
+	return values, err
+}
+
+// Put is like gorocks.DB.Put, traced as a "rocksdb.Put" span.
+func (d *DB) Put(ctx context.Context, wo *gorocks.WriteOptions, key, value []byte) error {
+	span := d.start(ctx, "rocksdb.Put", attrKeySize.Int(len(key)), attrValueSize.Int(len(value)))
+	err := d.db.Put(wo, key, value)
+	end(span, err)
+	return err
+}
+
+// PutCF is like gorocks.DB.PutCF, traced as a "rocksdb.Put" span.
+func (d *DB) PutCF(ctx context.Context, wo *gorocks.WriteOptions, cf *gorocks.ColumnFamilyHandle, key, value []byte) error {
+	span := d.start(ctx, "rocksdb.Put", attrKeySize.Int(len(key)), attrValueSize.Int(len(value)), attrCF.String(cf.Name()))
+	err := d.db.PutCF(wo, cf, key, value)
+	end(span, err)
+	return err
+}
+
+// Merge is like gorocks.DB.Merge, traced as a "rocksdb.Merge" span.
+func (d *DB) Merge(ctx context.Context, wo *gorocks.WriteOptions, key, value []byte) error {
+	span := d.start(ctx, "rocksdb.Merge", attrKeySize.Int(len(key)), attrValueSize.Int(len(value)))
+	err := d.db.Merge(wo, key, value)
+	end(span, err)
+	return err
+}
+
+// MergeCF is like gorocks.DB.MergeCF, traced as a "rocksdb.Merge" span.
+func (d *DB) MergeCF(ctx context.Context, wo *gorocks.WriteOptions, cf *gorocks.ColumnFamilyHandle, key, value []byte) error {
+	span := d.start(ctx, "rocksdb.Merge", attrKeySize.Int(len(key)), attrValueSize.Int(len(value)), attrCF.String(cf.Name()))
+	err := d.db.MergeCF(wo, cf, key, value)
+	end(span, err)
+	return err
+}
+
+// Delete is like gorocks.DB.Delete, traced as a "rocksdb.Delete" span.
+func (d *DB) Delete(ctx context.Context, wo *gorocks.WriteOptions, key []byte) error {
+	span := d.start(ctx, "rocksdb.Delete", attrKeySize.Int(len(key)))
+	err := d.db.Delete(wo, key)
+	end(span, err)
+	return err
+}
+
+// DeleteCF is like gorocks.DB.DeleteCF, traced as a "rocksdb.Delete" span.
+func (d *DB) DeleteCF(ctx context.Context, wo *gorocks.WriteOptions, cf *gorocks.ColumnFamilyHandle, key []byte) error {
+	span := d.start(ctx, "rocksdb.Delete", attrKeySize.Int(len(key)), attrCF.String(cf.Name()))
+	err := d.db.DeleteCF(wo, cf, key)
+	end(span, err)
+	return err
+}
+
+// DeleteRange is like gorocks.DB.DeleteRange, traced as a
+// "rocksdb.DeleteRange" span.
+func (d *DB) DeleteRange(ctx context.Context, wo *gorocks.WriteOptions, start, limit []byte) error {
+	span := d.start(ctx, "rocksdb.DeleteRange")
+	err := d.db.DeleteRange(wo, start, limit)
+	end(span, err)
+	return err
+}
+
+// Write is like gorocks.DB.Write, traced as a "rocksdb.Write" span.
+func (d *DB) Write(ctx context.Context, wo *gorocks.WriteOptions, w *gorocks.WriteBatch) error {
+	span := d.start(ctx, "rocksdb.Write", attrBatchOps.Int(w.Count()), attrValueSize.Int(len(w.Data())))
+	err := d.db.Write(wo, w)
+	end(span, err)
+	return err
+}
+
+// NewIterator is like gorocks.DB.NewIterator. Creating the Iterator is
+// traced as a "rocksdb.NewIterator" span; its steps are not traced.
+func (d *DB) NewIterator(ctx context.Context, ro *gorocks.ReadOptions) *gorocks.Iterator {
+	span := d.start(ctx, "rocksdb.NewIterator")
+	it := d.db.NewIterator(ro)
+	span.End()
+	return it
+}
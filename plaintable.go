@@ -0,0 +1,78 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+// PlainTableEncoding is the key encoding of plain table files.
+type PlainTableEncoding int
+
+const (
+	// PlainTableEncodingPlain stores every key in full.
+	PlainTableEncodingPlain PlainTableEncoding = 0
+	// PlainTableEncodingPrefix stores the prefix shared with the previous
+	// key only once, which makes files smaller at some cost in speed.
+	PlainTableEncodingPrefix PlainTableEncoding = 1
+)
+
+// PlainTableOptions configures the plain table SST format, set with
+// Options.SetPlainTableFactory. The zero value is not RocksDB's default;
+// start from DefaultPlainTableOptions.
+type PlainTableOptions struct {
+	// UserKeyLen is the length of every key, or 0 if keys vary in length.
+	UserKeyLen uint32
+
+	// BloomBitsPerKey is the size of the in-memory bloom filter over
+	// prefixes, or 0 for none.
+	BloomBitsPerKey int
+
+	// HashTableRatio is the desired utilization of the hash index over
+	// prefixes, or 0 to binary search the prefixes instead.
+	HashTableRatio float64
+
+	// IndexSparseness is the number of keys with the same prefix between
+	// index entries.
+	IndexSparseness int
+
+	// HugePageTLBSize allocates the index and bloom filter from huge pages
+	// of this size, if the system has them reserved, or 0 not to.
+	HugePageTLBSize int
+
+	Encoding PlainTableEncoding
+
+	// FullScanMode gives up the index, so that files only support
+	// iterating from the first key, in exchange for less memory.
+	FullScanMode bool
+
+	// StoreIndexInFile writes the index and bloom filter to the files, so
+	// that they are not rebuilt every time a file is opened.
+	StoreIndexInFile bool
+}
+
+// DefaultPlainTableOptions returns RocksDB's default PlainTableOptions.
+func DefaultPlainTableOptions() PlainTableOptions {
+	return PlainTableOptions{
+		BloomBitsPerKey: 10,
+		HashTableRatio:  0.75,
+		IndexSparseness: 16,
+	}
+}
+
+// SetPlainTableFactory makes the database write SST files in the plain
+// table format, which is designed for data held in memory, such as on a
+// tmpfs, and read with mmap: lookups index into the file without block
+// reads, caching or decompression. The index is built over prefixes, so a
+// prefix extractor should be set, see SetPrefixExtractor, and iterators
+// are confined to a prefix unless ReadOptions.SetTotalOrderSeek is set.
+//
+// Plain table files must be read with mmap, see SetAllowMmapReads.
+func (o *Options) SetPlainTableFactory(pt PlainTableOptions) {
+	C.rocksdb_options_set_plain_table_factory(o.Opt,
+		C.uint32_t(pt.UserKeyLen),
+		C.int(pt.BloomBitsPerKey),
+		C.double(pt.HashTableRatio),
+		C.size_t(pt.IndexSparseness),
+		C.size_t(pt.HugePageTLBSize),
+		C.char(pt.Encoding),
+		boolToUchar(pt.FullScanMode),
+		boolToUchar(pt.StoreIndexInFile))
+}
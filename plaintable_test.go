@@ -0,0 +1,39 @@
+package gorocks
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPlainTableFactory(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	pt := DefaultPlainTableOptions()
+	pt.UserKeyLen = 8
+	pt.Encoding = PlainTableEncodingPlain
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetAllowMmapReads(true)
+	options.SetPrefixExtractorFixed(4)
+	options.SetPlainTableFactory(pt)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 10; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("user%04d", i)), []byte("v"))
+	}
+	db.CompactRange(Range{})
+
+	CheckGet(t, "plain table", db, ro, []byte("user0003"), []byte("v"))
+	CheckGet(t, "plain table", db, ro, []byte("user0042"), nil)
+	if got := tableOption(t, dbname, "PlainTable", "user_key_len"); got != "8" {
+		t.Errorf("user_key_len = %q, want 8", got)
+	}
+}
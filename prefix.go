@@ -0,0 +1,194 @@
+package gorocks
+
+import (
+	"bytes"
+)
+
+// prefixSuccessor returns the smallest key greater than every key starting
+// with prefix, or nil if there is none because prefix is all 0xff bytes.
+func prefixSuccessor(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] != 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// DeletePrefixStage identifies a step of DB.DeletePrefix.
+type DeletePrefixStage int
+
+const (
+	// DeletePrefixKeysDeleted means the keys are no longer visible.
+	DeletePrefixKeysDeleted DeletePrefixStage = iota
+	// DeletePrefixFilesDeleted means the SST files holding only keys with
+	// the prefix have been dropped.
+	DeletePrefixFilesDeleted
+	// DeletePrefixCompacted means the remaining data and the range
+	// tombstone have been compacted away.
+	DeletePrefixCompacted
+)
+
+// DeletePrefix removes all keys starting with prefix, for example all the
+// data of one tenant, and reclaims their disk space promptly instead of
+// waiting for compaction to get around to it. It writes a single range
+// tombstone, drops the SST files lying entirely within the prefix, and
+// compacts what is left of the range.
+//
+// If progress is not nil, it is called after each stage, along with the
+// approximate size in bytes still taken up by the range.
+func (db *DB) DeletePrefix(wo *WriteOptions, prefix []byte, progress func(stage DeletePrefixStage, remaining uint64)) error {
+	end := prefixSuccessor(prefix)
+	if end == nil {
+		return DatabaseError("DeletePrefix: prefix must be non-empty and not all 0xff bytes")
+	}
+	r := Range{prefix, end}
+	report := func(stage DeletePrefixStage) {
+		if progress != nil {
+			progress(stage, db.GetApproximateSizes([]Range{r})[0])
+		}
+	}
+
+	if err := db.DeleteRange(wo, r.Start, r.Limit); err != nil {
+		return err
+	}
+	report(DeletePrefixKeysDeleted)
+	if err := db.DeleteFilesInRange(r); err != nil {
+		return err
+	}
+	report(DeletePrefixFilesDeleted)
+	db.CompactRange(r)
+	report(DeletePrefixCompacted)
+	return nil
+}
+
+// PrefixView is a logical namespace inside a DB: every key passed to it is
+// stored with the view's prefix prepended, and the keys it returns have the
+// prefix stripped. It gives cheap isolation between users of a database
+// without the overhead of column families. Keys are compared bytewise.
+type PrefixView struct {
+	db     *DB
+	prefix []byte
+}
+
+// WithPrefix returns a PrefixView of the keys of db that start with prefix.
+func (db *DB) WithPrefix(prefix []byte) *PrefixView {
+	return &PrefixView{db: db, prefix: append([]byte{}, prefix...)}
+}
+
+// Prefix returns the prefix of the view.
+func (v *PrefixView) Prefix() []byte {
+	return v.prefix
+}
+
+func (v *PrefixView) key(key []byte) []byte {
+	k := make([]byte, len(v.prefix)+len(key))
+	copy(k, v.prefix)
+	copy(k[len(v.prefix):], key)
+	return k
+}
+
+// Get is like DB.Get within the view.
+func (v *PrefixView) Get(ro *ReadOptions, key []byte) ([]byte, error) {
+	return v.db.Get(ro, v.key(key))
+}
+
+// Put is like DB.Put within the view.
+func (v *PrefixView) Put(wo *WriteOptions, key, value []byte) error {
+	return v.db.Put(wo, v.key(key), value)
+}
+
+// Delete is like DB.Delete within the view.
+func (v *PrefixView) Delete(wo *WriteOptions, key []byte) error {
+	return v.db.Delete(wo, v.key(key))
+}
+
+// NewIterator returns an Iterator over the keys of the view only.
+func (v *PrefixView) NewIterator(ro *ReadOptions) *PrefixIterator {
+	return &PrefixIterator{it: v.db.NewIterator(ro), prefix: v.prefix}
+}
+
+// PrefixIterator iterates over the keys of a PrefixView. It behaves like
+// Iterator, except that it becomes invalid when leaving the view and that
+// keys are passed and returned without the prefix.
+//
+// To prevent memory leaks, a PrefixIterator must have Close called on it
+// when it is no longer needed by the program.
+type PrefixIterator struct {
+	it     *Iterator
+	prefix []byte
+	buf    []byte
+}
+
+// Valid returns false when the iterator is positioned outside the view.
+func (pi *PrefixIterator) Valid() bool {
+	if !pi.it.Valid() {
+		return false
+	}
+	pi.buf = pi.it.AppendKey(pi.buf[:0])
+	return bytes.HasPrefix(pi.buf, pi.prefix)
+}
+
+// Key returns a copy of the current key without the prefix.
+func (pi *PrefixIterator) Key() []byte {
+	return pi.it.Key()[len(pi.prefix):]
+}
+
+// Value returns a copy of the current value.
+func (pi *PrefixIterator) Value() []byte {
+	return pi.it.Value()
+}
+
+// Next moves to the next key in the view.
+func (pi *PrefixIterator) Next() {
+	pi.it.Next()
+}
+
+// Prev moves to the previous key in the view.
+func (pi *PrefixIterator) Prev() {
+	pi.it.Prev()
+}
+
+// SeekToFirst moves to the first key in the view.
+func (pi *PrefixIterator) SeekToFirst() {
+	if len(pi.prefix) == 0 {
+		pi.it.SeekToFirst()
+		return
+	}
+	pi.it.Seek(pi.prefix)
+}
+
+// SeekToLast moves to the last key in the view.
+func (pi *PrefixIterator) SeekToLast() {
+	end := prefixSuccessor(pi.prefix)
+	if end == nil {
+		pi.it.SeekToLast()
+		return
+	}
+	pi.it.Seek(end)
+	if pi.it.Valid() {
+		pi.it.Prev()
+	} else {
+		pi.it.SeekToLast()
+	}
+}
+
+// Seek moves to key, or the next key in the view after it.
+func (pi *PrefixIterator) Seek(key []byte) {
+	k := make([]byte, len(pi.prefix)+len(key))
+	copy(k, pi.prefix)
+	copy(k[len(pi.prefix):], key)
+	pi.it.Seek(k)
+}
+
+// GetError returns the error of the underlying Iterator, if any.
+func (pi *PrefixIterator) GetError() error {
+	return pi.it.GetError()
+}
+
+// Close releases the underlying Iterator.
+func (pi *PrefixIterator) Close() {
+	pi.it.Close()
+}
@@ -0,0 +1,210 @@
+package gorocks
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPrefixView(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("a"), []byte("outside"))
+	db.Put(wo, []byte("t2/x"), []byte("other tenant"))
+	v := db.WithPrefix([]byte("t1/"))
+	v.Put(wo, []byte("x"), []byte("1"))
+	v.Put(wo, []byte("y"), []byte("2"))
+	CheckGet(t, "prefixed key", db, ro, []byte("t1/x"), []byte("1"))
+	if value, _ := v.Get(ro, []byte("y")); string(value) != "2" {
+		t.Errorf("view Get: expected 2, got %q", value)
+	}
+
+	it := v.NewIterator(ro)
+	defer it.Close()
+	var keys [][]byte
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0], []byte("x")) || !bytes.Equal(keys[1], []byte("y")) {
+		t.Errorf("expected view keys [x y], got %q", keys)
+	}
+	it.SeekToLast()
+	if !it.Valid() || !bytes.Equal(it.Key(), []byte("y")) {
+		t.Errorf("SeekToLast should stay inside the view")
+	}
+
+	if end := prefixSuccessor([]byte{'a', 0xff}); !bytes.Equal(end, []byte("b")) {
+		t.Errorf("unexpected prefix successor %q", end)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("tenant1/%03d", i)), make([]byte, 1024))
+		db.Put(wo, []byte(fmt.Sprintf("tenant2/%03d", i)), []byte("keep"))
+	}
+	db.CompactRange(Range{})
+
+	var stages []DeletePrefixStage
+	var remaining uint64
+	err = db.DeletePrefix(wo, []byte("tenant1/"), func(stage DeletePrefixStage, n uint64) {
+		stages = append(stages, stage)
+		remaining = n
+	})
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if len(stages) != 3 || stages[2] != DeletePrefixCompacted {
+		t.Errorf("unexpected stages %v", stages)
+	}
+	if remaining > 1024 {
+		t.Errorf("expected the space to be reclaimed, %d bytes remain", remaining)
+	}
+	CheckGet(t, "deleted tenant", db, ro, []byte("tenant1/000"), nil)
+	CheckGet(t, "other tenant", db, ro, []byte("tenant2/000"), []byte("keep"))
+
+	if err := db.DeletePrefix(wo, nil, nil); err == nil {
+		t.Errorf("DeletePrefix with an empty prefix should fail")
+	}
+}
+
+func TestPrefixExtractorFixed(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetPrefixExtractorFixed(4)
+	options.SetMemtablePrefixBloomSizeRatio(0.1)
+	options.SetMemtableWholeKeyFiltering(true)
+	filter := NewBloomFilter(10)
+	defer filter.Close()
+	options.SetFilterPolicy(filter)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for _, k := range []string{"aaaa1", "aaaa2", "bbbb1", "cccc1"} {
+		db.Put(wo, []byte(k), []byte("v"))
+	}
+	db.CompactRange(Range{})
+
+	keys := func(ro *ReadOptions, seek string) []string {
+		it := db.NewIterator(ro)
+		defer it.Close()
+		var keys []string
+		for it.Seek([]byte(seek)); it.Valid(); it.Next() {
+			keys = append(keys, string(it.Key()))
+		}
+		return keys
+	}
+	ro.SetPrefixSameAsStart(true)
+	if got := fmt.Sprint(keys(ro, "aaaa")); got != "[aaaa1 aaaa2]" {
+		t.Errorf("prefix iteration = %s, want [aaaa1 aaaa2]", got)
+	}
+	db.Put(wo, []byte("dddd1"), []byte("v"))
+	CheckGet(t, "in memtable", db, ro, []byte("dddd1"), []byte("v"))
+	CheckGet(t, "not in memtable", db, ro, []byte("eeee1"), nil)
+	if got := fmt.Sprint(keys(ro, "dddd")); got != "[dddd1]" {
+		t.Errorf("prefix iteration over the memtable = %s, want [dddd1]", got)
+	}
+	ro.SetPrefixSameAsStart(false)
+	ro.SetTotalOrderSeek(true)
+	if got := fmt.Sprint(keys(ro, "aaaa2")); got != "[aaaa2 bbbb1 cccc1 dddd1]" {
+		t.Errorf("total order iteration = %s, want [aaaa2 bbbb1 cccc1 dddd1]", got)
+	}
+}
+
+// delimiterTransform makes everything up to and including the first '/' of
+// a key its prefix.
+type delimiterTransform struct{}
+
+func (delimiterTransform) Name() string { return "test.delimiter" }
+
+func (delimiterTransform) Transform(key []byte) []byte {
+	return key[:bytes.IndexByte(key, '/')+1]
+}
+
+func (delimiterTransform) InDomain(key []byte) bool { return bytes.IndexByte(key, '/') >= 0 }
+
+func TestSliceTransforms(t *testing.T) {
+	tests := []struct {
+		st   SliceTransform
+		keys []string
+		seek string
+		want string
+	}{
+		{delimiterTransform{}, []string{"a/1", "a/2", "bb/1", "bb/2", "ccc/1", "nodelimiter"}, "bb/", "[bb/1 bb/2]"},
+		{NewCappedPrefixTransform(3), []string{"x", "xy1", "xyz1", "xyz2", "z"}, "xyz", "[xyz1 xyz2]"},
+		{NewFixedPrefixTransform(2), []string{"aa1", "ab1", "ab2", "b"}, "ab", "[ab1 ab2]"},
+	}
+	for _, tt := range tests {
+		dbname := tempDir(t)
+		options := NewOptions()
+		options.SetCreateIfMissing(true)
+		options.SetPrefixExtractor(tt.st)
+		filter := NewBloomFilter(10)
+		options.SetFilterPolicy(filter)
+		ro := NewReadOptions()
+		ro.SetPrefixSameAsStart(true)
+		wo := NewWriteOptions()
+		db, err := Open(dbname, options)
+		if err != nil {
+			t.Fatalf("Database could not be opened with %s: %v", tt.st.Name(), err)
+		}
+		for _, k := range tt.keys {
+			db.Put(wo, []byte(k), []byte("v"))
+		}
+		db.CompactRange(Range{})
+
+		it := db.NewIterator(ro)
+		var keys []string
+		for it.Seek([]byte(tt.seek)); it.Valid(); it.Next() {
+			keys = append(keys, string(it.Key()))
+		}
+		it.Close()
+		if got := fmt.Sprint(keys); got != tt.want {
+			t.Errorf("%s: prefix iteration from %q = %s, want %s", tt.st.Name(), tt.seek, got, tt.want)
+		}
+
+		db.Close()
+		wo.Close()
+		ro.Close()
+		options.Close()
+		filter.Close()
+		deleteDBDirectory(t, dbname)
+	}
+}
@@ -0,0 +1,227 @@
+package gorocks
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+)
+
+// Queue is a persistent FIFO queue stored in a DB. Every element is kept
+// under the queue's name followed by its 8-byte big-endian sequence number,
+// so elements sort in the order they were pushed.
+//
+// Elements can be consumed either destructively with Pop, or by any number
+// of independent QueueConsumers that follow the queue with a tailing
+// Iterator and are trimmed away with Trim once all consumers are past them.
+//
+// A Queue may be shared between goroutines, but a queue name must only be
+// opened once per database at a time.
+type Queue struct {
+	db     *DB
+	prefix []byte
+	ro     *ReadOptions
+
+	mu         sync.Mutex
+	head, tail uint64        // first live and next unused sequence number
+	pushed     chan struct{} // closed and replaced on every push
+}
+
+// OpenQueue opens the queue with the given name in db, finding its current
+// head and tail.
+func OpenQueue(db *DB, name string) (*Queue, error) {
+	q := &Queue{
+		db:     db,
+		prefix: append([]byte(name), 0),
+		ro:     NewReadOptions(),
+		pushed: make(chan struct{}),
+	}
+	it := db.NewIterator(q.ro)
+	defer it.Close()
+
+	it.Seek(q.key(0))
+	if it.Valid() {
+		if seq, ok := q.seq(it.Key()); ok {
+			q.head = seq
+		}
+	}
+	it.Seek(q.key(^uint64(0)))
+	if it.Valid() {
+		it.Prev()
+	} else {
+		it.SeekToLast()
+	}
+	if it.Valid() {
+		if seq, ok := q.seq(it.Key()); ok {
+			q.tail = seq + 1
+		}
+	}
+	if q.tail < q.head {
+		q.tail = q.head
+	}
+	if err := it.GetError(); err != nil {
+		q.ro.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) key(seq uint64) []byte {
+	k := make([]byte, len(q.prefix)+8)
+	copy(k, q.prefix)
+	binary.BigEndian.PutUint64(k[len(q.prefix):], seq)
+	return k
+}
+
+func (q *Queue) seq(key []byte) (uint64, bool) {
+	if len(key) != len(q.prefix)+8 || string(key[:len(q.prefix)]) != string(q.prefix) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(key[len(q.prefix):]), true
+}
+
+// Close releases the resources held by the Queue. The DB is not closed.
+func (q *Queue) Close() {
+	q.ro.Close()
+}
+
+// Len returns the number of elements between the head and the tail of the
+// queue.
+func (q *Queue) Len() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tail - q.head
+}
+
+// Push appends value to the queue and returns its sequence number.
+func (q *Queue) Push(wo *WriteOptions, value []byte) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seq := q.tail
+	if err := q.db.Put(wo, q.key(seq), value); err != nil {
+		return 0, err
+	}
+	q.tail++
+	close(q.pushed)
+	q.pushed = make(chan struct{})
+	return seq, nil
+}
+
+// Pop removes and returns the element at the head of the queue. ok is false
+// if the queue is empty.
+func (q *Queue) Pop(wo *WriteOptions) (seq uint64, value []byte, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.head < q.tail {
+		seq = q.head
+		key := q.key(seq)
+		value, err = q.db.Get(q.ro, key)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if value == nil {
+			// Already removed by Trim.
+			q.head++
+			continue
+		}
+		if err = q.db.Delete(wo, key); err != nil {
+			return 0, nil, false, err
+		}
+		q.head++
+		return seq, value, true, nil
+	}
+	return 0, nil, false, nil
+}
+
+// Trim removes all elements with sequence numbers below upTo using a single
+// range deletion.
+func (q *Queue) Trim(wo *WriteOptions, upTo uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if upTo <= q.head {
+		return nil
+	}
+	if upTo > q.tail {
+		upTo = q.tail
+	}
+	if err := q.db.DeleteRange(wo, q.key(q.head), q.key(upTo)); err != nil {
+		return err
+	}
+	q.head = upTo
+	return nil
+}
+
+// QueueConsumer reads the elements of a Queue in order without removing
+// them. It follows the queue with a tailing Iterator, so elements pushed
+// after the consumer was created are seen without reopening it.
+//
+// A QueueConsumer must only be used by one goroutine at a time. To prevent
+// memory leaks, Close must be called on it when it is no longer needed.
+type QueueConsumer struct {
+	q *Queue
+	// ro owns the copy of the upper bound it reads, so it is closed only
+	// after it.
+	ro   *ReadOptions
+	it   *Iterator
+	next uint64
+}
+
+// NewConsumer returns a QueueConsumer starting at sequence number from.
+// Elements below from, or already trimmed, are skipped.
+func (q *Queue) NewConsumer(from uint64) *QueueConsumer {
+	ro := NewReadOptions()
+	ro.SetTailing(true)
+	ro.SetIterateUpperBound(q.key(^uint64(0)))
+	return &QueueConsumer{q: q, ro: ro, it: q.db.NewIterator(ro), next: from}
+}
+
+// Position returns the sequence number of the next element the consumer
+// will return.
+func (c *QueueConsumer) Position() uint64 {
+	return c.next
+}
+
+// TryNext returns the next element if one is available. ok is false if the
+// consumer has caught up with the tail of the queue.
+func (c *QueueConsumer) TryNext() (seq uint64, value []byte, ok bool, err error) {
+	c.it.Seek(c.q.key(c.next))
+	if !c.it.Valid() {
+		return 0, nil, false, c.it.GetError()
+	}
+	seq, ok = c.q.seq(c.it.Key())
+	if !ok {
+		return 0, nil, false, nil
+	}
+	c.next = seq + 1
+	return seq, c.it.Value(), true, nil
+}
+
+// Next returns the next element, waiting for one to be pushed through the
+// same Queue if the consumer has caught up. It returns ctx.Err() if ctx is
+// done first.
+func (c *QueueConsumer) Next(ctx context.Context) (uint64, []byte, error) {
+	for {
+		c.q.mu.Lock()
+		pushed := c.q.pushed
+		c.q.mu.Unlock()
+
+		seq, value, ok, err := c.TryNext()
+		if err != nil {
+			return 0, nil, err
+		}
+		if ok {
+			return seq, value, nil
+		}
+		select {
+		case <-pushed:
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+// Close releases the consumer's Iterator and then its ReadOptions, which
+// holds the Iterator's upper bound.
+func (c *QueueConsumer) Close() {
+	c.it.Close()
+	c.ro.Close()
+}
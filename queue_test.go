@@ -0,0 +1,73 @@
+package gorocks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	q, err := OpenQueue(db, "jobs")
+	if err != nil {
+		t.Fatalf("OpenQueue failed: %v", err)
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		if _, err := q.Push(wo, []byte(v)); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+	}
+
+	c := q.NewConsumer(0)
+	defer c.Close()
+	for i, want := range []string{"a", "b", "c"} {
+		seq, value, ok, err := c.TryNext()
+		if !ok || err != nil || seq != uint64(i) || string(value) != want {
+			t.Errorf("TryNext %d: got %d, %q, %v, %v", i, seq, value, ok, err)
+		}
+	}
+	if _, _, ok, _ := c.TryNext(); ok {
+		t.Errorf("consumer should have caught up")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Push(wo, []byte("d"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	seq, value, err := c.Next(ctx)
+	if err != nil || seq != 3 || string(value) != "d" {
+		t.Errorf("Next: got %d, %q, %v", seq, value, err)
+	}
+
+	if err := q.Trim(wo, 2); err != nil {
+		t.Errorf("Trim failed: %v", err)
+	}
+	q.Close()
+
+	q, err = OpenQueue(db, "jobs")
+	if err != nil {
+		t.Fatalf("reopening queue failed: %v", err)
+	}
+	defer q.Close()
+	if q.Len() != 2 {
+		t.Errorf("expected 2 elements after Trim, got %d", q.Len())
+	}
+	seq, value, ok, err := q.Pop(wo)
+	if !ok || err != nil || seq != 2 || string(value) != "c" {
+		t.Errorf("Pop: got %d, %q, %v, %v", seq, value, ok, err)
+	}
+}
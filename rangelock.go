@@ -0,0 +1,104 @@
+package gorocks
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// RangeLocker serializes writers over ranges of keys within a process, for
+// example all the keys of one account, without a global mutex. Locks are
+// exclusive: a lock is granted once no lock on an overlapping range is held.
+//
+// RocksDB's own transactions only lock the individual keys they write,
+// which does not stop two transactions from, say, both checking that an
+// account has no pending order and then both adding one. Locking the
+// account's range first with LockTxn closes that gap.
+//
+// A RangeLocker may be shared between goroutines. Its zero value is ready
+// to use.
+type RangeLocker struct {
+	mu   sync.Mutex
+	held []*RangeLock
+}
+
+// RangeLock is a lock on a Range held through a RangeLocker.
+type RangeLock struct {
+	locker   *RangeLocker
+	r        Range
+	released chan struct{}
+	once     sync.Once
+}
+
+// rangesOverlap reports whether the ranges [a.Start, a.Limit) and
+// [b.Start, b.Limit) have keys in common. A nil Limit means the range is
+// unbounded.
+func rangesOverlap(a, b Range) bool {
+	return (b.Limit == nil || bytes.Compare(a.Start, b.Limit) < 0) &&
+		(a.Limit == nil || bytes.Compare(b.Start, a.Limit) < 0)
+}
+
+// Lock locks the keys from r.Start up to but not including r.Limit, or all
+// keys from r.Start if r.Limit is nil, waiting while an overlapping range
+// is locked. It returns ctx.Err() if ctx is done first.
+func (rl *RangeLocker) Lock(ctx context.Context, r Range) (*RangeLock, error) {
+	l := &RangeLock{locker: rl, r: r, released: make(chan struct{})}
+	for {
+		rl.mu.Lock()
+		var conflict *RangeLock
+		for _, h := range rl.held {
+			if rangesOverlap(h.r, r) {
+				conflict = h
+				break
+			}
+		}
+		if conflict == nil {
+			rl.held = append(rl.held, l)
+			rl.mu.Unlock()
+			return l, nil
+		}
+		rl.mu.Unlock()
+		select {
+		case <-conflict.released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// LockPrefix locks all keys starting with prefix.
+func (rl *RangeLocker) LockPrefix(ctx context.Context, prefix []byte) (*RangeLock, error) {
+	return rl.Lock(ctx, Range{prefix, prefixSuccessor(prefix)})
+}
+
+// LockTxn locks r as Lock does and holds the lock until t is committed,
+// rolled back or closed.
+func (rl *RangeLocker) LockTxn(ctx context.Context, t *Transaction, r Range) error {
+	l, err := rl.Lock(ctx, r)
+	if err != nil {
+		return err
+	}
+	t.rangeLocks = append(t.rangeLocks, l)
+	return nil
+}
+
+// Range returns the locked range.
+func (l *RangeLock) Range() Range {
+	return l.r
+}
+
+// Unlock releases the lock. Further calls do nothing.
+func (l *RangeLock) Unlock() {
+	l.once.Do(func() {
+		rl := l.locker
+		rl.mu.Lock()
+		for i, h := range rl.held {
+			if h == l {
+				rl.held = append(rl.held[:i], rl.held[i+1:]...)
+				break
+			}
+		}
+		rl.mu.Unlock()
+		close(l.released)
+	})
+}
@@ -0,0 +1,36 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"time"
+)
+
+// RateLimiter throttles the writes of flushes and compactions, so that
+// heavy background work does not starve foreground reads and writes of
+// I/O bandwidth. It is installed with Options.SetRateLimiter, and may be
+// shared between databases to limit them together.
+//
+// To prevent memory leaks, a RateLimiter must have Close called on it when
+// it is no longer needed by the program.
+type RateLimiter struct {
+	Limiter *C.rocksdb_ratelimiter_t
+}
+
+// NewRateLimiter creates a RateLimiter allowing bytesPerSec bytes of
+// background writes per second. The budget is refilled every refillPeriod;
+// 100ms is a good default, and shorter periods smooth out bursts at some
+// CPU cost. fairness is how many times more often high priority requests,
+// from flushes, are served before low priority ones, from compactions, and
+// is usually 10.
+func NewRateLimiter(bytesPerSec int64, refillPeriod time.Duration, fairness int) *RateLimiter {
+	rl := C.rocksdb_ratelimiter_create(C.int64_t(bytesPerSec), C.int64_t(refillPeriod/time.Microsecond), C.int32_t(fairness))
+	return &RateLimiter{rl}
+}
+
+// Close deallocates the RateLimiter. Databases using it keep their own
+// reference.
+func (rl *RateLimiter) Close() {
+	C.rocksdb_ratelimiter_destroy(rl.Limiter)
+}
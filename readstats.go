@@ -0,0 +1,69 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"runtime"
+)
+
+// ReadStats is the read amplification of a single request, as counted by
+// RocksDB's PerfContext. It is returned by DB.GetWithStats and
+// DB.MultiGetWithStats, so that unusually expensive reads can be logged.
+type ReadStats struct {
+	// BlocksRead and BytesRead are the blocks read from files, and their
+	// total size, because they were not in the block cache.
+	BlocksRead uint64
+	BytesRead  uint64
+
+	// BlockCacheHits is the number of blocks found in the block cache.
+	BlockCacheHits uint64
+
+	// BloomUseful is the number of files skipped because their bloom
+	// filter ruled the key out.
+	BloomUseful uint64
+}
+
+// withReadStats runs read and returns the ReadStats it incurred.
+//
+// The PerfContext and perf level are per thread, so the goroutine is locked
+// to its thread for the duration. Counting is turned off again afterwards,
+// whatever the thread's perf level was before.
+func withReadStats(read func()) ReadStats {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	C.rocksdb_set_perf_level(C.rocksdb_enable_count)
+	defer C.rocksdb_set_perf_level(C.rocksdb_disable)
+	pc := C.rocksdb_perfcontext_create()
+	defer C.rocksdb_perfcontext_destroy(pc)
+	C.rocksdb_perfcontext_reset(pc)
+
+	read()
+
+	return ReadStats{
+		BlocksRead:     uint64(C.rocksdb_perfcontext_metric(pc, C.rocksdb_block_read_count)),
+		BytesRead:      uint64(C.rocksdb_perfcontext_metric(pc, C.rocksdb_block_read_byte)),
+		BlockCacheHits: uint64(C.rocksdb_perfcontext_metric(pc, C.rocksdb_block_cache_hit_count)),
+		BloomUseful:    uint64(C.rocksdb_perfcontext_metric(pc, C.rocksdb_bloom_sst_miss_count)),
+	}
+}
+
+// GetWithStats is like Get, but also returns the ReadStats of the lookup.
+// Counting costs little, but more than nothing, so use Get where the stats
+// are not needed.
+func (db *DB) GetWithStats(ro *ReadOptions, key []byte) (v []byte, stats ReadStats, err error) {
+	stats = withReadStats(func() {
+		v, err = db.Get(ro, key)
+	})
+	return v, stats, err
+}
+
+// MultiGetWithStats is like MultiGet, but also returns the ReadStats of all
+// the lookups together.
+func (db *DB) MultiGetWithStats(ro *ReadOptions, keys [][]byte) (values [][]byte, stats ReadStats, err error) {
+	stats = withReadStats(func() {
+		values, err = db.MultiGet(ro, keys)
+	})
+	return values, stats, err
+}
@@ -2,10 +2,14 @@ package gorocks
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -22,7 +26,7 @@ func TestC(t *testing.T) {
 	cache := NewLRUCache(1 << 20)
 
 	options := NewOptions()
-	// options.SetComparator(cmp)
+	options.SetComparator(bytewiseComparator{"foo"})
 	options.SetErrorIfExists(true)
 	options.SetCache(cache)
 	options.SetEnv(env)
@@ -76,8 +80,7 @@ func TestC(t *testing.T) {
 	CheckGet(t, "after WriteBatch", db, roptions, []byte("foo"), []byte("hello"))
 	CheckGet(t, "after WriteBatch", db, roptions, []byte("bar"), nil)
 	CheckGet(t, "after WriteBatch", db, roptions, []byte("box"), []byte("c"))
-	// TODO: WriteBatch iteration isn't easy. Suffers same problems as
-	// Comparator.
+	// TODO: WriteBatch iteration isn't easy.
 	// wbiter := &TestWBIter{t: t}
 	// wb.Iterate(wbiter)
 	// if wbiter.pos != 3 {
@@ -208,7 +211,6 @@ func TestC(t *testing.T) {
 	roptions.Close()
 	woptions.Close()
 	cache.Close()
-	// DestroyComparator(cmp)
 	env.Close()
 }
 
@@ -255,6 +257,15 @@ func TestNilSlicesInDb(t *testing.T) {
 		t.Errorf("empty value Put errored: %v", err)
 	}
 	CheckGet(t, "empty value Put", db, ro, []byte("emptyvalue"), []byte{})
+	if size, found, err := db.GetValueSize(ro, []byte("emptyvalue")); size != 0 || !found || err != nil {
+		t.Errorf("GetValueSize of empty value: got %d, %v, %v", size, found, err)
+	}
+	if size, found, err := db.GetValueSize(ro, nil); size != 4 || !found || err != nil {
+		t.Errorf("GetValueSize of nil key: got %d, %v, %v", size, found, err)
+	}
+	if _, found, _ := db.GetValueSize(ro, []byte("missing")); found {
+		t.Errorf("GetValueSize should not find a missing key")
+	}
 
 	err = db.Delete(wo, nil)
 	if err != nil {
@@ -314,42 +325,1409 @@ func TestIterationValidityLimits(t *testing.T) {
 	}
 }
 
-func CheckGet(t *testing.T, where string, db *DB, roptions *ReadOptions, key, expected []byte) {
-	getValue, err := db.Get(roptions, key)
+func TestOptionsClone(t *testing.T) {
+	template := NewOptions()
+	template.SetCreateIfMissing(true)
+	template.SetCompactionStyle(UniversalStyleCompaction)
+
+	clone := template.Clone()
+	template.Close()
+	defer clone.Close()
 
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	db, err := Open(dbname, clone)
 	if err != nil {
-		t.Errorf("%s, Get failed: %v", where, err)
+		t.Fatalf("Open with cloned options failed: %v", err)
 	}
-	if !bytes.Equal(getValue, expected) {
-		t.Errorf("%s, expected Get value %v, got %v", where, expected, getValue)
+	db.Close()
+}
+
+func TestUpdate(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	key := []byte("counter")
+	incr := func(old []byte) ([]byte, error) {
+		var n int
+		if old != nil {
+			fmt.Sscan(string(old), &n)
+		}
+		return []byte(fmt.Sprint(n + 1)), nil
+	}
+	policy := RetryPolicy{MaxAttempts: 1000, Backoff: time.Microsecond, MaxBackoff: time.Millisecond}
+	done := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 50; j++ {
+				if err := db.UpdateWithRetry(ro, wo, key, policy, incr); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("Update failed: %v", err)
+		}
+	}
+	CheckGet(t, "after Update", db, ro, key, []byte("400"))
+
+	err = db.Update(ro, wo, key, func(old []byte) ([]byte, error) { return nil, nil })
+	if err != nil {
+		t.Errorf("deleting Update failed: %v", err)
 	}
+	CheckGet(t, "after deleting Update", db, ro, key, nil)
 }
 
-func WBIterCheckEqual(t *testing.T, where string, which string, pos int, expected, given []byte) {
-	if !bytes.Equal(expected, given) {
-		t.Errorf("%s at pos %d, %s expected: %v, got: %v", where, pos, which, expected, given)
+func TestRetryPolicyBackoff(t *testing.T) {
+	capped := RetryPolicy{Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	uncapped := RetryPolicy{Backoff: time.Millisecond}
+	cases := []struct {
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{capped, 1, time.Millisecond},
+		{capped, 3, 4 * time.Millisecond},
+		{capped, 4, 5 * time.Millisecond},
+		{capped, 100, 5 * time.Millisecond},
+		{uncapped, 1, time.Millisecond},
+		{uncapped, 4, 8 * time.Millisecond},
+		{uncapped, 11, 1024 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if got := c.policy.backoff(c.attempt); got != c.want {
+			t.Errorf("%+v.backoff(%d) = %v, want %v", c.policy, c.attempt, got, c.want)
+		}
+	}
+	if got := uncapped.backoff(1000); got <= 0 {
+		t.Errorf("uncapped backoff overflowed to %v", got)
 	}
 }
 
-func CheckIter(t *testing.T, it *Iterator, key, value []byte) {
-	if !bytes.Equal(key, it.Key()) {
-		t.Errorf("Iterator: expected key %v, got %v", key, it.Key())
+func TestGetMultiConsistent(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
 	}
-	if !bytes.Equal(value, it.Value()) {
-		t.Errorf("Iterator: expected value %v, got %v", value, it.Value())
+	defer db.Close()
+
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.Put(wo, []byte("c"), []byte{})
+	values, err := db.GetMultiConsistent(ro, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	if err != nil {
+		t.Fatalf("GetMultiConsistent failed: %v", err)
+	}
+	expected := [][]byte{[]byte("1"), nil, []byte{}}
+	for i := range expected {
+		if !bytes.Equal(values[i], expected[i]) || (values[i] == nil) != (expected[i] == nil) {
+			t.Errorf("value %d: expected %v, got %v", i, expected[i], values[i])
+		}
 	}
 }
 
-func deleteDBDirectory(t *testing.T, dirPath string) {
-	err := os.RemoveAll(dirPath)
+func TestIteratorStatus(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
 	if err != nil {
-		t.Errorf("Unable to remove database directory: %s", dirPath)
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		db.Put(wo, []byte(k), []byte(k))
+	}
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetIterateUpperBound([]byte("c"))
+	it := db.NewIterator(ro)
+	defer it.Close()
+	if !bytes.Equal(it.UpperBound(), []byte("c")) || it.LowerBound() != nil {
+		t.Errorf("unexpected bounds %q, %q", it.LowerBound(), it.UpperBound())
+	}
+	var n int
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("expected 2 keys below the upper bound, got %d", n)
+	}
+	if st := it.Status(); st.State != IteratorAtBound {
+		t.Errorf("expected IteratorAtBound, got %v", st)
+	}
+	it.SeekToFirst()
+	it.Prev()
+	if st := it.Status(); st.State != IteratorExhausted {
+		t.Errorf("expected IteratorExhausted, got %v", st)
 	}
 }
 
-func tempDir(t *testing.T) string {
-	bottom := fmt.Sprintf("rocksdb-test-%d", rand.Int())
-	path := filepath.Join(os.TempDir(), bottom)
-	deleteDBDirectory(t, path)
-	return path
+func TestDBNameAndOptionsFile(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	if db.Name() != dbname {
+		t.Errorf("expected Name %q, got %q", dbname, db.Name())
+	}
+	if !filepath.IsAbs(db.Path()) {
+		t.Errorf("Path should be absolute, got %q", db.Path())
+	}
+	opts, err := db.OptionsFile()
+	if err != nil {
+		t.Fatalf("OptionsFile failed: %v", err)
+	}
+	if !strings.Contains(opts, "[DBOptions]") {
+		t.Errorf("OPTIONS file has no DBOptions section:\n%s", opts)
+	}
+}
+
+func TestParanoidReads(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetParanoidReads()
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("a"), []byte("1"))
+	CheckGet(t, "from memtable", db, ro, []byte("a"), []byte("1"))
+	opts, err := db.OptionsFile()
+	if err != nil {
+		t.Fatalf("OptionsFile failed: %v", err)
+	}
+	parsed := parseOptionsFile(opts)
+	if got := parsed[`CFOptions "default"`+"\tmemtable_protection_bytes_per_key"]; got != "8" {
+		t.Errorf("memtable_protection_bytes_per_key = %q, want 8", got)
+	}
+	if got := parsed["DBOptions\tparanoid_checks"]; got != "true" {
+		t.Errorf("paranoid_checks = %q, want true", got)
+	}
+}
+
+func TestWriteHook(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	var seqs []uint64
+	var counts []int
+	var last Record
+	db.SetWriteHook(func(seq uint64, wb *WriteBatch) {
+		seqs = append(seqs, seq)
+		counts = append(counts, wb.Count())
+		it := wb.NewIterator()
+		for it.Next() {
+			last = *it.Record()
+		}
+	})
+	db.Put(wo, []byte("a"), []byte("1"))
+	wb := NewWriteBatch()
+	wb.Put([]byte("b"), []byte("2"))
+	wb.Delete([]byte("a"))
+	db.Write(wo, wb)
+	wb.Close()
+	db.Delete(wo, nil)
+	db.DeleteRange(wo, []byte("a"), []byte("z"))
+	db.SetWriteHook(nil)
+	db.Put(wo, []byte("c"), []byte("3"))
+
+	if len(seqs) != 4 {
+		t.Fatalf("expected 4 hook calls, got %d", len(seqs))
+	}
+	if seqs[1] != seqs[0]+2 || seqs[2] != seqs[1]+1 || seqs[3] != seqs[2]+1 {
+		t.Errorf("unexpected sequence numbers %v", seqs)
+	}
+	if counts[0] != 1 || counts[1] != 2 || counts[2] != 1 || counts[3] != 1 {
+		t.Errorf("unexpected batch counts %v", counts)
+	}
+	if last.Type != RecordTypeRangeDeletion || string(last.Key) != "a" || string(last.Value) != "z" {
+		t.Errorf("DeleteRange reached the hook as %+v", last)
+	}
+}
+
+func TestOpHook(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	var ops []OpInfo
+	db.SetOpHook(func(info OpInfo) {
+		ops = append(ops, info)
+	})
+	db.Put(wo, []byte("key"), []byte("value"))
+	db.Get(ro, []byte("key"))
+	it := db.NewIterator(ro)
+	it.SeekToFirst()
+	it.Next()
+	it.Close()
+	db.SetOpHook(nil)
+	db.Delete(wo, []byte("key"))
+
+	want := []OpInfo{
+		{Op: OpPut, KeySize: 3, ValueSize: 5},
+		{Op: OpGet, KeySize: 3, ValueSize: 5},
+		{Op: OpIteratorSeek, KeySize: 3, ValueSize: 5},
+		{Op: OpIteratorNext},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d hook calls, got %d: %v", len(want), len(ops), ops)
+	}
+	for i, op := range ops {
+		if op.Duration < 0 || op.Err != nil {
+			t.Errorf("%v: unexpected duration %v or error %v", op.Op, op.Duration, op.Err)
+		}
+		op.Duration = 0
+		if op != want[i] {
+			t.Errorf("hook call %d: expected %+v, got %+v", i, want[i], op)
+		}
+	}
+}
+
+func TestIteratorAll(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		db.Put(wo, []byte(k), []byte(k+k))
+	}
+	it := db.NewIterator(ro)
+	defer it.Close()
+	it.Seek([]byte("b"))
+	var got []string
+	for k, v := range it.All() {
+		got = append(got, string(k)+"="+string(v))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if strings.Join(got, ",") != "b=bb,c=cc" {
+		t.Errorf("unexpected pairs %v", got)
+	}
+}
+
+func TestIteratorAllError(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.CompactRange(Range{})
+
+	// Reading uncached blocks with BlockCacheTier makes the loop fail.
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetFillCache(false)
+	ro.SetReadTier(BlockCacheTier)
+	it := db.NewIterator(ro)
+	it.SeekToFirst()
+	for range it.All() {
+	}
+	it.Close()
+	if err := it.Err(); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Err after Close = %v, want ErrIncomplete", err)
+	}
+
+	// A clean loop reports no error after a deferred Close.
+	clean := func() *Iterator {
+		ro := NewReadOptions()
+		defer ro.Close()
+		it := db.NewIterator(ro)
+		defer it.Close()
+		it.SeekToFirst()
+		for range it.All() {
+		}
+		return it
+	}
+	it = clean()
+	if err := it.Err(); err != nil {
+		t.Errorf("Err after Close of a clean loop = %v, want nil", err)
+	}
+	if err := it.GetError(); err != ErrIteratorClosed {
+		t.Errorf("GetError after Close = %v, want ErrIteratorClosed", err)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{IteratorError("Corruption: block checksum mismatch"), ErrCorruption},
+		{IteratorError("IO error: No such file or directory"), ErrIO},
+		{DatabaseError("Result incomplete: no I/O allowed"), ErrIncomplete},
+		{DatabaseError("IO error: disk full"), ErrIO},
+	}
+	for _, c := range cases {
+		for _, kind := range []error{ErrCorruption, ErrIO, ErrIncomplete} {
+			if errors.Is(c.err, kind) != (kind == c.kind) {
+				t.Errorf("errors.Is(%q, %v) = %v", c.err, kind, !(kind == c.kind))
+			}
+		}
+	}
+	if errors.Is(DatabaseError("Invalid argument: x"), ErrIO) {
+		t.Errorf("unrelated error matched ErrIO")
+	}
+}
+
+func TestSnapshotStats(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	var ops []OpType
+	db.SetOpHook(func(info OpInfo) {
+		if info.Op == OpNewSnapshot || info.Op == OpReleaseSnapshot {
+			ops = append(ops, info.Op)
+		}
+	})
+
+	if stats := db.SnapshotStats(); stats != (SnapshotStats{}) {
+		t.Errorf("expected no snapshots, got %+v", stats)
+	}
+	db.Put(wo, []byte("a"), []byte("1"))
+	first := db.NewSnapshot()
+	db.Put(wo, []byte("b"), []byte("2"))
+	second := db.NewSnapshot()
+	if second.Sequence() <= first.Sequence() {
+		t.Errorf("sequence numbers should increase: %d, %d", first.Sequence(), second.Sequence())
+	}
+
+	stats := db.SnapshotStats()
+	if stats.Count != 2 || stats.OldestSequence != first.Sequence() {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	db.ReleaseSnapshot(first)
+	stats = db.SnapshotStats()
+	if stats.Count != 1 || stats.OldestSequence != second.Sequence() {
+		t.Errorf("unexpected stats after release %+v", stats)
+	}
+	db.ReleaseSnapshot(second)
+	if stats := db.SnapshotStats(); stats.Count != 0 {
+		t.Errorf("expected no snapshots, got %+v", stats)
+	}
+	want := []OpType{OpNewSnapshot, OpNewSnapshot, OpReleaseSnapshot, OpReleaseSnapshot}
+	if fmt.Sprint(ops) != fmt.Sprint(want) {
+		t.Errorf("hook saw %v, want %v", ops, want)
+	}
+}
+
+func TestCloseWithOpenIterator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	db.Put(wo, []byte("a"), []byte("1"))
+
+	closed := db.NewIterator(ro)
+	closed.Close()
+	it := db.NewIterator(ro)
+	it.SeekToFirst()
+	db.Close()
+
+	if it.Valid() {
+		t.Errorf("iterator should be invalid after its DB was closed")
+	}
+	if err := it.GetError(); err != ErrIteratorClosed {
+		t.Errorf("expected ErrIteratorClosed, got %v", err)
+	}
+	it.Seek([]byte("a"))
+	it.Next()
+	it.Prev()
+	if it.Key() != nil || it.Value() != nil || it.AppendKey(nil) != nil || it.AppendValue(nil) != nil {
+		t.Errorf("a closed iterator should have no key or value")
+	}
+	it.Close()
+	closed.Close()
+}
+
+func TestWriteAsync(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	wo.SetSync(true)
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	var pending []<-chan error
+	var batches []*WriteBatch
+	for i := 0; i < 10; i++ {
+		wb := NewWriteBatch()
+		wb.Put([]byte(fmt.Sprintf("key%d", i)), []byte("v"))
+		batches = append(batches, wb)
+		pending = append(pending, db.WriteAsync(wo, wb))
+	}
+	for i, done := range pending {
+		if err := <-done; err != nil {
+			t.Errorf("write %d failed: %v", i, err)
+		}
+		batches[i].Close()
+	}
+	for i := 0; i < 10; i++ {
+		CheckGet(t, "WriteAsync", db, ro, []byte(fmt.Sprintf("key%d", i)), []byte("v"))
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	cpDir := tempDir(t)
+	defer deleteDBDirectory(t, cpDir)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("a"), []byte("1"))
+	cp, err := db.NewCheckpoint()
+	if err != nil {
+		t.Fatalf("NewCheckpoint failed: %v", err)
+	}
+	defer cp.Close()
+	if err := cp.Create(cpDir, 1<<20); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := cp.Create(cpDir, 1<<20); err == nil {
+		t.Errorf("Create into an existing directory should fail")
+	}
+	db.Put(wo, []byte("a"), []byte("2"))
+
+	copied, err := Open(cpDir, options)
+	if err != nil {
+		t.Fatalf("Checkpoint could not be opened: %v", err)
+	}
+	defer copied.Close()
+	CheckGet(t, "checkpoint", copied, ro, []byte("a"), []byte("1"))
+}
+
+func TestDiskUsage(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("a"), make([]byte, 4096))
+	usage, err := db.DiskUsage()
+	if err != nil {
+		t.Fatalf("DiskUsage failed: %v", err)
+	}
+	if usage.WAL < 4096 || usage.LiveSST != 0 {
+		t.Errorf("expected the write in the WAL only, got %+v", usage)
+	}
+
+	db.CompactRange(Range{})
+	usage, err = db.DiskUsage()
+	if err != nil {
+		t.Fatalf("DiskUsage failed: %v", err)
+	}
+	var live int64
+	for _, f := range db.LiveFiles() {
+		live += f.Size
+	}
+	if usage.LiveSST != live || live == 0 {
+		t.Errorf("expected %d live SST bytes, got %+v", live, usage)
+	}
+	if usage.Total() < usage.LiveSST+usage.Other || usage.Other == 0 {
+		t.Errorf("inconsistent usage %+v", usage)
+	}
+}
+
+func TestOpenLocked(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+
+	_, err = Open(dbname, options)
+	if !errors.Is(err, ErrDBLocked) {
+		t.Fatalf("expected ErrDBLocked opening twice, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		db.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db, err = OpenContext(ctx, dbname, options)
+	if err != nil {
+		t.Fatalf("OpenContext should succeed once the lock is released: %v", err)
+	}
+	db.Close()
+}
+
+func TestOpenLockNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("file permissions do not apply to root")
+	}
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	db.Close()
+
+	lock := filepath.Join(dbname, "LOCK")
+	if err := os.Chmod(lock, 0444); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(lock, 0644)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = OpenContext(ctx, dbname, options)
+	if err == nil || errors.Is(err, ErrDBLocked) {
+		t.Fatalf("expected a non-lock error opening with an unwritable LOCK, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("OpenContext retried a permanent error for %v", time.Since(start))
+	}
+}
+
+func TestLockConflictMessages(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"IO error: lock hold by current process, acquire time 1700000000 acquiring thread 1: /db/LOCK: No locks available": true,
+		"IO error: While lock file: /db/LOCK: Resource temporarily unavailable":                                            true,
+		"IO error: While open a file for lock: /db/LOCK: Permission denied":                                                false,
+		"IO error: While lock file: /db/LOCK: No locks available":                                                          false,
+		"Corruption: bad record in /db/MANIFEST-000005":                                                                    false,
+	} {
+		if got := isLockConflict(msg); got != want {
+			t.Errorf("isLockConflict(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
+
+func TestCloneAtSnapshot(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	db.Put(wo, []byte("key"), []byte("before"))
+	clonedir := tempDir(t)
+	clone, err := db.CloneAtSnapshot(clonedir, options)
+	if err != nil {
+		t.Fatalf("CloneAtSnapshot failed: %v", err)
+	}
+	db.Put(wo, []byte("key"), []byte("after"))
+	CheckGet(t, "clone", clone, ro, []byte("key"), []byte("before"))
+	if err := clone.Put(wo, []byte("key"), []byte("x")); err == nil {
+		t.Errorf("writing to a clone should fail")
+	}
+	clone.Close()
+	if _, err := os.Stat(clonedir); !os.IsNotExist(err) {
+		t.Errorf("clone directory should be removed on Close, got %v", err)
+	}
+}
+
+func CheckGet(t *testing.T, where string, db *DB, roptions *ReadOptions, key, expected []byte) {
+	getValue, err := db.Get(roptions, key)
+
+	if err != nil {
+		t.Errorf("%s, Get failed: %v", where, err)
+	}
+	if !bytes.Equal(getValue, expected) {
+		t.Errorf("%s, expected Get value %v, got %v", where, expected, getValue)
+	}
+}
+
+func WBIterCheckEqual(t *testing.T, where string, which string, pos int, expected, given []byte) {
+	if !bytes.Equal(expected, given) {
+		t.Errorf("%s at pos %d, %s expected: %v, got: %v", where, pos, which, expected, given)
+	}
+}
+
+func CheckIter(t *testing.T, it *Iterator, key, value []byte) {
+	if !bytes.Equal(key, it.Key()) {
+		t.Errorf("Iterator: expected key %v, got %v", key, it.Key())
+	}
+	if !bytes.Equal(value, it.Value()) {
+		t.Errorf("Iterator: expected value %v, got %v", value, it.Value())
+	}
+}
+
+func deleteDBDirectory(t *testing.T, dirPath string) {
+	err := os.RemoveAll(dirPath)
+	if err != nil {
+		t.Errorf("Unable to remove database directory: %s", dirPath)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	bottom := fmt.Sprintf("rocksdb-test-%d", rand.Int())
+	path := filepath.Join(os.TempDir(), bottom)
+	deleteDBDirectory(t, path)
+	return path
+}
+
+func TestOpenChecked(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%03d", i)), []byte("value"))
+	}
+	db.CompactRange(Range{})
+	db.Close()
+
+	options.SetWriteBufferSize(8 << 20)
+	checks := StartupChecks{Files: true, ChecksumSampleFiles: -1, Options: true}
+	db, report, err := OpenChecked(dbname, options, checks)
+	if err != nil {
+		t.Fatalf("OpenChecked failed: %v", err)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("startup checks of a healthy database failed: %v", err)
+	}
+	if report.ChecksumFilesChecked == 0 {
+		t.Errorf("no files had their checksums verified")
+	}
+	var changed bool
+	for _, c := range report.OptionChanges {
+		if c.Name == "write_buffer_size" && c.New == "8388608" {
+			changed = !c.Incompatible
+		}
+	}
+	if !changed {
+		t.Errorf("write_buffer_size change not reported as compatible: %+v", report.OptionChanges)
+	}
+	files := db.LiveFiles()
+	db.Close()
+
+	if len(files) == 0 {
+		t.Fatalf("no live files after compaction")
+	}
+	if err := os.Remove(filepath.Join(dbname, files[0].Name)); err != nil {
+		t.Fatal(err)
+	}
+	db, report, err = OpenChecked(dbname, options, StartupChecks{Files: true})
+	if err != nil {
+		// RocksDB itself refuses to open with a missing file when it
+		// checks them at open; it must then say which one.
+		num := strings.TrimSuffix(filepath.Base(files[0].Name), ".sst")
+		if !strings.Contains(err.Error(), num) {
+			t.Errorf("open error does not name the missing file %s: %v", files[0].Name, err)
+		}
+		return
+	}
+	defer db.Close()
+	if len(report.MissingFiles) != 1 || report.Err() == nil {
+		t.Errorf("missing file not reported: %+v", report)
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "000007.sst"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "000008.sst"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []LiveFileMetadata{
+		{Name: "/000007.sst", Size: 10},
+		{Name: "/000008.sst", Size: 20},
+		{Name: "/000009.sst", Size: 10},
+	}
+	report := &StartupReport{}
+	checkFiles(dir, files, report)
+	if len(report.MissingFiles) != 1 || report.MissingFiles[0] != "/000009.sst" {
+		t.Errorf("MissingFiles = %v, want [/000009.sst]", report.MissingFiles)
+	}
+	if len(report.SizeMismatches) != 1 || report.SizeMismatches[0] != "/000008.sst" {
+		t.Errorf("SizeMismatches = %v, want [/000008.sst]", report.SizeMismatches)
+	}
+	if report.Err() == nil {
+		t.Errorf("Err should report the problems: %+v", report)
+	}
+}
+
+func TestOpenCheckedReverseComparator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetComparator(NewReverseBytewiseComparator())
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%03d", i)), []byte("value"))
+	}
+	db.CompactRange(Range{})
+	db.Close()
+
+	db, report, err := OpenChecked(dbname, options, StartupChecks{ChecksumSampleFiles: -1})
+	if err != nil {
+		t.Fatalf("OpenChecked failed: %v", err)
+	}
+	defer db.Close()
+	if err := report.Err(); err != nil {
+		t.Errorf("startup checks failed: %v", err)
+	}
+	if report.ChecksumFilesChecked == 0 {
+		t.Errorf("no files had their checksums verified")
+	}
+
+	files := []LiveFileMetadata{
+		{Name: "b", Level: 2, ColumnFamily: DefaultColumnFamilyName},
+		{Name: "a", Level: 0, ColumnFamily: DefaultColumnFamilyName},
+	}
+	cfs := map[string]*ColumnFamilyHandle{DefaultColumnFamilyName: nil}
+	db.checkFileChecksums(files, cfs, -1, options.cmp.Compare, &StartupReport{})
+	if files[0].Name != "b" {
+		t.Errorf("checkFileChecksums reordered the caller's files")
+	}
+}
+
+func TestWritePressure(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetWriteBufferSize(1 << 20)
+	options.SetMaxWriteBuffers(4)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	p := db.WritePressure()
+	if p.WriteBufferSize != 1<<20 || p.MaxWriteBuffers != 4 {
+		t.Errorf("memtable limits = %d, %d; want %d, 4", p.WriteBufferSize, p.MaxWriteBuffers, 1<<20)
+	}
+	empty := p.Level()
+	db.Put(wo, []byte("key"), make([]byte, 256<<10))
+	p = db.WritePressure()
+	if p.ActiveMemtableBytes < 256<<10 || p.Level() <= empty {
+		t.Errorf("pressure did not grow after a write: %+v", p)
+	}
+
+	if err := db.SetOptions(map[string]string{"write_buffer_size": "4194304"}); err != nil {
+		t.Fatalf("SetOptions failed: %v", err)
+	}
+	if p := db.WritePressure(); p.WriteBufferSize != 4<<20 {
+		t.Errorf("WriteBufferSize after SetOptions = %d, want %d", p.WriteBufferSize, 4<<20)
+	}
+}
+
+func TestSchemaManifest(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := db.WriteSchemaManifest(path); err != nil {
+		t.Fatalf("WriteSchemaManifest failed: %v", err)
+	}
+	db.Close()
+
+	m, err := ReadSchemaManifest(path)
+	if err != nil {
+		t.Fatalf("ReadSchemaManifest failed: %v", err)
+	}
+	if len(m.ColumnFamilies) != 1 || m.ColumnFamilies[0].Name != DefaultColumnFamilyName {
+		t.Errorf("unexpected column families %+v", m.ColumnFamilies)
+	}
+
+	merging := options.Clone()
+	defer merging.Close()
+	merging.SetMergeOperator(appendOperator{})
+	db, report, err := OpenChecked(dbname, merging, StartupChecks{Manifest: m})
+	if err != nil {
+		t.Fatalf("OpenChecked failed: %v", err)
+	}
+	defer db.Close()
+	if len(report.ManifestMismatches) != 1 || report.Err() == nil {
+		t.Errorf("merge operator change not reported: %v", report.ManifestMismatches)
+	}
+	if err := m.Validate(db); err == nil {
+		t.Errorf("Validate should fail after the merge operator changed")
+	}
+}
+
+func TestCgoStats(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	EnableCgoStats(true)
+	defer EnableCgoStats(false)
+	ResetCgoStats()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.Put(wo, []byte("b"), []byte("2"))
+	db.Get(ro, []byte("a"))
+	it := db.NewIterator(ro)
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		it.Key()
+	}
+	it.Close()
+	EnableCgoStats(false)
+	db.Get(ro, []byte("a"))
+
+	want := map[CgoCall]uint64{
+		CgoPut: 2, CgoGet: 1, CgoIteratorCreate: 1, CgoIteratorSeek: 1,
+		CgoIteratorStep: 2, CgoIteratorValid: 3, CgoIteratorKey: 2,
+	}
+	for _, s := range CgoStats() {
+		if s.Calls != want[s.Call] {
+			t.Errorf("%v: %d calls, want %d", s.Call, s.Calls, want[s.Call])
+		}
+	}
+}
+
+func TestDegraded(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.SetDegradeOnBackgroundError(true)
+	db.Put(wo, []byte("key"), []byte("value"))
+
+	if err := db.writeError("IO error: No space left on device"); errors.Is(err, ErrDegraded) {
+		t.Errorf("write error without a background error degraded the handle")
+	}
+	// Background errors cannot be provoked portably, so degrade directly.
+	db.degrade("IO error: No space left on device")
+	err = db.Put(wo, []byte("key"), []byte("other"))
+	var de *DegradedError
+	if !errors.As(err, &de) || !errors.Is(err, ErrDegraded) || de.Cause == "" {
+		t.Errorf("write to degraded handle: got %v, want a *DegradedError", err)
+	}
+	wb := NewWriteBatch()
+	defer wb.Close()
+	wb.Delete([]byte("key"))
+	if err := db.Write(wo, wb); !errors.Is(err, ErrDegraded) {
+		t.Errorf("Write to degraded handle: got %v", err)
+	}
+	CheckGet(t, "read while degraded", db, ro, []byte("key"), []byte("value"))
+
+	db.ExitDegraded()
+	if err := db.Degraded(); err != nil {
+		t.Errorf("still degraded after ExitDegraded: %v", err)
+	}
+	if err := db.Put(wo, []byte("key"), []byte("other")); err != nil {
+		t.Errorf("Put after ExitDegraded failed: %v", err)
+	}
+}
+
+func TestGetWithStats(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("1"))
+	db.Put(wo, []byte("b"), []byte("2"))
+	db.CompactRange(Range{})
+
+	v, stats, err := db.GetWithStats(ro, []byte("a"))
+	if err != nil || string(v) != "1" {
+		t.Fatalf("GetWithStats = %q, %v", v, err)
+	}
+	if stats.BlocksRead == 0 || stats.BytesRead == 0 {
+		t.Errorf("first read from the file read no blocks: %+v", stats)
+	}
+	values, stats, err := db.MultiGetWithStats(ro, [][]byte{[]byte("a"), []byte("b")})
+	if err != nil || len(values) != 2 || string(values[1]) != "2" {
+		t.Fatalf("MultiGetWithStats = %q, %v", values, err)
+	}
+	if stats.BlockCacheHits == 0 {
+		t.Errorf("second read found no blocks in the cache: %+v", stats)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	// RocksDB's limiter runs on the wall clock, so only compare against an
+	// unlimited compaction: 256KB at 1MB/s take at least 250ms, against a
+	// few milliseconds without the limit.
+	unlimited := compactionTime(t, nil)
+	rl := NewRateLimiter(1<<20, 100*time.Millisecond, 10)
+	limited := compactionTime(t, rl)
+	rl.Close()
+	if limited <= unlimited {
+		t.Errorf("rate limited compaction took %v, unlimited %v", limited, unlimited)
+	}
+}
+
+// compactionTime writes 256KB to a new database using rl, which may be nil,
+// and returns how long flushing and compacting them takes.
+func compactionTime(t *testing.T, rl *RateLimiter) time.Duration {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	if rl != nil {
+		options.SetRateLimiter(rl)
+	}
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	value := make([]byte, 1<<10)
+	rand.Read(value)
+	for i := 0; i < 256; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%05d", i)), value)
+	}
+	start := time.Now()
+	db.CompactRange(Range{})
+	d := time.Since(start)
+	CheckGet(t, "after compaction", db, ro, []byte("key00042"), value)
+	return d
+}
+
+func TestSstFileManager(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	env := NewDefaultEnv()
+	defer env.Close()
+	m := NewSstFileManager(env)
+	defer m.Close()
+	m.SetDeleteRateBytesPerSecond(1 << 20)
+	m.SetMaxTrashDBRatio(0.5)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetEnv(env)
+	options.SetSstFileManager(m)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), make([]byte, 1000))
+	db.CompactRange(Range{})
+
+	if m.TotalSize() == 0 {
+		t.Errorf("SstFileManager tracks no files after a flush")
+	}
+	if m.DeleteRateBytesPerSecond() != 1<<20 || m.MaxTrashDBRatio() != 0.5 {
+		t.Errorf("settings not applied: rate %d, ratio %v", m.DeleteRateBytesPerSecond(), m.MaxTrashDBRatio())
+	}
+	if m.IsMaxAllowedSpaceReached() {
+		t.Errorf("space limit reached without a limit")
+	}
+	m.SetMaxAllowedSpaceUsage(1)
+	if !m.IsMaxAllowedSpaceReached() || !m.IsMaxAllowedSpaceReachedIncludingCompactions() {
+		t.Errorf("space limit of 1 byte not reached")
+	}
+}
+
+func TestIterateBounds(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		db.Put(wo, []byte(k), []byte(k))
+	}
+
+	keys := func(ro *ReadOptions, backward bool) string {
+		it := db.NewIterator(ro)
+		defer it.Close()
+		var keys []string
+		if backward {
+			for it.SeekToLast(); it.Valid(); it.Prev() {
+				keys = append(keys, string(it.Key()))
+			}
+		} else {
+			for it.SeekToFirst(); it.Valid(); it.Next() {
+				keys = append(keys, string(it.Key()))
+			}
+		}
+		return strings.Join(keys, "")
+	}
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	lower, upper := []byte("b"), []byte("d")
+	ro.SetIterateLowerBound(lower)
+	ro.SetIterateUpperBound(upper)
+	lower[0], upper[0] = 'x', 'x'
+	if got := keys(ro, false); got != "bc" {
+		t.Errorf("forward within [b, d) = %q, want \"bc\"", got)
+	}
+	if got := keys(ro, true); got != "cb" {
+		t.Errorf("backward within [b, d) = %q, want \"cb\"", got)
+	}
+
+	it := db.NewIterator(ro)
+	it.Seek([]byte("a"))
+	if !it.Valid() || string(it.Key()) != "b" {
+		t.Errorf("Seek below the lower bound did not stop at the bound")
+	}
+	it.Seek([]byte("d"))
+	if it.Valid() {
+		t.Errorf("Seek to the upper bound found %q", it.Key())
+	}
+	it.Close()
+
+	// A replaced bound is copied as well, so neither reusing the caller's
+	// slice nor the garbage collector reclaiming it moves the bound.
+	buf := []byte("c")
+	ro.SetIterateLowerBound(buf)
+	buf[0] = 'a'
+	runtime.GC()
+	if got := keys(ro, false); got != "c" {
+		t.Errorf("within [c, d) after reusing the slice = %q, want \"c\"", got)
+	}
+
+	ro.SetIterateLowerBound(nil)
+	ro.SetIterateUpperBound(nil)
+	if got := keys(ro, false); got != "abcde" {
+		t.Errorf("after removing the bounds = %q, want \"abcde\"", got)
+	}
+}
+
+func TestTailingIterator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("log/1"), []byte("1"))
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetTailing(true)
+	it := db.NewIterator(ro)
+	defer it.Close()
+
+	var seen []string
+	var last []byte
+	follow := func() {
+		if last == nil {
+			it.Seek([]byte("log/"))
+		} else {
+			it.Seek(append(last, 0))
+		}
+		for ; it.Valid(); it.Next() {
+			last = it.Key()
+			seen = append(seen, string(last))
+		}
+		if err := it.GetError(); err != nil {
+			t.Fatalf("tailing iteration failed: %v", err)
+		}
+	}
+	follow()
+	db.Put(wo, []byte("log/2"), []byte("2"))
+	db.Put(wo, []byte("log/3"), []byte("3"))
+	follow()
+	follow()
+	if got := strings.Join(seen, " "); got != "log/1 log/2 log/3" {
+		t.Errorf("tailing iterator saw %q, want \"log/1 log/2 log/3\"", got)
+	}
+
+	it.SeekToLast()
+	if it.GetError() == nil {
+		t.Errorf("SeekToLast on a tailing iterator did not fail")
+	}
+}
+
+func TestPinnedIterator(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	want := []string{"a", "b", "c", "d"}
+	for _, k := range want {
+		db.Put(wo, []byte(k), []byte("v"+k))
+	}
+	db.CompactRange(Range{})
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	it := db.NewIterator(ro)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("PinnedKey without SetPinData did not panic")
+			}
+		}()
+		it.SeekToFirst()
+		it.PinnedKey()
+	}()
+	it.Close()
+
+	ro.SetPinData(true)
+	it = db.NewIterator(ro)
+	defer it.Close()
+	var keys, values [][]byte
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		keys = append(keys, it.PinnedKey())
+		values = append(values, it.PinnedValue())
+	}
+	if err := it.GetError(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(keys), len(want))
+	}
+	for i, k := range want {
+		if string(keys[i]) != k || string(values[i]) != "v"+k {
+			t.Errorf("pinned entry %d = %q=%q, want %q=%q", i, keys[i], values[i], k, "v"+k)
+		}
+	}
+}
+
+func TestBlockCacheTier(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("cold"), []byte("1"))
+	db.CompactRange(Range{})
+	db.Put(wo, []byte("hot"), []byte("2"))
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetFillCache(false)
+	ro.SetReadTier(BlockCacheTier)
+	CheckGet(t, "hot", db, ro, []byte("hot"), []byte("2"))
+	if _, err := db.Get(ro, []byte("cold")); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Get of an uncached key = %v, want ErrIncomplete", err)
+	}
+
+	ro.SetReadTier(ReadAllTier)
+	CheckGet(t, "cold", db, ro, []byte("cold"), []byte("1"))
 }
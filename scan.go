@@ -0,0 +1,247 @@
+package gorocks
+
+import (
+	"bytes"
+	"time"
+)
+
+// ScanOptions configures a Scanner.
+type ScanOptions struct {
+	// KeysOnly skips copying values out of RocksDB. Scanner.Value returns
+	// nil. Index scans and key counting jobs over large values should set
+	// it.
+	KeysOnly bool
+
+	// KeysPerSecond and BytesPerSecond, if positive, cap the rate of the
+	// scan, so that background jobs such as reindexing do not monopolize
+	// the block cache and disk bandwidth. Next sleeps as needed to stay
+	// below them, allowing bursts of up to a tenth of a second's worth.
+	// Bytes are those of the keys and values returned.
+	KeysPerSecond  float64
+	BytesPerSecond float64
+}
+
+// scanBurst is the time worth of tokens a rate-limited Scanner may spend
+// at once.
+const scanBurst = 100 * time.Millisecond
+
+// tokenBucket limits a rate by letting callers spend tokens that refill
+// continuously. Spending more than is available puts the bucket in debt,
+// which take sleeps off.
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	tokens float64
+	last   time.Time
+
+	// now and sleep are time.Now and time.Sleep outside of tests.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:   rate,
+		tokens: rate * scanBurst.Seconds(),
+		last:   time.Now(),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// take spends n tokens, sleeping until the bucket is out of debt.
+func (b *tokenBucket) take(n float64) {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if burst := b.rate * scanBurst.Seconds(); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens < 0 {
+		d := time.Duration(-b.tokens / b.rate * float64(time.Second))
+		b.sleep(d)
+		b.tokens = 0
+		b.last = now.Add(d)
+	}
+}
+
+// Scanner walks the keys of a Range in order. It is a convenience over
+// Iterator for bulk jobs: the bounds are checked for the caller, and the
+// key and value buffers are reused between steps instead of allocating a
+// copy of every key and value.
+//
+// A typical use looks like:
+//
+//	s := db.NewScanner(ro, gorocks.Range{start, limit}, gorocks.ScanOptions{KeysOnly: true})
+//	defer s.Close()
+//	for s.Next() {
+//		use(s.Key())
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// To prevent memory leaks, a Scanner must have Close called on it when it
+// is no longer needed by the program.
+type Scanner struct {
+	it      *Iterator
+	r       Range
+	opts    ScanOptions
+	started bool
+	key     []byte
+	value   []byte
+
+	keyRate, byteRate *tokenBucket
+}
+
+// NewScanner returns a Scanner over the keys from r.Start up to, but not
+// including, r.Limit. A nil Limit scans to the end of the database. The
+// limit is compared bytewise.
+func (db *DB) NewScanner(ro *ReadOptions, r Range, opts ScanOptions) *Scanner {
+	return newScanner(db.NewIterator(ro), r, opts)
+}
+
+// NewScannerCF is like NewScanner, but scans the given column family.
+func (db *DB) NewScannerCF(ro *ReadOptions, cf *ColumnFamilyHandle, r Range, opts ScanOptions) *Scanner {
+	return newScanner(db.NewIteratorCF(ro, cf), r, opts)
+}
+
+func newScanner(it *Iterator, r Range, opts ScanOptions) *Scanner {
+	return &Scanner{
+		it:       it,
+		r:        r,
+		opts:     opts,
+		keyRate:  newTokenBucket(opts.KeysPerSecond),
+		byteRate: newTokenBucket(opts.BytesPerSecond),
+	}
+}
+
+// Next advances the Scanner to the next key in the range, returning false
+// when the range is exhausted or an error occurred.
+func (s *Scanner) Next() bool {
+	if !s.started {
+		s.started = true
+		if len(s.r.Start) == 0 {
+			s.it.SeekToFirst()
+		} else {
+			s.it.Seek(s.r.Start)
+		}
+	} else if s.it.Valid() {
+		s.it.Next()
+	}
+	if !s.it.Valid() {
+		return false
+	}
+	s.key = s.it.AppendKey(s.key[:0])
+	if s.r.Limit != nil && bytes.Compare(s.key, s.r.Limit) >= 0 {
+		return false
+	}
+	if !s.opts.KeysOnly {
+		s.value = s.it.AppendValue(s.value[:0])
+	}
+	if s.keyRate != nil {
+		s.keyRate.take(1)
+	}
+	if s.byteRate != nil {
+		s.byteRate.take(float64(len(s.key) + len(s.Value())))
+	}
+	return true
+}
+
+// Key returns the current key. The slice is only valid until the next call
+// to Next.
+func (s *Scanner) Key() []byte {
+	return s.key
+}
+
+// Value returns the current value, or nil in KeysOnly mode. The slice is
+// only valid until the next call to Next.
+func (s *Scanner) Value() []byte {
+	if s.opts.KeysOnly {
+		return nil
+	}
+	return s.value
+}
+
+// Err returns the error that stopped the scan, if any.
+func (s *Scanner) Err() error {
+	return s.it.GetError()
+}
+
+// Close releases the underlying Iterator.
+func (s *Scanner) Close() {
+	s.it.Close()
+}
+
+// DefaultDeleteBatchKeys is the number of deletes DeleteWhere writes per
+// WriteBatch unless told otherwise.
+const DefaultDeleteBatchKeys = 1000
+
+// DeleteWhereOptions configures DB.DeleteWhere.
+type DeleteWhereOptions struct {
+	// Scan configures the scan of the range, for example to rate-limit it
+	// or to skip reading values the predicate does not need.
+	Scan ScanOptions
+	// BatchKeys is the maximum number of deletes written in one WriteBatch.
+	// Zero means DefaultDeleteBatchKeys.
+	BatchKeys int
+	// Progress, if not nil, is called after every batch is written, with
+	// the number of keys scanned and deleted so far.
+	Progress func(scanned, deleted uint64)
+}
+
+// DeleteWhere deletes the keys in r for which pred returns true, for
+// cleanup jobs that cannot be expressed as a single DeleteRange. The range
+// is scanned as by NewScanner, and the deletes are written in batches of
+// at most opts.BatchKeys keys, so the job neither builds one huge batch nor
+// pays for a write per key. The key and value passed to pred are only
+// valid during the call.
+//
+// Keys are deleted as they are found, so if DeleteWhere fails, the batches
+// written up to then stay deleted. It returns the number of keys deleted.
+func (db *DB) DeleteWhere(ro *ReadOptions, wo *WriteOptions, r Range, pred func(key, value []byte) bool, opts DeleteWhereOptions) (uint64, error) {
+	batchKeys := opts.BatchKeys
+	if batchKeys <= 0 {
+		batchKeys = DefaultDeleteBatchKeys
+	}
+	s := db.NewScanner(ro, r, opts.Scan)
+	defer s.Close()
+	wb := NewWriteBatch()
+	defer wb.Close()
+
+	var scanned, deleted uint64
+	flush := func() error {
+		n := wb.Count()
+		if n == 0 {
+			return nil
+		}
+		if err := db.Write(wo, wb); err != nil {
+			return err
+		}
+		wb.Clear()
+		deleted += uint64(n)
+		if opts.Progress != nil {
+			opts.Progress(scanned, deleted)
+		}
+		return nil
+	}
+	for s.Next() {
+		scanned++
+		if !pred(s.Key(), s.Value()) {
+			continue
+		}
+		wb.Delete(s.Key())
+		if wb.Count() >= batchKeys {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
@@ -0,0 +1,285 @@
+package gorocks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestScanner(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		db.Put(wo, []byte(k), []byte("value of "+k))
+	}
+
+	s := db.NewScanner(ro, Range{[]byte("b"), []byte("d")}, ScanOptions{KeysOnly: true})
+	var keys []string
+	for s.Next() {
+		keys = append(keys, string(s.Key()))
+		if s.Value() != nil {
+			t.Errorf("KeysOnly scanner returned value %q", s.Value())
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("scan failed: %v", err)
+	}
+	s.Close()
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Errorf("expected keys [b c], got %v", keys)
+	}
+
+	s = db.NewScanner(ro, Range{}, ScanOptions{})
+	defer s.Close()
+	var n int
+	for s.Next() {
+		if string(s.Value()) != "value of "+string(s.Key()) {
+			t.Errorf("unexpected value %q for key %q", s.Value(), s.Key())
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("expected 4 keys in unbounded scan, got %d", n)
+	}
+}
+
+func TestScannerRateLimit(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 30; i++ {
+		db.Put(wo, []byte{byte(i)}, []byte("v"))
+	}
+
+	// The limit only delays the scan; TestTokenBucket checks the delays.
+	s := db.NewScanner(ro, Range{}, ScanOptions{KeysPerSecond: 1000})
+	defer s.Close()
+	var n int
+	for s.Next() {
+		n++
+	}
+	if n != 30 {
+		t.Errorf("expected 30 keys, got %d", n)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var slept time.Duration
+	b := newTokenBucket(100)
+	b.last = now
+	b.now = func() time.Time { return now }
+	b.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+
+	// 100 tokens per second allow a burst of 10; the other 20 take 200ms.
+	for i := 0; i < 30; i++ {
+		b.take(1)
+	}
+	if slept != 200*time.Millisecond {
+		t.Errorf("30 tokens at 100/s slept %v, want 200ms", slept)
+	}
+
+	// Idle time refills the bucket, but only up to the burst.
+	now = now.Add(time.Hour)
+	slept = 0
+	for i := 0; i < 10; i++ {
+		b.take(1)
+	}
+	if slept != 0 {
+		t.Errorf("a burst after idling slept %v", slept)
+	}
+	b.take(1)
+	if slept != 10*time.Millisecond {
+		t.Errorf("a token past the burst slept %v, want 10ms", slept)
+	}
+
+	if newTokenBucket(0) != nil {
+		t.Errorf("a zero rate should not be limited")
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		value := "keep"
+		if i%2 == 0 {
+			value = "expired"
+		}
+		db.Put(wo, []byte{'k', byte(i)}, []byte(value))
+	}
+	db.Put(wo, []byte("z"), []byte("expired"))
+
+	var batches int
+	deleted, err := db.DeleteWhere(ro, wo, Range{[]byte("k"), []byte("l")},
+		func(key, value []byte) bool { return string(value) == "expired" },
+		DeleteWhereOptions{BatchKeys: 10, Progress: func(scanned, deleted uint64) { batches++ }})
+	if err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if deleted != 50 || batches != 5 {
+		t.Errorf("deleted %d keys in %d batches, want 50 in 5", deleted, batches)
+	}
+	CheckGet(t, "deleted", db, ro, []byte{'k', 2}, nil)
+	CheckGet(t, "kept", db, ro, []byte{'k', 3}, []byte("keep"))
+	CheckGet(t, "outside range", db, ro, []byte("z"), []byte("expired"))
+}
+
+func TestCountRange(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	value := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		prefix := "a"
+		if i%4 == 0 {
+			prefix = "b"
+		}
+		db.Put(wo, []byte(fmt.Sprintf("%s%04d", prefix, i)), value)
+	}
+	b := Range{[]byte("b"), []byte("c")}
+
+	n, err := db.CountRange(ro, b)
+	if err != nil || n != 250 {
+		t.Errorf("CountRange = %d, %v; want 250", n, err)
+	}
+	if n, err := db.ApproxCountRange(b); err != nil || n != 250 {
+		t.Errorf("ApproxCountRange before flush = %d, %v; want exactly 250", n, err)
+	}
+	db.CompactRange(Range{})
+	n, err = db.ApproxCountRange(b)
+	if err != nil || n < 125 || n > 500 {
+		t.Errorf("ApproxCountRange = %d, %v; want about 250", n, err)
+	}
+
+	// Keys still in the memtable are counted exactly on top of the files.
+	for i := 0; i < 100; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("b%04d", 1000+i)), value)
+	}
+	if m, err := db.ApproxCountRange(b); err != nil || m != n+100 {
+		t.Errorf("ApproxCountRange with memtable keys = %d, %v; want %d", m, err, n+100)
+	}
+	if m, err := db.ApproxCountRange(Range{[]byte("z"), nil}); err != nil || m != 0 {
+		t.Errorf("ApproxCountRange past the last key = %d, %v; want 0", m, err)
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	cache := NewLRUCache(8 << 20)
+	defer cache.Close()
+	options.SetCache(cache)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%04d", i)), make([]byte, 100))
+	}
+	db.CompactRange(Range{})
+
+	var lastRanges int
+	var lastKeys uint64
+	ranges := []Range{{[]byte("key0"), []byte("key05")}, {[]byte("key09"), nil}}
+	err = db.WarmCache(ranges, func(rangesDone int, keys uint64) {
+		lastRanges, lastKeys = rangesDone, keys
+	})
+	if err != nil {
+		t.Fatalf("WarmCache failed: %v", err)
+	}
+	if lastRanges != 2 || lastKeys != 600 {
+		t.Errorf("final progress %d ranges, %d keys; want 2, 600", lastRanges, lastKeys)
+	}
+}
+
+func TestReadaheadAndAsyncIO(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		db.Put(wo, []byte(fmt.Sprintf("key%04d", i)), make([]byte, 100))
+	}
+	db.CompactRange(Range{})
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetReadaheadSize(1 << 20)
+	ro.SetAsyncIO(true)
+	it := db.NewIterator(ro)
+	defer it.Close()
+	var n int
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		n++
+	}
+	if err := it.GetError(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if n != 1000 {
+		t.Errorf("iterated %d keys, want 1000", n)
+	}
+}
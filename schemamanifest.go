@@ -0,0 +1,131 @@
+package gorocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ColumnFamilySchema records the options of a column family that decide
+// how its existing data is interpreted.
+type ColumnFamilySchema struct {
+	Name          string `json:"name"`
+	Comparator    string `json:"comparator"`
+	MergeOperator string `json:"merge_operator"`
+}
+
+// SchemaManifest is a snapshot of the effective options of a database: its
+// column families with their comparator and merge operator names, and the
+// OPTIONS file they were taken from. Written next to a database, or kept
+// with the program that uses it, it catches mistakes such as opening the
+// database with the wrong comparator before they corrupt data.
+type SchemaManifest struct {
+	ColumnFamilies []ColumnFamilySchema `json:"column_families"`
+	OptionsFile    string               `json:"options_file"`
+}
+
+// SchemaManifest returns the manifest of the options the database is
+// currently open with.
+func (db *DB) SchemaManifest() (*SchemaManifest, error) {
+	contents, err := db.OptionsFile()
+	if err != nil {
+		return nil, err
+	}
+	return newSchemaManifest(contents), nil
+}
+
+func newSchemaManifest(optionsFile string) *SchemaManifest {
+	m := &SchemaManifest{OptionsFile: optionsFile}
+	opts := parseOptionsFile(optionsFile)
+	for key := range opts {
+		section, name, _ := strings.Cut(key, "\t")
+		if name != "comparator" || !strings.HasPrefix(section, "CFOptions ") {
+			continue
+		}
+		cf := strings.Trim(strings.TrimPrefix(section, "CFOptions "), `"`)
+		m.ColumnFamilies = append(m.ColumnFamilies, ColumnFamilySchema{
+			Name:          cf,
+			Comparator:    opts[section+"\tcomparator"],
+			MergeOperator: opts[section+"\tmerge_operator"],
+		})
+	}
+	sort.Slice(m.ColumnFamilies, func(i, j int) bool {
+		return m.ColumnFamilies[i].Name < m.ColumnFamilies[j].Name
+	})
+	return m
+}
+
+// WriteSchemaManifest writes the manifest of the options the database is
+// currently open with to path, as JSON.
+func (db *DB) WriteSchemaManifest(path string) error {
+	m, err := db.SchemaManifest()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadSchemaManifest reads a manifest written by WriteSchemaManifest.
+func ReadSchemaManifest(path string) (*SchemaManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &SchemaManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("gorocks: reading schema manifest %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Validate checks that the database has the column families of the
+// manifest, with the same comparators and merge operators. It returns an
+// error describing every mismatch, or nil. Column families created since
+// the manifest was written are not an error.
+func (m *SchemaManifest) Validate(db *DB) error {
+	now, err := db.SchemaManifest()
+	if err != nil {
+		return err
+	}
+	problems := m.mismatches(now)
+	if len(problems) == 0 {
+		return nil
+	}
+	return DatabaseError("schema manifest mismatch: " + strings.Join(problems, "; "))
+}
+
+func (m *SchemaManifest) mismatches(now *SchemaManifest) []string {
+	current := make(map[string]ColumnFamilySchema, len(now.ColumnFamilies))
+	for _, cf := range now.ColumnFamilies {
+		current[cf.Name] = cf
+	}
+	var problems []string
+	for _, want := range m.ColumnFamilies {
+		got, ok := current[want.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column family %q is missing", want.Name))
+		case got.Comparator != want.Comparator:
+			problems = append(problems, fmt.Sprintf("column family %q has comparator %s, want %s", want.Name, got.Comparator, want.Comparator))
+		case got.MergeOperator != want.MergeOperator:
+			problems = append(problems, fmt.Sprintf("column family %q has merge operator %s, want %s", want.Name, got.MergeOperator, want.MergeOperator))
+		}
+	}
+	return problems
+}
+
+// OptionChanges lists the options that differ between the OPTIONS file
+// recorded in the manifest and the one the database is open with.
+func (m *SchemaManifest) OptionChanges(db *DB) ([]OptionChange, error) {
+	now, err := db.OptionsFile()
+	if err != nil {
+		return nil, err
+	}
+	return diffOptions(parseOptionsFile(m.OptionsFile), parseOptionsFile(now)), nil
+}
@@ -0,0 +1,48 @@
+package gorocks
+
+import (
+	"encoding/binary"
+)
+
+// schemaTagMagic starts the LogData blobs written by PutSchemaTag, so that
+// they can be told apart from other LogData.
+const schemaTagMagic = "gorocks-schema\x00"
+
+// SchemaTag names the encoding of the keys and values that follow it in a
+// WriteBatch, such as the name of a record type and the version of its
+// serialization format. Tags are written as LogData, so they take up no
+// space in the database but travel with the batch to whoever decodes it
+// from a WriteHook or the write ahead log, letting consumers interpret
+// values written by newer or older versions of a program correctly.
+type SchemaTag struct {
+	Name    string
+	Version uint32
+}
+
+// PutSchemaTag adds tag to the WriteBatch. It applies to the records added
+// after it, up to the next tag. WriteBatchIterator.Schema reports it.
+func (w *WriteBatch) PutSchemaTag(tag SchemaTag) {
+	w.PutLogData(EncodeSchemaTag(tag))
+}
+
+// EncodeSchemaTag returns the LogData blob PutSchemaTag writes for tag.
+func EncodeSchemaTag(tag SchemaTag) []byte {
+	b := make([]byte, 0, len(schemaTagMagic)+binary.MaxVarintLen32+len(tag.Name))
+	b = append(b, schemaTagMagic...)
+	b = binary.AppendUvarint(b, uint64(tag.Version))
+	return append(b, tag.Name...)
+}
+
+// DecodeSchemaTag decodes a LogData blob written by PutSchemaTag. ok is
+// false if blob is some other LogData.
+func DecodeSchemaTag(blob []byte) (tag SchemaTag, ok bool) {
+	if len(blob) < len(schemaTagMagic) || string(blob[:len(schemaTagMagic)]) != schemaTagMagic {
+		return SchemaTag{}, false
+	}
+	blob = blob[len(schemaTagMagic):]
+	v, n := binary.Uvarint(blob)
+	if n <= 0 || v > 1<<32-1 {
+		return SchemaTag{}, false
+	}
+	return SchemaTag{Name: string(blob[n:]), Version: uint32(v)}, true
+}
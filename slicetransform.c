@@ -0,0 +1,83 @@
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+#include "_cgo_export.h"
+
+/* Slice transforms implemented in Go. The state is a cgo.Handle of the
+   goSliceTransform; the callbacks forward to the exported Go functions in
+   slicetransform.go. */
+
+static void gorocks_slicetransform_destructor(void* state) {
+	gorocksSliceTransformDestroy((uintptr_t)state);
+}
+
+static char* gorocks_slicetransform_transform(void* state,
+		const char* key, size_t length, size_t* dst_length) {
+	return gorocksSliceTransformTransform((uintptr_t)state,
+		(char*)key, length, dst_length);
+}
+
+static unsigned char gorocks_slicetransform_in_domain(void* state,
+		const char* key, size_t length) {
+	return gorocksSliceTransformInDomain((uintptr_t)state, (char*)key, length);
+}
+
+static unsigned char gorocks_slicetransform_in_range(void* state,
+		const char* key, size_t length) {
+	return 0;
+}
+
+static const char* gorocks_slicetransform_name(void* state) {
+	return gorocksSliceTransformName((uintptr_t)state);
+}
+
+rocksdb_slicetransform_t* gorocks_slicetransform_create(uintptr_t state) {
+	return rocksdb_slicetransform_create((void*)state,
+		gorocks_slicetransform_destructor,
+		gorocks_slicetransform_transform,
+		gorocks_slicetransform_in_domain,
+		gorocks_slicetransform_in_range,
+		gorocks_slicetransform_name);
+}
+
+/* The capped prefix transform, implemented in C to avoid calling into Go
+   for every key. The prefix is the first cap bytes of the key, or the
+   whole key if it is shorter. */
+
+typedef struct {
+	size_t cap;
+	char name[48];
+} gorocks_capped_prefix;
+
+static void gorocks_capped_prefix_destructor(void* state) {
+	free(state);
+}
+
+static char* gorocks_capped_prefix_transform(void* state,
+		const char* key, size_t length, size_t* dst_length) {
+	size_t cap = ((gorocks_capped_prefix*)state)->cap;
+	*dst_length = length < cap ? length : cap;
+	return (char*)key;
+}
+
+static unsigned char gorocks_capped_prefix_in_domain(void* state,
+		const char* key, size_t length) {
+	return 1;
+}
+
+static const char* gorocks_capped_prefix_name(void* state) {
+	return ((gorocks_capped_prefix*)state)->name;
+}
+
+rocksdb_slicetransform_t* gorocks_slicetransform_create_capped_prefix(size_t cap) {
+	gorocks_capped_prefix* state = malloc(sizeof(gorocks_capped_prefix));
+	state->cap = cap;
+	snprintf(state->name, sizeof(state->name), "rocksdb.CappedPrefix.%zu", cap);
+	return rocksdb_slicetransform_create(state,
+		gorocks_capped_prefix_destructor,
+		gorocks_capped_prefix_transform,
+		gorocks_capped_prefix_in_domain,
+		gorocks_slicetransform_in_range,
+		gorocks_capped_prefix_name);
+}
@@ -0,0 +1,133 @@
+package gorocks
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern rocksdb_slicetransform_t* gorocks_slicetransform_create(uintptr_t state);
+extern rocksdb_slicetransform_t* gorocks_slicetransform_create_capped_prefix(size_t cap);
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// SliceTransform extracts the prefix of keys, which prefix filters are
+// built over and prefix seeks are confined to. It is installed with
+// Options.SetPrefixExtractor.
+//
+// The methods are called from RocksDB's threads, for most reads and writes,
+// and must be safe for concurrent use. A panic in a method crashes the
+// program. NewFixedPrefixTransform and NewCappedPrefixTransform run in C
+// and are much cheaper than a transform implemented in Go.
+type SliceTransform interface {
+	// Name identifies the transform. It is recorded with the filters, which
+	// are ignored when the database is opened with a transform of a
+	// different name.
+	Name() string
+
+	// Transform returns the prefix of key, which must be a slice of key
+	// itself, usually key[:n]. It is only called for keys InDomain accepts.
+	Transform(key []byte) []byte
+
+	// InDomain reports whether key has a prefix. Keys without one are never
+	// skipped by prefix filters.
+	InDomain(key []byte) bool
+}
+
+// NewFixedPrefixTransform returns a SliceTransform whose prefix is the
+// first n bytes of the key. Keys shorter than n bytes have no prefix.
+func NewFixedPrefixTransform(n int) SliceTransform {
+	return fixedPrefixTransform(n)
+}
+
+type fixedPrefixTransform int
+
+func (t fixedPrefixTransform) Name() string { return fmt.Sprintf("rocksdb.FixedPrefix.%d", int(t)) }
+
+func (t fixedPrefixTransform) Transform(key []byte) []byte { return key[:t] }
+
+func (t fixedPrefixTransform) InDomain(key []byte) bool { return len(key) >= int(t) }
+
+// NewCappedPrefixTransform returns a SliceTransform whose prefix is the
+// first n bytes of the key, or the whole key if it is shorter. Unlike
+// NewFixedPrefixTransform, it lets short keys benefit from prefix filters
+// too, which suits variable-length key prefixes up to n bytes.
+func NewCappedPrefixTransform(n int) SliceTransform {
+	return cappedPrefixTransform(n)
+}
+
+type cappedPrefixTransform int
+
+func (t cappedPrefixTransform) Name() string { return fmt.Sprintf("rocksdb.CappedPrefix.%d", int(t)) }
+
+func (t cappedPrefixTransform) Transform(key []byte) []byte {
+	if len(key) > int(t) {
+		return key[:t]
+	}
+	return key
+}
+
+func (t cappedPrefixTransform) InDomain(key []byte) bool { return true }
+
+// goSliceTransform is the state of a SliceTransform handed to RocksDB.
+type goSliceTransform struct {
+	st   SliceTransform
+	name *C.char
+}
+
+// SetPrefixExtractor sets the SliceTransform that extracts key prefixes.
+// See SetPrefixExtractorFixed for how prefixes affect reads.
+func (o *Options) SetPrefixExtractor(st SliceTransform) {
+	var cst *C.rocksdb_slicetransform_t
+	switch t := st.(type) {
+	case fixedPrefixTransform:
+		cst = C.rocksdb_slicetransform_create_fixed_prefix(C.size_t(t))
+	case cappedPrefixTransform:
+		cst = C.gorocks_slicetransform_create_capped_prefix(C.size_t(t))
+	default:
+		state := &goSliceTransform{st: st, name: C.CString(st.Name())}
+		cst = C.gorocks_slicetransform_create(C.uintptr_t(cgo.NewHandle(state)))
+	}
+	C.rocksdb_options_set_prefix_extractor(o.Opt, cst)
+}
+
+func sliceTransformState(state C.uintptr_t) *goSliceTransform {
+	return cgo.Handle(state).Value().(*goSliceTransform)
+}
+
+//export gorocksSliceTransformTransform
+func gorocksSliceTransformTransform(state C.uintptr_t, key *C.char, length C.size_t, dstLength *C.size_t) *C.char {
+	k := cBytes(key, length)
+	prefix := sliceTransformState(state).st.Transform(k)
+	*dstLength = C.size_t(len(prefix))
+	if len(prefix) == 0 {
+		return key
+	}
+	off := uintptr(unsafe.Pointer(&prefix[0])) - uintptr(unsafe.Pointer(key))
+	if off > uintptr(len(k)) || off+uintptr(len(prefix)) > uintptr(len(k)) {
+		panic("gorocks: SliceTransform.Transform must return a slice of its argument")
+	}
+	return (*C.char)(unsafe.Add(unsafe.Pointer(key), off))
+}
+
+//export gorocksSliceTransformInDomain
+func gorocksSliceTransformInDomain(state C.uintptr_t, key *C.char, length C.size_t) C.uchar {
+	return boolToUchar(sliceTransformState(state).st.InDomain(cBytes(key, length)))
+}
+
+//export gorocksSliceTransformName
+func gorocksSliceTransformName(state C.uintptr_t) *C.char {
+	return sliceTransformState(state).name
+}
+
+//export gorocksSliceTransformDestroy
+func gorocksSliceTransformDestroy(state C.uintptr_t) {
+	h := cgo.Handle(state)
+	C.free(unsafe.Pointer(h.Value().(*goSliceTransform).name))
+	h.Delete()
+}
@@ -0,0 +1,72 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"sync"
+	"time"
+)
+
+// SnapshotStats describes the Snapshots held through a DB handle. Every
+// live Snapshot keeps the data it sees from being compacted away, so a
+// forgotten one silently makes the database grow.
+type SnapshotStats struct {
+	// Count is the number of Snapshots not yet released.
+	Count int
+	// OldestSequence is the sequence number of the oldest live Snapshot,
+	// and OldestAge how long ago it was created. Both are zero if Count is.
+	OldestSequence uint64
+	OldestAge      time.Duration
+}
+
+type snapshotRegistry struct {
+	mu   sync.Mutex
+	live map[*Snapshot]struct{}
+}
+
+func (r *snapshotRegistry) add(s *Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.live == nil {
+		r.live = make(map[*Snapshot]struct{})
+	}
+	r.live[s] = struct{}{}
+}
+
+func (r *snapshotRegistry) remove(s *Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.live, s)
+}
+
+// Sequence returns the sequence number of the last write the Snapshot
+// sees.
+func (s *Snapshot) Sequence() uint64 {
+	return s.seq
+}
+
+// Created returns the time the Snapshot was created.
+func (s *Snapshot) Created() time.Time {
+	return s.created
+}
+
+// SnapshotStats returns statistics about the Snapshots created with
+// NewSnapshot on this handle and not yet released.
+func (db *DB) SnapshotStats() SnapshotStats {
+	r := &db.snapshots
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := SnapshotStats{Count: len(r.live)}
+	var oldest *Snapshot
+	for s := range r.live {
+		if oldest == nil || s.seq < oldest.seq {
+			oldest = s
+		}
+	}
+	if oldest != nil {
+		stats.OldestSequence = oldest.seq
+		stats.OldestAge = time.Since(oldest.created)
+	}
+	return stats
+}
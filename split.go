@@ -0,0 +1,87 @@
+package gorocks
+
+import (
+	"bytes"
+	"sort"
+)
+
+// SuggestSplitKeys returns up to n-1 keys that divide the keys from start up
+// to, but not including, end into n pieces of roughly equal on-disk size. A
+// nil end extends the range to the last key in the database.
+//
+// The candidate split points are the boundaries of the live SST files of
+// the default column family, and their sizes are estimated with
+// GetApproximateSizes, so data still in the memtable is not taken into
+// account and the result is only as fine-grained as the files are. Fewer
+// keys are returned when there are not enough files in the range to tell
+// the pieces apart.
+//
+// Like ApproxCountRange, SuggestSplitKeys assumes the default bytewise key
+// order; under another comparator the keys it returns are meaningless.
+func (db *DB) SuggestSplitKeys(start, end []byte, n int) [][]byte {
+	if n < 2 {
+		return nil
+	}
+	inRange := func(k []byte) bool {
+		return bytes.Compare(k, start) > 0 && (end == nil || bytes.Compare(k, end) < 0)
+	}
+
+	var candidates [][]byte
+	var last []byte
+	for _, f := range db.LiveFiles() {
+		if f.ColumnFamily != DefaultColumnFamilyName {
+			continue
+		}
+		for _, k := range [][]byte{f.SmallestKey, f.LargestKey} {
+			if inRange(k) {
+				candidates = append(candidates, k)
+			}
+		}
+		if last == nil || bytes.Compare(f.LargestKey, last) > 0 {
+			last = f.LargestKey
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return bytes.Compare(candidates[i], candidates[j]) < 0
+	})
+	uniq := candidates[:1]
+	for _, k := range candidates[1:] {
+		if !bytes.Equal(k, uniq[len(uniq)-1]) {
+			uniq = append(uniq, k)
+		}
+	}
+	candidates = uniq
+
+	if end == nil {
+		// The smallest key sorting after last under the bytewise order.
+		end = append(append([]byte{}, last...), 0)
+	}
+	ranges := make([]Range, len(candidates)+1)
+	for i, k := range candidates {
+		ranges[i] = Range{start, k}
+	}
+	ranges[len(candidates)] = Range{start, end}
+	sizes := db.GetApproximateSizes(ranges)
+	total := sizes[len(candidates)]
+	if total == 0 {
+		return nil
+	}
+
+	var splits [][]byte
+	c := 0
+	for i := 1; i < n && c < len(candidates); i++ {
+		target := total * uint64(i) / uint64(n)
+		for c < len(candidates) && sizes[c] < target {
+			c++
+		}
+		if c == len(candidates) {
+			break
+		}
+		splits = append(splits, candidates[c])
+		c++
+	}
+	return splits
+}
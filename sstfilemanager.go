@@ -0,0 +1,94 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+// SstFileManager tracks the SST files of the databases it is installed on
+// with Options.SetSstFileManager, and controls two things about them: how
+// much space they may take up, and how fast obsolete files are deleted.
+// Rate limiting deletions keeps the bursts of deletes after big compactions
+// from saturating the disk, since deleting a file takes I/O on many file
+// systems. It may be shared between databases.
+//
+// To prevent memory leaks, an SstFileManager must have Close called on it
+// when it is no longer needed by the program.
+type SstFileManager struct {
+	Manager *C.rocksdb_sst_file_manager_t
+}
+
+// NewSstFileManager creates an SstFileManager that works through env, which
+// must be the Env of the databases it is installed on, with no space limit
+// and no deletion rate limit.
+func NewSstFileManager(env *Env) *SstFileManager {
+	return &SstFileManager{C.rocksdb_sst_file_manager_create(env.Env)}
+}
+
+// Close deallocates the SstFileManager. Databases using it keep their own
+// reference.
+func (m *SstFileManager) Close() {
+	C.rocksdb_sst_file_manager_destroy(m.Manager)
+}
+
+// SetMaxAllowedSpaceUsage caps the total size of the SST files. Once it is
+// reached, flushes and compactions fail, which puts the databases in read
+// only mode with a background error, instead of filling up the volume.
+// Zero, the default, means no limit.
+func (m *SstFileManager) SetMaxAllowedSpaceUsage(bytes uint64) {
+	C.rocksdb_sst_file_manager_set_max_allowed_space_usage(m.Manager, C.uint64_t(bytes))
+}
+
+// SetCompactionBufferSize reserves space for the output of compactions:
+// compactions are not started unless the SST files and the reservation fit
+// in the maximum allowed space.
+func (m *SstFileManager) SetCompactionBufferSize(bytes uint64) {
+	C.rocksdb_sst_file_manager_set_compaction_buffer_size(m.Manager, C.uint64_t(bytes))
+}
+
+// IsMaxAllowedSpaceReached reports whether the SST files have reached the
+// maximum allowed space.
+func (m *SstFileManager) IsMaxAllowedSpaceReached() bool {
+	return ucharToBool(C.rocksdb_sst_file_manager_is_max_allowed_space_reached(m.Manager))
+}
+
+// IsMaxAllowedSpaceReachedIncludingCompactions is like
+// IsMaxAllowedSpaceReached, but also counts the space reserved for running
+// compactions.
+func (m *SstFileManager) IsMaxAllowedSpaceReachedIncludingCompactions() bool {
+	return ucharToBool(C.rocksdb_sst_file_manager_is_max_allowed_space_reached_including_compactions(m.Manager))
+}
+
+// TotalSize returns the total size of the SST files tracked.
+func (m *SstFileManager) TotalSize() uint64 {
+	return uint64(C.rocksdb_sst_file_manager_get_total_size(m.Manager))
+}
+
+// DeleteRateBytesPerSecond returns the deletion rate limit, or 0 if
+// deletions are not rate limited.
+func (m *SstFileManager) DeleteRateBytesPerSecond() int64 {
+	return int64(C.rocksdb_sst_file_manager_get_delete_rate_bytes_per_second(m.Manager))
+}
+
+// SetDeleteRateBytesPerSecond limits how fast obsolete SST files are
+// deleted. Files are first moved to trash and then deleted in the
+// background at this rate. Zero disables rate limiting.
+func (m *SstFileManager) SetDeleteRateBytesPerSecond(rate int64) {
+	C.rocksdb_sst_file_manager_set_delete_rate_bytes_per_second(m.Manager, C.int64_t(rate))
+}
+
+// MaxTrashDBRatio returns the trash to database size ratio above which
+// files are deleted immediately.
+func (m *SstFileManager) MaxTrashDBRatio() float64 {
+	return float64(C.rocksdb_sst_file_manager_get_max_trash_db_ratio(m.Manager))
+}
+
+// SetMaxTrashDBRatio sets the ratio of trash to live database size above
+// which files are deleted immediately regardless of the deletion rate, so
+// that trash cannot pile up without bound. The default is 0.25.
+func (m *SstFileManager) SetMaxTrashDBRatio(ratio float64) {
+	C.rocksdb_sst_file_manager_set_max_trash_db_ratio(m.Manager, C.double(ratio))
+}
+
+// TotalTrashSize returns the size of the files waiting to be deleted.
+func (m *SstFileManager) TotalTrashSize() uint64 {
+	return uint64(C.rocksdb_sst_file_manager_get_total_trash_size(m.Manager))
+}
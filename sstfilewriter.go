@@ -0,0 +1,135 @@
+package gorocks
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"unsafe"
+)
+
+// SstFileWriter builds an SST file outside of any database, for bulk loads
+// that are later added to a database with IngestExternalFile instead of
+// going through the write path. Keys must be added in increasing order, as
+// defined by the comparator of the Options the writer was created with,
+// which must match that of the database the file is ingested into.
+//
+// To prevent memory leaks, Close must be called on an SstFileWriter when
+// the program no longer needs it.
+type SstFileWriter struct {
+	Writer *C.rocksdb_sstfilewriter_t
+
+	// envOpts is owned by the SstFileWriter and freed by Close.
+	envOpts *C.rocksdb_envoptions_t
+}
+
+// NewSstFileWriter returns an SstFileWriter producing files for databases
+// opened with o. The writer does not retain o.
+func NewSstFileWriter(o *Options) *SstFileWriter {
+	envOpts := C.rocksdb_envoptions_create()
+	w := C.rocksdb_sstfilewriter_create(envOpts, o.Opt)
+	return &SstFileWriter{Writer: w, envOpts: envOpts}
+}
+
+// Open starts a new SST file at path. A writer can produce several files
+// one after another, each started with Open and completed with Finish.
+func (w *SstFileWriter) Open(path string) error {
+	var errStr *C.char
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	C.rocksdb_sstfilewriter_open(w.Writer, cpath, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Put adds a key-value pair to the file. key must sort after every key
+// added before it.
+func (w *SstFileWriter) Put(key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_sstfilewriter_put(w.Writer, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Merge adds a merge operand for key to the file, to be combined by the
+// database's merge operator. key must sort after every key added before it.
+func (w *SstFileWriter) Merge(key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_sstfilewriter_merge(w.Writer, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Delete adds a deletion marker for key to the file. key must sort after
+// every key added before it.
+func (w *SstFileWriter) Delete(key []byte) error {
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	C.rocksdb_sstfilewriter_delete(w.Writer, k, C.size_t(len(key)), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Finish completes the file started by Open. Finish fails if no entries
+// were added.
+func (w *SstFileWriter) Finish() error {
+	var errStr *C.char
+	C.rocksdb_sstfilewriter_finish(w.Writer, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// FileSize returns the size of the file being written so far.
+func (w *SstFileWriter) FileSize() uint64 {
+	var size C.uint64_t
+	C.rocksdb_sstfilewriter_file_size(w.Writer, &size)
+	return uint64(size)
+}
+
+// Close deallocates the SstFileWriter, freeing its underlying C structs. A
+// file that was opened but not finished is abandoned.
+func (w *SstFileWriter) Close() {
+	C.rocksdb_sstfilewriter_destroy(w.Writer)
+	C.rocksdb_envoptions_destroy(w.envOpts)
+}
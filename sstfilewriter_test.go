@@ -0,0 +1,209 @@
+package gorocks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSstFileWriter(t *testing.T) {
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	defer options.Close()
+
+	w := NewSstFileWriter(options)
+	defer w.Close()
+	path := filepath.Join(dir, "bulk.sst")
+	if err := w.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := w.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := w.Delete([]byte("b")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := w.Put([]byte("a"), []byte("2")); err == nil {
+		t.Errorf("Put of an out of order key should fail")
+	}
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("SST file was not written: %v", err)
+	}
+	if uint64(info.Size()) != w.FileSize() {
+		t.Errorf("FileSize %d does not match the file's size %d", w.FileSize(), info.Size())
+	}
+}
+
+func TestIngestExternalFile(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("b"), []byte("old"))
+
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bulk.sst")
+	w := NewSstFileWriter(options)
+	defer w.Close()
+	if err := w.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	w.Put([]byte("a"), []byte("1"))
+	w.Put([]byte("b"), []byte("2"))
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	opts := NewIngestExternalFileOptions()
+	defer opts.Close()
+	opts.SetMoveFiles(true)
+	if err := db.IngestExternalFile([]string{path}, opts); err != nil {
+		t.Fatalf("IngestExternalFile failed: %v", err)
+	}
+	CheckGet(t, "ingested", db, ro, []byte("a"), []byte("1"))
+	CheckGet(t, "overwritten", db, ro, []byte("b"), []byte("2"))
+}
+
+func TestIngestBehind(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetAllowIngestBehind(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+	db.Put(wo, []byte("a"), []byte("live"))
+
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "history.sst")
+	w := NewSstFileWriter(options)
+	defer w.Close()
+	if err := w.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	w.Put([]byte("a"), []byte("historical"))
+	w.Put([]byte("b"), []byte("historical"))
+	if err := w.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	opts := NewIngestExternalFileOptions()
+	defer opts.Close()
+	opts.SetIngestBehind(true)
+	if err := db.IngestExternalFile([]string{path}, opts); err != nil {
+		t.Fatalf("IngestExternalFile failed: %v", err)
+	}
+	CheckGet(t, "live key kept", db, ro, []byte("a"), []byte("live"))
+	CheckGet(t, "historical key", db, ro, []byte("b"), []byte("historical"))
+}
+
+func TestVerifyAndIngestExternalFile(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	dir := tempDir(t)
+	defer deleteDBDirectory(t, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	w := NewSstFileWriter(options)
+	defer w.Close()
+	writeFile := func(name string, keys ...string) string {
+		path := filepath.Join(dir, name)
+		if err := w.Open(path); err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		for _, k := range keys {
+			if err := w.Put([]byte(k), []byte("v")); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+		}
+		if err := w.Finish(); err != nil {
+			t.Fatalf("Finish failed: %v", err)
+		}
+		return path
+	}
+	good := writeFile("good.sst", "a", "b", "c")
+	overlap := writeFile("overlap.sst", "c", "d")
+	corrupt := writeFile("corrupt.sst", "x", "y")
+	if err := os.WriteFile(corrupt, []byte("not an sst file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := VerifySstFiles(options, []string{good, overlap, corrupt}, VerifySstOptions{})
+	if err != nil {
+		t.Fatalf("VerifySstFiles failed: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	if r := reports[0]; !r.OK() || r.Keys != 3 || r.Entries != 3 || string(r.SmallestKey) != "a" || string(r.LargestKey) != "c" {
+		t.Errorf("report for good.sst: %+v", r)
+	}
+	if reports[1].OK() {
+		t.Errorf("overlapping file passed verification")
+	}
+	if reports[2].OK() {
+		t.Errorf("corrupt file passed verification")
+	}
+
+	ingestOpts := NewIngestExternalFileOptions()
+	defer ingestOpts.Close()
+	_, err = db.VerifyAndIngestExternalFile(options, []string{good, corrupt}, ingestOpts, VerifySstOptions{})
+	var verr *SstVerificationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("VerifyAndIngestExternalFile returned %v, want an SstVerificationError", err)
+	}
+	CheckGet(t, "not ingested", db, ro, []byte("a"), nil)
+
+	reports, err = db.VerifyAndIngestExternalFile(options, []string{good}, ingestOpts, VerifySstOptions{})
+	if err != nil {
+		t.Fatalf("VerifyAndIngestExternalFile failed: %v %+v", err, reports)
+	}
+	CheckGet(t, "ingested", db, ro, []byte("a"), []byte("v"))
+}
@@ -0,0 +1,144 @@
+package gorocks
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timePartitionLayout formats the start of a partition's bucket in its
+// column family name. It sorts in time order.
+const timePartitionLayout = "20060102T150405Z"
+
+// TimePartition is a column family holding the data of one time bucket.
+type TimePartition struct {
+	Start  time.Time
+	Handle *ColumnFamilyHandle
+}
+
+// TimePartitions spreads time-series data over one column family per time
+// bucket, for example one per day, so that expiring old data is a matter of
+// dropping whole column families instead of deleting keys one by one.
+//
+// The partitions are named by a common prefix followed by the UTC start
+// time of their bucket. To reopen them, open the database with
+// OpenColumnFamilies, listing every column family found by
+// ListColumnFamilies, and pass the handles to NewTimePartitions.
+//
+// A TimePartitions may be shared between goroutines. Close closes the
+// handles of the partitions it manages.
+type TimePartitions struct {
+	db        *DB
+	opts      *Options
+	prefix    string
+	bucket    time.Duration
+	retention time.Duration
+
+	mu         sync.Mutex
+	partitions []TimePartition // sorted by Start
+}
+
+// NewTimePartitions manages the partitions named with prefix, each covering
+// bucket worth of time and kept for retention after its bucket has ended.
+// New partitions are created with opts, which must stay open as long as the
+// TimePartitions is used.
+//
+// Of the given handles, those of column families named with prefix and a
+// valid bucket start are adopted; the others are left to the caller.
+func NewTimePartitions(db *DB, handles []*ColumnFamilyHandle, opts *Options, prefix string, bucket, retention time.Duration) *TimePartitions {
+	tp := &TimePartitions{
+		db:        db,
+		opts:      opts,
+		prefix:    prefix,
+		bucket:    bucket,
+		retention: retention,
+	}
+	for _, h := range handles {
+		name := h.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		start, err := time.Parse(timePartitionLayout, name[len(prefix):])
+		if err != nil {
+			continue
+		}
+		tp.partitions = append(tp.partitions, TimePartition{start, h})
+	}
+	sort.Slice(tp.partitions, func(i, j int) bool {
+		return tp.partitions[i].Start.Before(tp.partitions[j].Start)
+	})
+	return tp
+}
+
+// Partition returns the column family for the bucket containing t,
+// creating it if necessary.
+func (tp *TimePartitions) Partition(t time.Time) (*ColumnFamilyHandle, error) {
+	start := t.UTC().Truncate(tp.bucket)
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	i := sort.Search(len(tp.partitions), func(i int) bool {
+		return !tp.partitions[i].Start.Before(start)
+	})
+	if i < len(tp.partitions) && tp.partitions[i].Start.Equal(start) {
+		return tp.partitions[i].Handle, nil
+	}
+	h, err := tp.db.CreateColumnFamily(tp.opts, tp.prefix+start.Format(timePartitionLayout))
+	if err != nil {
+		return nil, err
+	}
+	tp.partitions = append(tp.partitions, TimePartition{})
+	copy(tp.partitions[i+1:], tp.partitions[i:])
+	tp.partitions[i] = TimePartition{start, h}
+	return h, nil
+}
+
+// Put writes a key-value pair into the partition for t.
+func (tp *TimePartitions) Put(wo *WriteOptions, t time.Time, key, value []byte) error {
+	h, err := tp.Partition(t)
+	if err != nil {
+		return err
+	}
+	return tp.db.PutCF(wo, h, key, value)
+}
+
+// Partitions returns the current partitions, oldest first. The handles
+// remain owned by the TimePartitions and are closed when their partition
+// expires.
+func (tp *TimePartitions) Partitions() []TimePartition {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return append([]TimePartition(nil), tp.partitions...)
+}
+
+// Expire drops the partitions whose bucket ended more than the retention
+// period before now, returning how many were dropped.
+func (tp *TimePartitions) Expire(now time.Time) (int, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	var dropped int
+	for len(tp.partitions) > 0 {
+		p := tp.partitions[0]
+		if now.Sub(p.Start.Add(tp.bucket)) <= tp.retention {
+			break
+		}
+		if err := tp.db.DropColumnFamily(p.Handle); err != nil {
+			return dropped, err
+		}
+		p.Handle.Close()
+		tp.partitions = tp.partitions[1:]
+		dropped++
+	}
+	return dropped, nil
+}
+
+// Close closes the handles of all managed partitions. The DB and the
+// Options are not closed.
+func (tp *TimePartitions) Close() {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for _, p := range tp.partitions {
+		p.Handle.Close()
+	}
+	tp.partitions = nil
+}
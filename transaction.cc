@@ -0,0 +1,34 @@
+// Transaction IDs and lock holders through RocksDB's C++ API, which the C
+// API does not expose.
+
+#include <stdlib.h>
+#include <string.h>
+
+#include <string>
+#include <vector>
+
+#include "cinternal.h"
+
+extern "C" uint64_t gorocks_transaction_get_id(rocksdb_transaction_t* txn) {
+	return txn->rep->GetID();
+}
+
+// gorocks_transactiondb_lock_holders returns the IDs of the transactions
+// holding a lock on key in the column family with the given ID, as a
+// malloc'ed array of *n IDs.
+extern "C" uint64_t* gorocks_transactiondb_lock_holders(rocksdb_transactiondb_t* db,
+		uint32_t cf_id, const char* key, size_t keylen, size_t* n) {
+	std::string k(key, keylen);
+	std::vector<uint64_t> ids;
+	for (const auto& entry : db->rep->GetLockStatusData()) {
+		if (entry.first == cf_id && entry.second.key == k) {
+			ids.insert(ids.end(), entry.second.ids.begin(), entry.second.ids.end());
+		}
+	}
+	*n = ids.size();
+	uint64_t* result = static_cast<uint64_t*>(malloc(ids.size() * sizeof(uint64_t) + 1));
+	if (!ids.empty()) {
+		memcpy(result, ids.data(), ids.size() * sizeof(uint64_t));
+	}
+	return result;
+}
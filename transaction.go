@@ -0,0 +1,592 @@
+package gorocks
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include "rocksdb/c.h"
+
+extern uint64_t gorocks_transaction_get_id(rocksdb_transaction_t* txn);
+extern uint64_t* gorocks_transactiondb_lock_holders(rocksdb_transactiondb_t* db, uint32_t cf_id, const char* key, size_t keylen, size_t* n);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ErrTransactionConflict is matched by the error returned when a Transaction
+// cannot commit, or cannot read a key for update, because of a conflicting
+// write by someone else. The returned error is a *TransactionConflictError.
+// The transaction should be rolled back and may be retried from the start.
+var ErrTransactionConflict = errors.New("transaction conflict")
+
+// TransactionConflictError reports a Transaction that lost a conflict.
+type TransactionConflictError struct {
+	// Msg is the error message reported by RocksDB.
+	Msg string
+}
+
+func (e *TransactionConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTransactionConflict, e.Msg)
+}
+
+// Is reports whether target is ErrTransactionConflict.
+func (e *TransactionConflictError) Is(target error) bool {
+	return target == ErrTransactionConflict
+}
+
+// ErrLockTimeout and ErrDeadlock are matched by the *LockError returned
+// when a Transaction on a TransactionDB fails to lock a key, because it
+// waited longer than its lock timeout or because waiting would have
+// deadlocked with other Transactions. Either way the Transaction should be
+// rolled back and may be retried.
+var (
+	ErrLockTimeout = errors.New("lock timeout")
+	ErrDeadlock    = errors.New("deadlock")
+)
+
+// LockError reports a key a Transaction could not lock.
+type LockError struct {
+	// Reason is ErrLockTimeout or ErrDeadlock.
+	Reason error
+	// Key is the key the Transaction was waiting for, or nil if the lock
+	// was taken on Commit or Prepare.
+	Key []byte
+	// Txn is the name of the waiting Transaction, if it has one, and TxnID
+	// its ID, as returned by Transaction.ID.
+	Txn   string
+	TxnID uint64
+	// HolderIDs are the IDs of the Transactions holding the lock on Key.
+	// RocksDB does not report them with the error, so they are looked up
+	// right after it, and a holder that released the lock in the meantime
+	// is missing. It is nil if Key is.
+	HolderIDs []uint64
+	// Msg is the error message reported by RocksDB.
+	Msg string
+}
+
+func (e *LockError) Error() string {
+	s := e.Reason.Error()
+	if e.Txn != "" {
+		s += " in transaction " + strconv.Quote(e.Txn)
+	}
+	s += fmt.Sprintf(" (id %d)", e.TxnID)
+	if e.Key != nil {
+		s += fmt.Sprintf(" on key %q", e.Key)
+	}
+	if len(e.HolderIDs) > 0 {
+		s += fmt.Sprintf(" held by %v", e.HolderIDs)
+	}
+	return s + ": " + e.Msg
+}
+
+// Is reports whether target is e.Reason.
+func (e *LockError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// error turns the error message of a failed operation on key in cf, nil
+// for the default column family, into an error, recognizing conflicts and
+// lock failures. RocksDB reports conflicts as Busy, or as TryAgain when it
+// no longer has enough history to check.
+func (t *Transaction) error(msg string, cf *ColumnFamilyHandle, key []byte) error {
+	switch {
+	case strings.HasPrefix(msg, "Resource busy: Deadlock"):
+		return t.lockError(ErrDeadlock, msg, cf, key)
+	case strings.HasPrefix(msg, "Operation timed out"):
+		return t.lockError(ErrLockTimeout, msg, cf, key)
+	case strings.HasPrefix(msg, "Resource busy"), strings.HasPrefix(msg, "Operation failed. Try again."):
+		return &TransactionConflictError{Msg: msg}
+	}
+	return DatabaseError(msg)
+}
+
+func (t *Transaction) lockError(reason error, msg string, cf *ColumnFamilyHandle, key []byte) error {
+	e := &LockError{Reason: reason, Txn: t.Name(), TxnID: t.ID(), Msg: msg}
+	if key != nil {
+		e.Key = append([]byte{}, key...)
+		e.HolderIDs = t.lockHolders(cf, key)
+	}
+	return e
+}
+
+// lockHolders returns the IDs of the Transactions other than t holding a
+// lock on key in cf.
+func (t *Transaction) lockHolders(cf *ColumnFamilyHandle, key []byte) []uint64 {
+	holders := []uint64{}
+	if t.tdb == nil {
+		return holders
+	}
+	var cfID uint32
+	if cf != nil {
+		cfID = cf.ID()
+	}
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	var n C.size_t
+	ids := C.gorocks_transactiondb_lock_holders(t.tdb.Tdb, C.uint32_t(cfID), k, C.size_t(len(key)), &n)
+	defer C.free(unsafe.Pointer(ids))
+	self := t.ID()
+	for _, id := range unsafe.Slice(ids, n) {
+		if uint64(id) != self {
+			holders = append(holders, uint64(id))
+		}
+	}
+	return holders
+}
+
+// ID returns the ID RocksDB gave the Transaction, which identifies it in
+// LockErrors. IDs are unique within a database while it is open.
+func (t *Transaction) ID() uint64 {
+	return uint64(C.gorocks_transaction_get_id(t.Txn))
+}
+
+// OptimisticTransactionDB is a database opened with
+// OpenOptimisticTransactionDb. Its Transactions take no locks while they
+// run; instead, Commit fails with ErrTransactionConflict if any key read
+// with GetForUpdate or written by the transaction was changed by someone
+// else since. This suits workloads where conflicts are rare.
+//
+// To avoid memory and file descriptor leaks, call Close when the process no
+// longer needs the handle.
+type OptimisticTransactionDB struct {
+	Odb *C.rocksdb_optimistictransactiondb_t
+}
+
+// OptimisticTransactionOptions represent the options for starting a
+// Transaction on an OptimisticTransactionDB.
+//
+// To prevent memory leaks, Close must be called on an
+// OptimisticTransactionOptions when the program no longer needs it.
+type OptimisticTransactionOptions struct {
+	Opt *C.rocksdb_optimistictransaction_options_t
+}
+
+// Transaction is a set of reads and writes that is committed atomically or
+// not at all. Writes are buffered in the Transaction and only become visible
+// to others on Commit; reads through the Transaction see its own writes.
+//
+// A Transaction must only be used by one goroutine at a time. To prevent
+// memory leaks, Close must be called on it once it has been committed or
+// rolled back, or is no longer needed.
+type Transaction struct {
+	Txn *C.rocksdb_transaction_t
+
+	// tdb is the TransactionDB the Transaction was begun on, nil for
+	// optimistic Transactions, which take no locks.
+	tdb *TransactionDB
+
+	// rangeLocks are the locks taken with RangeLocker.LockTxn, released
+	// when the transaction ends.
+	rangeLocks []*RangeLock
+}
+
+// OpenOptimisticTransactionDb opens a database for use with optimistic
+// transactions. Options are the same as for Open.
+func OpenOptimisticTransactionDb(dbname string, o *Options) (*OptimisticTransactionDB, error) {
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	odb := C.rocksdb_optimistictransactiondb_open(o.Opt, ldbname, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, openError(dbname, gs)
+	}
+	return &OptimisticTransactionDB{odb}, nil
+}
+
+// OpenOptimisticTransactionDbColumnFamilies is like
+// OpenOptimisticTransactionDb, but opens the database along with the named
+// column families, as OpenColumnFamilies does. The returned handles are in
+// the same order as cfNames. A Transaction may write to several column
+// families and commits all of its writes atomically.
+func OpenOptimisticTransactionDbColumnFamilies(dbname string, o *Options, cfNames []string, cfOpts []*Options) (*OptimisticTransactionDB, []*ColumnFamilyHandle, error) {
+	if len(cfNames) != len(cfOpts) {
+		return nil, nil, DatabaseError("column family names and options must have the same length")
+	}
+	if len(cfNames) == 0 {
+		return nil, nil, DatabaseError("at least the default column family must be opened")
+	}
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	cnames := make([]*C.char, len(cfNames))
+	copts := make([]*C.rocksdb_options_t, len(cfOpts))
+	for i, name := range cfNames {
+		cnames[i] = C.CString(name)
+		defer C.free(unsafe.Pointer(cnames[i]))
+		copts[i] = cfOpts[i].Opt
+	}
+	chandles := make([]*C.rocksdb_column_family_handle_t, len(cfNames))
+
+	odb := C.rocksdb_optimistictransactiondb_open_column_families(o.Opt, ldbname,
+		C.int(len(cfNames)), &cnames[0], &copts[0], &chandles[0], &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, nil, openError(dbname, gs)
+	}
+
+	handles := make([]*ColumnFamilyHandle, len(chandles))
+	for i, h := range chandles {
+		handles[i] = &ColumnFamilyHandle{h}
+	}
+	return &OptimisticTransactionDB{odb}, handles, nil
+}
+
+// Begin starts a Transaction. oto may be nil to use the default options.
+func (odb *OptimisticTransactionDB) Begin(wo *WriteOptions, oto *OptimisticTransactionOptions) *Transaction {
+	if oto == nil {
+		oto = NewOptimisticTransactionOptions()
+		defer oto.Close()
+	}
+	txn := C.rocksdb_optimistictransaction_begin(odb.Odb, wo.Opt, oto.Opt, nil)
+	return &Transaction{Txn: txn}
+}
+
+// Close closes the database. All Transactions must have been closed first.
+func (odb *OptimisticTransactionDB) Close() {
+	C.rocksdb_optimistictransactiondb_close(odb.Odb)
+}
+
+// NewOptimisticTransactionOptions allocates a new
+// OptimisticTransactionOptions with the default settings.
+func NewOptimisticTransactionOptions() *OptimisticTransactionOptions {
+	opt := C.rocksdb_optimistictransaction_options_create()
+	return &OptimisticTransactionOptions{opt}
+}
+
+// SetSetSnapshot, if true, makes the Transaction take a snapshot when it
+// begins, so that Commit also fails if a key it writes was changed by
+// someone else after that point, even if the Transaction never read it.
+func (o *OptimisticTransactionOptions) SetSetSnapshot(b bool) {
+	C.rocksdb_optimistictransaction_options_set_set_snapshot(o.Opt, boolToUchar(b))
+}
+
+// Close deallocates the OptimisticTransactionOptions, freeing its
+// underlying C struct.
+func (o *OptimisticTransactionOptions) Close() {
+	C.rocksdb_optimistictransaction_options_destroy(o.Opt)
+}
+
+// Get returns the value of key as seen by the Transaction, including its own
+// uncommitted writes. As with DB.Get, a missing key yields a nil []byte.
+func (t *Transaction) Get(ro *ReadOptions, key []byte) ([]byte, error) {
+	var errStr *C.char
+	var vallen C.size_t
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	value := C.rocksdb_transaction_get(
+		t.Txn, ro.Opt, k, C.size_t(len(key)), &vallen, &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, t.error(gs, nil, key)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	defer C.free(unsafe.Pointer(value))
+	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
+}
+
+// GetForUpdate is like Get, but also makes the Transaction conflict with any
+// write to key by someone else before it commits, as if the Transaction had
+// written key itself.
+func (t *Transaction) GetForUpdate(ro *ReadOptions, key []byte) ([]byte, error) {
+	var errStr *C.char
+	var vallen C.size_t
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	value := C.rocksdb_transaction_get_for_update(
+		t.Txn, ro.Opt, k, C.size_t(len(key)), &vallen, boolToUchar(true), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, t.error(gs, nil, key)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	defer C.free(unsafe.Pointer(value))
+	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
+}
+
+// Put writes a key-value pair in the Transaction.
+func (t *Transaction) Put(key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_transaction_put(
+		t.Txn, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, nil, key)
+	}
+	return nil
+}
+
+// Merge writes a merge operand for key in the Transaction, as DB.Merge
+// does.
+func (t *Transaction) Merge(key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_transaction_merge(
+		t.Txn, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, nil, key)
+	}
+	return nil
+}
+
+// Delete removes key in the Transaction.
+func (t *Transaction) Delete(key []byte) error {
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	C.rocksdb_transaction_delete(t.Txn, k, C.size_t(len(key)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, nil, key)
+	}
+	return nil
+}
+
+// GetCF is like Get, but reads the key from the given column family.
+func (t *Transaction) GetCF(ro *ReadOptions, cf *ColumnFamilyHandle, key []byte) ([]byte, error) {
+	var errStr *C.char
+	var vallen C.size_t
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	value := C.rocksdb_transaction_get_cf(
+		t.Txn, ro.Opt, cf.Handle, k, C.size_t(len(key)), &vallen, &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, t.error(gs, cf, key)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	defer C.free(unsafe.Pointer(value))
+	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
+}
+
+// GetForUpdateCF is like GetForUpdate, but reads the key from the given
+// column family.
+func (t *Transaction) GetForUpdateCF(ro *ReadOptions, cf *ColumnFamilyHandle, key []byte) ([]byte, error) {
+	var errStr *C.char
+	var vallen C.size_t
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	value := C.rocksdb_transaction_get_for_update_cf(
+		t.Txn, ro.Opt, cf.Handle, k, C.size_t(len(key)), &vallen, boolToUchar(true), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, t.error(gs, cf, key)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	defer C.free(unsafe.Pointer(value))
+	return C.GoBytes(unsafe.Pointer(value), C.int(vallen)), nil
+}
+
+// PutCF is like Put, but writes the key-value pair to the given column
+// family.
+func (t *Transaction) PutCF(cf *ColumnFamilyHandle, key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_transaction_put_cf(
+		t.Txn, cf.Handle, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, cf, key)
+	}
+	return nil
+}
+
+// MergeCF is like Merge, but writes the merge operand to the given column
+// family.
+func (t *Transaction) MergeCF(cf *ColumnFamilyHandle, key, value []byte) error {
+	var errStr *C.char
+	var k, v *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+	if len(value) != 0 {
+		v = (*C.char)(unsafe.Pointer(&value[0]))
+	}
+
+	C.rocksdb_transaction_merge_cf(
+		t.Txn, cf.Handle, k, C.size_t(len(key)), v, C.size_t(len(value)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, cf, key)
+	}
+	return nil
+}
+
+// DeleteCF is like Delete, but removes the key from the given column
+// family.
+func (t *Transaction) DeleteCF(cf *ColumnFamilyHandle, key []byte) error {
+	var errStr *C.char
+	var k *C.char
+	if len(key) != 0 {
+		k = (*C.char)(unsafe.Pointer(&key[0]))
+	}
+
+	C.rocksdb_transaction_delete_cf(t.Txn, cf.Handle, k, C.size_t(len(key)), &errStr)
+
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, cf, key)
+	}
+	return nil
+}
+
+// NewIteratorCF is like NewIterator, but the returned Iterator only covers
+// the keys of the given column family.
+func (t *Transaction) NewIteratorCF(ro *ReadOptions, cf *ColumnFamilyHandle) *Iterator {
+	it := C.rocksdb_transaction_create_iterator_cf(t.Txn, ro.Opt, cf.Handle)
+	return &Iterator{Iter: it, cf: cf, snap: ro.snap, lower: ro.lower, upper: ro.upper}
+}
+
+// NewIterator returns an Iterator over the database as seen by the
+// Transaction, including its own uncommitted writes. The Iterator must be
+// closed before the Transaction.
+func (t *Transaction) NewIterator(ro *ReadOptions) *Iterator {
+	it := C.rocksdb_transaction_create_iterator(t.Txn, ro.Opt)
+	return &Iterator{Iter: it, snap: ro.snap, lower: ro.lower, upper: ro.upper}
+}
+
+// Commit makes the writes of the Transaction visible atomically. On an
+// OptimisticTransactionDB it fails with ErrTransactionConflict if a key the
+// Transaction depends on was changed by someone else, in which case nothing
+// is written.
+func (t *Transaction) Commit() error {
+	var errStr *C.char
+	C.rocksdb_transaction_commit(t.Txn, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, nil, nil)
+	}
+	t.unlockRanges()
+	return nil
+}
+
+// Rollback discards the writes of the Transaction.
+func (t *Transaction) Rollback() error {
+	var errStr *C.char
+	C.rocksdb_transaction_rollback(t.Txn, &errStr)
+	t.unlockRanges()
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+func (t *Transaction) unlockRanges() {
+	for _, l := range t.rangeLocks {
+		l.Unlock()
+	}
+	t.rangeLocks = nil
+}
+
+// SetSavePoint records the current state of the Transaction, so that the
+// writes made after it can be undone with RollbackToSavePoint.
+func (t *Transaction) SetSavePoint() {
+	C.rocksdb_transaction_set_savepoint(t.Txn)
+}
+
+// RollbackToSavePoint discards the writes made since the most recent
+// SetSavePoint, and forgets that save point.
+func (t *Transaction) RollbackToSavePoint() error {
+	var errStr *C.char
+	C.rocksdb_transaction_rollback_to_savepoint(t.Txn, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Close deallocates the Transaction. A Transaction that was neither
+// committed nor rolled back is rolled back.
+func (t *Transaction) Close() {
+	C.rocksdb_transaction_destroy(t.Txn)
+	t.unlockRanges()
+}
@@ -0,0 +1,368 @@
+package gorocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOptimisticTransaction(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	odb, err := OpenOptimisticTransactionDb(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer odb.Close()
+
+	txn := odb.Begin(wo, nil)
+	if err := txn.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if v, err := txn.Get(ro, []byte("a")); err != nil || string(v) != "1" {
+		t.Errorf("transaction should see its own write, got %q, %v", v, err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	txn.Close()
+
+	// Two transactions read and update the same key; the second to commit
+	// must fail.
+	t1 := odb.Begin(wo, nil)
+	defer t1.Close()
+	t2 := odb.Begin(wo, nil)
+	defer t2.Close()
+	if _, err := t1.GetForUpdate(ro, []byte("a")); err != nil {
+		t.Fatalf("GetForUpdate failed: %v", err)
+	}
+	if _, err := t2.GetForUpdate(ro, []byte("a")); err != nil {
+		t.Fatalf("GetForUpdate failed: %v", err)
+	}
+	t1.Put([]byte("a"), []byte("2"))
+	t2.Put([]byte("a"), []byte("3"))
+	if err := t1.Commit(); err != nil {
+		t.Fatalf("first Commit failed: %v", err)
+	}
+	if err := t2.Commit(); !errors.Is(err, ErrTransactionConflict) {
+		t.Errorf("expected ErrTransactionConflict, got %v", err)
+	}
+
+	t3 := odb.Begin(wo, nil)
+	defer t3.Close()
+	v, err := t3.Get(ro, []byte("a"))
+	if err != nil || string(v) != "2" {
+		t.Errorf("expected committed value %q, got %q, %v", "2", v, err)
+	}
+	t3.SetSavePoint()
+	t3.Delete([]byte("a"))
+	if err := t3.RollbackToSavePoint(); err != nil {
+		t.Fatalf("RollbackToSavePoint failed: %v", err)
+	}
+	if v, _ := t3.Get(ro, []byte("a")); string(v) != "2" {
+		t.Errorf("delete should have been rolled back, got %q", v)
+	}
+}
+
+func TestTransactionDBPrepare(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	tdb, err := OpenTransactionDb(dbname, options, nil)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+
+	txn := tdb.Begin(wo, nil)
+	if err := txn.Prepare(); err == nil {
+		t.Errorf("Prepare of an unnamed transaction should fail")
+	}
+	if err := txn.SetName("xid-1"); err != nil {
+		t.Fatalf("SetName failed: %v", err)
+	}
+	if txn.Name() != "xid-1" {
+		t.Errorf("unexpected name %q", txn.Name())
+	}
+	txn.Put([]byte("a"), []byte("1"))
+	if err := txn.Prepare(); err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	// Leave the transaction prepared across a restart.
+	txn.Close()
+	tdb.Close()
+
+	tdb, err = OpenTransactionDb(dbname, options, nil)
+	if err != nil {
+		t.Fatalf("Database could not be reopened: %v", err)
+	}
+	defer tdb.Close()
+	prepared := tdb.GetPreparedTransactions()
+	if len(prepared) != 1 || prepared[0].Name() != "xid-1" {
+		t.Fatalf("expected prepared transaction xid-1, got %d", len(prepared))
+	}
+	if err := prepared[0].Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	prepared[0].Close()
+
+	check := tdb.Begin(wo, nil)
+	defer check.Close()
+	if v, err := check.Get(ro, []byte("a")); err != nil || string(v) != "1" {
+		t.Errorf("expected committed value %q, got %q, %v", "1", v, err)
+	}
+}
+
+func TestTransactionLockTimeout(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	tdbo := NewTransactionDBOptions()
+	tdbo.SetTransactionLockTimeout(10 * time.Millisecond)
+	defer tdbo.Close()
+	tdb, err := OpenTransactionDb(dbname, options, tdbo)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer tdb.Close()
+
+	t1 := tdb.Begin(wo, nil)
+	defer t1.Close()
+	t2 := tdb.Begin(wo, nil)
+	defer t2.Close()
+	t2.SetName("second")
+	if err := t1.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	err = t2.Put([]byte("a"), []byte("2"))
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("expected ErrLockTimeout, got %v", err)
+	}
+	lerr := err.(*LockError)
+	if string(lerr.Key) != "a" || lerr.Txn != "second" || lerr.TxnID != t2.ID() {
+		t.Errorf("unexpected lock error details %+v", lerr)
+	}
+	if t1.ID() == t2.ID() {
+		t.Errorf("transactions share the ID %d", t1.ID())
+	}
+	if len(lerr.HolderIDs) != 1 || lerr.HolderIDs[0] != t1.ID() {
+		t.Errorf("lock holders %v, want [%d]", lerr.HolderIDs, t1.ID())
+	}
+}
+
+func TestTransactionDeadlock(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	tdb, err := OpenTransactionDb(dbname, options, nil)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer tdb.Close()
+	to := NewTransactionOptions()
+	to.SetDeadlockDetect(true)
+	to.SetLockTimeout(10 * time.Second)
+	defer to.Close()
+
+	t1 := tdb.Begin(wo, to)
+	defer t1.Close()
+	t2 := tdb.Begin(wo, to)
+	defer t2.Close()
+	t1.Put([]byte("a"), []byte("1"))
+	t2.Put([]byte("b"), []byte("2"))
+
+	// Each transaction asks for the other's lock. Whichever asks second
+	// must detect the deadlock and roll back, letting the other proceed.
+	lock := func(txn *Transaction, key []byte, errc chan<- error) {
+		err := txn.Put(key, []byte("x"))
+		if errors.Is(err, ErrDeadlock) {
+			txn.Rollback()
+		}
+		errc <- err
+	}
+	errc := make(chan error)
+	go lock(t1, []byte("b"), errc)
+	go lock(t2, []byte("a"), errc)
+	var deadlocks int
+	for i := 0; i < 2; i++ {
+		err := <-errc
+		switch {
+		case errors.Is(err, ErrDeadlock):
+			deadlocks++
+		case err != nil:
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if deadlocks != 1 {
+		t.Errorf("expected one deadlock, got %d", deadlocks)
+	}
+}
+
+func TestTransactionColumnFamilies(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCreateMissingColumnFamilies(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	tdb, handles, err := OpenTransactionDbColumnFamilies(dbname, options, nil,
+		[]string{DefaultColumnFamilyName, "other"}, []*Options{options, options})
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer tdb.Close()
+	for _, h := range handles {
+		defer h.Close()
+	}
+	other := handles[1]
+
+	txn := tdb.Begin(wo, nil)
+	txn.Put([]byte("k"), []byte("default"))
+	txn.PutCF(other, []byte("k"), []byte("other"))
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	txn.Close()
+
+	txn = tdb.Begin(wo, nil)
+	defer txn.Close()
+	if v, err := txn.GetForUpdateCF(ro, other, []byte("k")); err != nil || string(v) != "other" {
+		t.Errorf("unexpected GetForUpdateCF result %q, %v", v, err)
+	}
+	txn.DeleteCF(other, []byte("k"))
+	if v, _ := txn.GetCF(ro, other, []byte("k")); v != nil {
+		t.Errorf("delete should be visible in the transaction, got %q", v)
+	}
+	if v, _ := txn.Get(ro, []byte("k")); string(v) != "default" {
+		t.Errorf("default column family should be untouched, got %q", v)
+	}
+}
+
+func TestOptimisticTransactionColumnFamilies(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	options.SetCreateMissingColumnFamilies(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	odb, handles, err := OpenOptimisticTransactionDbColumnFamilies(dbname, options,
+		[]string{DefaultColumnFamilyName, "accounts", "log"}, []*Options{options, options, options})
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer odb.Close()
+	for _, h := range handles {
+		defer h.Close()
+	}
+	accounts, log := handles[1], handles[2]
+
+	// A transaction spanning two column families loses a conflict on one
+	// of them; none of its writes may become visible.
+	t1 := odb.Begin(wo, nil)
+	defer t1.Close()
+	t2 := odb.Begin(wo, nil)
+	defer t2.Close()
+	t1.GetForUpdateCF(ro, accounts, []byte("alice"))
+	t1.PutCF(accounts, []byte("alice"), []byte("90"))
+	t1.PutCF(log, []byte("1"), []byte("alice -10"))
+	t2.PutCF(accounts, []byte("alice"), []byte("50"))
+	if err := t2.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := t1.Commit(); !errors.Is(err, ErrTransactionConflict) {
+		t.Fatalf("expected ErrTransactionConflict, got %v", err)
+	}
+
+	check := odb.Begin(wo, nil)
+	defer check.Close()
+	if v, _ := check.GetCF(ro, accounts, []byte("alice")); string(v) != "50" {
+		t.Errorf("expected the winning write, got %q", v)
+	}
+	if v, _ := check.GetCF(ro, log, []byte("1")); v != nil {
+		t.Errorf("write of the failed transaction became visible: %q", v)
+	}
+}
+
+func TestRangeLocker(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	tdb, err := OpenTransactionDb(dbname, options, nil)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer tdb.Close()
+
+	var locker RangeLocker
+	txn := tdb.Begin(wo, nil)
+	defer txn.Close()
+	if err := locker.LockTxn(context.Background(), txn, Range{[]byte("acct1/"), []byte("acct10")}); err != nil {
+		t.Fatalf("LockTxn failed: %v", err)
+	}
+
+	other, err := locker.LockPrefix(context.Background(), []byte("acct2/"))
+	if err != nil {
+		t.Fatalf("locking a disjoint range failed: %v", err)
+	}
+	other.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := locker.LockPrefix(ctx, []byte("acct1/")); err != context.DeadlineExceeded {
+		t.Fatalf("locking an overlapping range: got %v, want DeadlineExceeded", err)
+	}
+
+	locked := make(chan *RangeLock)
+	go func() {
+		l, _ := locker.Lock(context.Background(), Range{[]byte("acct1/x"), nil})
+		locked <- l
+	}()
+	txn.Put([]byte("acct1/balance"), []byte("10"))
+	select {
+	case <-locked:
+		t.Fatal("overlapping lock granted while the transaction is open")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	select {
+	case l := <-locked:
+		l.Unlock()
+	case <-time.After(time.Second):
+		t.Fatal("lock not released by Commit")
+	}
+}
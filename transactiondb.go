@@ -0,0 +1,300 @@
+package gorocks
+
+// #include <stdlib.h>
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"time"
+	"unsafe"
+)
+
+// TransactionDB is a database opened with OpenTransactionDb. Its
+// Transactions lock the keys they write, and those they read with
+// GetForUpdate, as they go, so conflicts are detected when they happen
+// rather than at commit time. This suits workloads with frequent conflicts.
+//
+// To avoid memory and file descriptor leaks, call Close when the process no
+// longer needs the handle.
+type TransactionDB struct {
+	Tdb *C.rocksdb_transactiondb_t
+}
+
+// TransactionDBOptions represent the options for opening a TransactionDB.
+//
+// To prevent memory leaks, Close must be called on a TransactionDBOptions
+// when the program no longer needs it.
+type TransactionDBOptions struct {
+	Opt *C.rocksdb_transactiondb_options_t
+}
+
+// TransactionOptions represent the options for starting a Transaction on a
+// TransactionDB.
+//
+// To prevent memory leaks, Close must be called on a TransactionOptions when
+// the program no longer needs it.
+type TransactionOptions struct {
+	Opt *C.rocksdb_transaction_options_t
+}
+
+// OpenTransactionDb opens a database for use with pessimistic transactions.
+// Options are the same as for Open. tdbo may be nil to use the default
+// TransactionDBOptions.
+func OpenTransactionDb(dbname string, o *Options, tdbo *TransactionDBOptions) (*TransactionDB, error) {
+	if tdbo == nil {
+		tdbo = NewTransactionDBOptions()
+		defer tdbo.Close()
+	}
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	tdb := C.rocksdb_transactiondb_open(o.Opt, tdbo.Opt, ldbname, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, openError(dbname, gs)
+	}
+	return &TransactionDB{tdb}, nil
+}
+
+// OpenTransactionDbColumnFamilies is like OpenTransactionDb, but opens the
+// database along with the named column families, as OpenColumnFamilies
+// does. The returned handles are in the same order as cfNames.
+func OpenTransactionDbColumnFamilies(dbname string, o *Options, tdbo *TransactionDBOptions, cfNames []string, cfOpts []*Options) (*TransactionDB, []*ColumnFamilyHandle, error) {
+	if len(cfNames) != len(cfOpts) {
+		return nil, nil, DatabaseError("column family names and options must have the same length")
+	}
+	if len(cfNames) == 0 {
+		return nil, nil, DatabaseError("at least the default column family must be opened")
+	}
+	if tdbo == nil {
+		tdbo = NewTransactionDBOptions()
+		defer tdbo.Close()
+	}
+	var errStr *C.char
+	ldbname := C.CString(dbname)
+	defer C.free(unsafe.Pointer(ldbname))
+
+	cnames := make([]*C.char, len(cfNames))
+	copts := make([]*C.rocksdb_options_t, len(cfOpts))
+	for i, name := range cfNames {
+		cnames[i] = C.CString(name)
+		defer C.free(unsafe.Pointer(cnames[i]))
+		copts[i] = cfOpts[i].Opt
+	}
+	chandles := make([]*C.rocksdb_column_family_handle_t, len(cfNames))
+
+	tdb := C.rocksdb_transactiondb_open_column_families(o.Opt, tdbo.Opt, ldbname,
+		C.int(len(cfNames)), &cnames[0], &copts[0], &chandles[0], &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, nil, openError(dbname, gs)
+	}
+
+	handles := make([]*ColumnFamilyHandle, len(chandles))
+	for i, h := range chandles {
+		handles[i] = &ColumnFamilyHandle{h}
+	}
+	return &TransactionDB{tdb}, handles, nil
+}
+
+// CreateColumnFamily creates a new column family with the given name and
+// Options, and returns a handle to it.
+func (tdb *TransactionDB) CreateColumnFamily(o *Options, name string) (*ColumnFamilyHandle, error) {
+	var errStr *C.char
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	handle := C.rocksdb_transactiondb_create_column_family(tdb.Tdb, o.Opt, cname, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return nil, DatabaseError(gs)
+	}
+	return &ColumnFamilyHandle{handle}, nil
+}
+
+// Begin starts a Transaction. to may be nil to use the default options.
+func (tdb *TransactionDB) Begin(wo *WriteOptions, to *TransactionOptions) *Transaction {
+	if to == nil {
+		to = NewTransactionOptions()
+		defer to.Close()
+	}
+	txn := C.rocksdb_transaction_begin(tdb.Tdb, wo.Opt, to.Opt, nil)
+	return &Transaction{Txn: txn, tdb: tdb}
+}
+
+// GetPreparedTransactions returns the Transactions that were prepared, but
+// neither committed nor rolled back, when the database was last closed. A
+// process taking part in two-phase commit calls it after reopening the
+// database to find out which Transactions it must still resolve, by name,
+// with Commit or Rollback.
+//
+// The returned Transactions must be closed once resolved.
+func (tdb *TransactionDB) GetPreparedTransactions() []*Transaction {
+	var n C.size_t
+	txns := C.rocksdb_transactiondb_get_prepared_transactions(tdb.Tdb, &n)
+	if txns == nil {
+		return nil
+	}
+	defer C.rocksdb_free(unsafe.Pointer(txns))
+	ptrs := (*[1 << 30]*C.rocksdb_transaction_t)(unsafe.Pointer(txns))[:n:n]
+	prepared := make([]*Transaction, n)
+	for i, txn := range ptrs {
+		prepared[i] = &Transaction{Txn: txn, tdb: tdb}
+	}
+	return prepared
+}
+
+// Close closes the database. All Transactions must have been closed first.
+func (tdb *TransactionDB) Close() {
+	C.rocksdb_transactiondb_close(tdb.Tdb)
+}
+
+// NewTransactionDBOptions allocates a new TransactionDBOptions with the
+// default settings.
+func NewTransactionDBOptions() *TransactionDBOptions {
+	opt := C.rocksdb_transactiondb_options_create()
+	return &TransactionDBOptions{opt}
+}
+
+// Close deallocates the TransactionDBOptions, freeing its underlying C
+// struct.
+func (o *TransactionDBOptions) Close() {
+	C.rocksdb_transactiondb_options_destroy(o.Opt)
+}
+
+// lockTimeoutMillis converts a lock timeout to the milliseconds RocksDB
+// expects, where a negative value means waiting forever.
+func lockTimeoutMillis(d time.Duration) C.int64_t {
+	if d < 0 {
+		return -1
+	}
+	return C.int64_t(d / time.Millisecond)
+}
+
+// SetMaxNumLocks limits the number of keys that can be locked at once in
+// each column family. Transactions fail to lock further keys once it is
+// reached. Zero or a negative value means no limit.
+func (o *TransactionDBOptions) SetMaxNumLocks(n int64) {
+	C.rocksdb_transactiondb_options_set_max_num_locks(o.Opt, C.int64_t(n))
+}
+
+// SetNumStripes sets the number of sub-tables the lock table of each column
+// family is split into, to reduce contention between unrelated keys.
+func (o *TransactionDBOptions) SetNumStripes(n int) {
+	C.rocksdb_transactiondb_options_set_num_stripes(o.Opt, C.size_t(n))
+}
+
+// SetTransactionLockTimeout sets the default time a Transaction waits for a
+// lock held by another Transaction before failing with ErrLockTimeout. A
+// negative duration means waiting forever. It can be overridden per
+// Transaction with TransactionOptions.SetLockTimeout.
+func (o *TransactionDBOptions) SetTransactionLockTimeout(d time.Duration) {
+	C.rocksdb_transactiondb_options_set_transaction_lock_timeout(o.Opt, lockTimeoutMillis(d))
+}
+
+// SetDefaultLockTimeout sets the time writes made directly to the
+// TransactionDB, outside of a Transaction, wait for a lock. A negative
+// duration means waiting forever.
+func (o *TransactionDBOptions) SetDefaultLockTimeout(d time.Duration) {
+	C.rocksdb_transactiondb_options_set_default_lock_timeout(o.Opt, lockTimeoutMillis(d))
+}
+
+// NewTransactionOptions allocates a new TransactionOptions with the default
+// settings.
+func NewTransactionOptions() *TransactionOptions {
+	opt := C.rocksdb_transaction_options_create()
+	return &TransactionOptions{opt}
+}
+
+// Close deallocates the TransactionOptions, freeing its underlying C
+// struct.
+func (o *TransactionOptions) Close() {
+	C.rocksdb_transaction_options_destroy(o.Opt)
+}
+
+// SetSetSnapshot, if true, makes the Transaction take a snapshot when it
+// begins, so that it fails to lock a key that was changed by someone else
+// after that point.
+func (o *TransactionOptions) SetSetSnapshot(b bool) {
+	C.rocksdb_transaction_options_set_set_snapshot(o.Opt, boolToUchar(b))
+}
+
+// SetDeadlockDetect, if true, makes the Transaction check whether waiting
+// for a lock would deadlock, and fail with ErrDeadlock instead of waiting
+// until the lock timeout.
+func (o *TransactionOptions) SetDeadlockDetect(b bool) {
+	C.rocksdb_transaction_options_set_deadlock_detect(o.Opt, boolToUchar(b))
+}
+
+// SetDeadlockDetectDepth limits how many Transactions deep the wait-for
+// graph is searched by deadlock detection.
+func (o *TransactionOptions) SetDeadlockDetectDepth(depth int64) {
+	C.rocksdb_transaction_options_set_deadlock_detect_depth(o.Opt, C.int64_t(depth))
+}
+
+// SetLockTimeout overrides TransactionDBOptions.SetTransactionLockTimeout
+// for the Transaction. A negative duration means waiting forever.
+func (o *TransactionOptions) SetLockTimeout(d time.Duration) {
+	C.rocksdb_transaction_options_set_lock_timeout(o.Opt, lockTimeoutMillis(d))
+}
+
+// SetExpiration sets how long the Transaction may run before other
+// Transactions may steal its locks, after which it can no longer commit. A
+// negative duration means it never expires.
+func (o *TransactionOptions) SetExpiration(d time.Duration) {
+	C.rocksdb_transaction_options_set_expiration(o.Opt, lockTimeoutMillis(d))
+}
+
+// SetMaxWriteBatchSize limits the size in bytes of the writes buffered by
+// the Transaction. Zero means no limit.
+func (o *TransactionOptions) SetMaxWriteBatchSize(size int) {
+	C.rocksdb_transaction_options_set_max_write_batch_size(o.Opt, C.size_t(size))
+}
+
+// SetName names the Transaction, which is required before Prepare. The name
+// must be unique among the Transactions of the TransactionDB and is how a
+// prepared Transaction is found again after a restart.
+func (t *Transaction) SetName(name string) error {
+	var errStr *C.char
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	C.rocksdb_transaction_set_name(t.Txn, cname, C.size_t(len(name)), &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return DatabaseError(gs)
+	}
+	return nil
+}
+
+// Name returns the name given to the Transaction with SetName, or an empty
+// string.
+func (t *Transaction) Name() string {
+	var n C.size_t
+	name := C.rocksdb_transaction_get_name(t.Txn, &n)
+	if name == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(name))
+	return C.GoStringN(name, C.int(n))
+}
+
+// Prepare is the first phase of a two-phase commit on a TransactionDB. It
+// durably records the writes of the named Transaction, so that it can still
+// be committed or rolled back after a crash, while holding on to its locks.
+// After a successful Prepare, Commit or Rollback decides the outcome.
+func (t *Transaction) Prepare() error {
+	var errStr *C.char
+	C.rocksdb_transaction_prepare(t.Txn, &errStr)
+	if errStr != nil {
+		gs := C.GoString(errStr)
+		C.free(unsafe.Pointer(errStr))
+		return t.error(gs, nil, nil)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package gorocks
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// ttlHeaderLen is the size of the expiry timestamp stored in front of every
+// value written through a TTLDB.
+const ttlHeaderLen = 8
+
+// TTLDB stores values with a per-key expiry time. Each value is prefixed
+// with an 8-byte big-endian expiry timestamp in Unix nanoseconds, 0 meaning
+// the value never expires. Expired values are hidden by Get and removed by
+// PurgeExpired.
+//
+// All access to keys written through a TTLDB must go through it, since the
+// stored values carry the expiry header.
+//
+// The header is not the timestamp TTLFilter uses, which is the write time
+// in Unix seconds: a TTLFilter would misread TTLDB values and drop or keep
+// the wrong ones. To expire TTLDB values in compactions, install a
+// TTLDBFilter instead.
+type TTLDB struct {
+	db *DB
+
+	// Now returns the current time. It defaults to time.Now and may be
+	// replaced in tests.
+	Now func() time.Time
+}
+
+// NewTTLDB returns a TTLDB storing its data in db.
+func NewTTLDB(db *DB) *TTLDB {
+	return &TTLDB{db: db, Now: time.Now}
+}
+
+func encodeTTLValue(expiry time.Time, value []byte) []byte {
+	buf := make([]byte, ttlHeaderLen+len(value))
+	if !expiry.IsZero() {
+		binary.BigEndian.PutUint64(buf, uint64(expiry.UnixNano()))
+	}
+	copy(buf[ttlHeaderLen:], value)
+	return buf
+}
+
+// decodeTTLValue splits a stored value into its expiry time, the zero Time
+// if it never expires, and the user value.
+func decodeTTLValue(stored []byte) (time.Time, []byte, error) {
+	if len(stored) < ttlHeaderLen {
+		return time.Time{}, nil, DatabaseError("ttl: value too short for expiry header")
+	}
+	var expiry time.Time
+	if ns := binary.BigEndian.Uint64(stored); ns != 0 {
+		expiry = time.Unix(0, int64(ns))
+	}
+	return expiry, stored[ttlHeaderLen:], nil
+}
+
+func (t *TTLDB) expired(expiry time.Time) bool {
+	return !expiry.IsZero() && !t.Now().Before(expiry)
+}
+
+// Put writes value under key, expiring after ttl. A ttl of zero or less
+// means the value never expires.
+func (t *TTLDB) Put(wo *WriteOptions, key, value []byte, ttl time.Duration) error {
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = t.Now().Add(ttl)
+	}
+	return t.db.Put(wo, key, encodeTTLValue(expiry, value))
+}
+
+// Get returns the value of key, or nil if it does not exist or has expired.
+func (t *TTLDB) Get(ro *ReadOptions, key []byte) ([]byte, error) {
+	value, _, err := t.GetWithExpiry(ro, key)
+	return value, err
+}
+
+// GetWithExpiry is like Get, but also returns the time at which the value
+// expires, or the zero Time if it never does.
+func (t *TTLDB) GetWithExpiry(ro *ReadOptions, key []byte) ([]byte, time.Time, error) {
+	stored, err := t.db.Get(ro, key)
+	if err != nil || stored == nil {
+		return nil, time.Time{}, err
+	}
+	expiry, value, err := decodeTTLValue(stored)
+	if err != nil || t.expired(expiry) {
+		return nil, time.Time{}, err
+	}
+	return value, expiry, nil
+}
+
+// Delete removes key.
+func (t *TTLDB) Delete(wo *WriteOptions, key []byte) error {
+	return t.db.Delete(wo, key)
+}
+
+// PurgeExpired scans the whole database and deletes the expired values it
+// finds, returning how many were removed. Deletes are issued in batches of
+// at most batchSize keys. A key rewritten while the scan is running may be
+// deleted if its old value had expired, so PurgeExpired is best run when
+// writes to expiring keys are quiet.
+func (t *TTLDB) PurgeExpired(ro *ReadOptions, wo *WriteOptions, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	it := t.db.NewIterator(ro)
+	defer it.Close()
+	wb := NewWriteBatch()
+	defer wb.Close()
+
+	var purged int
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		expiry, _, err := decodeTTLValue(it.Value())
+		if err != nil || !t.expired(expiry) {
+			continue
+		}
+		wb.Delete(it.Key())
+		if wb.Count() >= batchSize {
+			if err := t.db.Write(wo, wb); err != nil {
+				return purged, err
+			}
+			purged += wb.Count()
+			wb.Clear()
+		}
+	}
+	if err := it.GetError(); err != nil {
+		return purged, err
+	}
+	if wb.Count() > 0 {
+		if err := t.db.Write(wo, wb); err != nil {
+			return purged, err
+		}
+		purged += wb.Count()
+	}
+	return purged, nil
+}
+
+// TTLDBFilter is a CompactionFilter that drops values written through a
+// TTLDB once they have expired, so that compactions reclaim them without
+// PurgeExpired. Values without a valid expiry header are kept.
+type TTLDBFilter struct {
+	// Clock returns the current time. It is time.Now if nil; it should
+	// agree with the Now of the TTLDBs writing to the database.
+	Clock func() time.Time
+}
+
+// NewTTLDBFilter returns a TTLDBFilter using time.Now.
+func NewTTLDBFilter() *TTLDBFilter {
+	return &TTLDBFilter{}
+}
+
+func (f *TTLDBFilter) Name() string { return "gorocks.TTLDBFilter" }
+
+func (f *TTLDBFilter) Filter(level int, key, value []byte) (bool, []byte) {
+	expiry, _, err := decodeTTLValue(value)
+	if err != nil || expiry.IsZero() {
+		return false, nil
+	}
+	now := time.Now()
+	if f.Clock != nil {
+		now = f.Clock()
+	}
+	return !now.Before(expiry), nil
+}
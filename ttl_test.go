@@ -0,0 +1,75 @@
+package gorocks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLDB(t *testing.T) {
+	dbname := tempDir(t)
+	defer deleteDBDirectory(t, dbname)
+	options := NewOptions()
+	options.SetCreateIfMissing(true)
+	defer options.Close()
+	ro := NewReadOptions()
+	defer ro.Close()
+	wo := NewWriteOptions()
+	defer wo.Close()
+	db, err := Open(dbname, options)
+	if err != nil {
+		t.Fatalf("Database could not be opened: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1000, 0)
+	ttl := NewTTLDB(db)
+	ttl.Now = func() time.Time { return now }
+
+	ttl.Put(wo, []byte("short"), []byte("a"), time.Minute)
+	ttl.Put(wo, []byte("long"), []byte("b"), time.Hour)
+	ttl.Put(wo, []byte("forever"), []byte("c"), 0)
+
+	value, expiry, err := ttl.GetWithExpiry(ro, []byte("short"))
+	if err != nil || string(value) != "a" || !expiry.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected GetWithExpiry result %q, %v, %v", value, expiry, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if value, _ := ttl.Get(ro, []byte("short")); value != nil {
+		t.Errorf("expired value should not be returned, got %q", value)
+	}
+	if value, _ := ttl.Get(ro, []byte("long")); string(value) != "b" {
+		t.Errorf("unexpired value should be returned, got %q", value)
+	}
+
+	purged, err := ttl.PurgeExpired(ro, wo, 0)
+	if err != nil || purged != 1 {
+		t.Errorf("expected 1 purged key, got %d, %v", purged, err)
+	}
+	CheckGet(t, "after PurgeExpired", db, ro, []byte("short"), nil)
+	if value, _ := ttl.Get(ro, []byte("forever")); string(value) != "c" {
+		t.Errorf("value without ttl should be returned, got %q", value)
+	}
+}
+
+func TestTTLDBFilter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	f := NewTTLDBFilter()
+	f.Clock = func() time.Time { return now }
+
+	tests := []struct {
+		value  []byte
+		remove bool
+	}{
+		{encodeTTLValue(now.Add(-time.Second), []byte("a")), true},
+		{encodeTTLValue(now, []byte("a")), true},
+		{encodeTTLValue(now.Add(time.Second), []byte("a")), false},
+		{encodeTTLValue(time.Time{}, []byte("a")), false},
+		{[]byte("short"), false},
+	}
+	for i, test := range tests {
+		if remove, _ := f.Filter(0, nil, test.value); remove != test.remove {
+			t.Errorf("%d: Filter(%q) = %v, want %v", i, test.value, remove, test.remove)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gorocks
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// TimestampPosition says where a TTLFilter finds the timestamp in a value.
+type TimestampPosition int
+
+const (
+	// TimestampPrefix stores the timestamp in the first 8 bytes of values.
+	TimestampPrefix TimestampPosition = iota
+	// TimestampSuffix stores the timestamp in the last 8 bytes of values.
+	TimestampSuffix
+)
+
+// timestampLen is the size of the timestamps used by TTLFilter: Unix
+// seconds as a big-endian uint64.
+const timestampLen = 8
+
+// TTLFilter is a CompactionFilter that drops values older than TTL. Values
+// carry the time they were written, added with Stamp, and compactions
+// remove them once that time is more than TTL before Clock. Expired values
+// remain readable until a compaction reaches them, so readers that must not
+// see them should check Expired as well.
+//
+// Values too short to hold a timestamp are kept.
+//
+// TTLFilter does not understand values written through a TTLDB, which
+// prefix them with their expiry time in Unix nanoseconds rather than their
+// write time in seconds; use TTLDBFilter for those.
+type TTLFilter struct {
+	TTL      time.Duration
+	Position TimestampPosition
+
+	// Clock returns the current time. It is time.Now if nil; tests can set
+	// it to expire values without waiting.
+	Clock func() time.Time
+}
+
+// NewTTLFilter returns a TTLFilter that expires values after ttl, with their
+// timestamps at pos.
+func NewTTLFilter(ttl time.Duration, pos TimestampPosition) *TTLFilter {
+	return &TTLFilter{TTL: ttl, Position: pos}
+}
+
+func (f *TTLFilter) Name() string { return "gorocks.TTLFilter" }
+
+func (f *TTLFilter) Filter(level int, key, value []byte) (bool, []byte) {
+	return f.Expired(value), nil
+}
+
+// Stamp returns value with timestamp t added at the filter's Position.
+func (f *TTLFilter) Stamp(value []byte, t time.Time) []byte {
+	b := make([]byte, len(value)+timestampLen)
+	if f.Position == TimestampSuffix {
+		copy(b, value)
+		binary.BigEndian.PutUint64(b[len(value):], uint64(t.Unix()))
+	} else {
+		binary.BigEndian.PutUint64(b, uint64(t.Unix()))
+		copy(b[timestampLen:], value)
+	}
+	return b
+}
+
+// Split separates a value made by Stamp into the original value and its
+// timestamp. It returns false if stamped is too short to hold a timestamp.
+func (f *TTLFilter) Split(stamped []byte) (value []byte, t time.Time, ok bool) {
+	if len(stamped) < timestampLen {
+		return nil, time.Time{}, false
+	}
+	var ts []byte
+	if f.Position == TimestampSuffix {
+		n := len(stamped) - timestampLen
+		value, ts = stamped[:n], stamped[n:]
+	} else {
+		ts, value = stamped[:timestampLen], stamped[timestampLen:]
+	}
+	return value, time.Unix(int64(binary.BigEndian.Uint64(ts)), 0), true
+}
+
+// Expired reports whether a value made by Stamp is older than the TTL.
+func (f *TTLFilter) Expired(stamped []byte) bool {
+	_, t, ok := f.Split(stamped)
+	return ok && f.now().Sub(t) > f.TTL
+}
+
+func (f *TTLFilter) now() time.Time {
+	if f.Clock != nil {
+		return f.Clock()
+	}
+	return time.Now()
+}
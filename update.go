@@ -0,0 +1,141 @@
+package gorocks
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrUpdateConflict is returned by DB.Update when the key kept changing
+// underneath it until the RetryPolicy was exhausted.
+var ErrUpdateConflict = DatabaseError("update conflict: retries exhausted")
+
+// updateLockStripes is the number of mutexes DB.Update spreads keys over.
+const updateLockStripes = 64
+
+type updateLocks [updateLockStripes]sync.Mutex
+
+func (l *updateLocks) forKey(key []byte) *sync.Mutex {
+	return &l[stripe(key)]
+}
+
+// lockKeys locks the stripes of all the given keys, in stripe order so that
+// concurrent callers cannot deadlock, and returns a function unlocking them.
+func (l *updateLocks) lockKeys(keys [][]byte) (unlock func()) {
+	var held [updateLockStripes]bool
+	for _, k := range keys {
+		held[stripe(k)] = true
+	}
+	for i := range held {
+		if held[i] {
+			l[i].Lock()
+		}
+	}
+	return func() {
+		for i := range held {
+			if held[i] {
+				l[i].Unlock()
+			}
+		}
+	}
+}
+
+func stripe(key []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(key)
+	return h.Sum32() % updateLockStripes
+}
+
+// RetryPolicy controls how often and how patiently an operation that lost a
+// race with a concurrent writer is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It doubles after every
+	// further attempt, up to MaxBackoff, or without limit if MaxBackoff is
+	// zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by DB.Update.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 10,
+	Backoff:     time.Millisecond,
+	MaxBackoff:  100 * time.Millisecond,
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || d < p.MaxBackoff) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Update performs a read-modify-write of a single key using
+// DefaultRetryPolicy. See UpdateWithRetry.
+//
+// Update is not built on a transaction: the database is not opened as a
+// TransactionDB, so GetForUpdate is not available, and it serializes
+// itself with a lock in this process instead. Only other Update calls
+// through the same *DB are excluded; writes through another handle, or
+// plain Put, Delete and Write calls, can still be lost. Use
+// OptimisticTransactionDB and GetForUpdate where those race with it.
+func (db *DB) Update(ro *ReadOptions, wo *WriteOptions, key []byte, fn func(old []byte) ([]byte, error)) error {
+	return db.UpdateWithRetry(ro, wo, key, DefaultRetryPolicy, fn)
+}
+
+// UpdateWithRetry reads the value of key, passes it to fn and writes back
+// the value fn returns. A nil old value means the key does not exist, and
+// returning a nil value from fn deletes the key. If fn returns an error,
+// nothing is written and the error is returned.
+//
+// fn runs without any lock held. Before writing, the key is read again and,
+// if it no longer holds the value fn was given, fn is run again on the new
+// value after a backoff. ErrUpdateConflict is returned once the policy's
+// attempts are used up, so fn must be safe to call more than once.
+//
+// The check and the write are atomic with respect to other Update calls on
+// the same DB handle. ro must not carry a Snapshot, or the re-read will never
+// observe concurrent writes. Plain Put, Delete and Write calls racing with Update
+// are not excluded; use a transaction when those must be serialized too.
+func (db *DB) UpdateWithRetry(ro *ReadOptions, wo *WriteOptions, key []byte, policy RetryPolicy, fn func(old []byte) ([]byte, error)) error {
+	mu := db.updateLocks.forKey(key)
+	for attempt := 1; ; attempt++ {
+		old, err := db.Get(ro, key)
+		if err != nil {
+			return err
+		}
+		value, err := fn(old)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		cur, err := db.Get(ro, key)
+		if err == nil && (cur == nil) == (old == nil) && bytes.Equal(cur, old) {
+			if value == nil {
+				err = db.Delete(wo, key)
+			} else {
+				err = db.Put(wo, key, value)
+			}
+			mu.Unlock()
+			return err
+		}
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if attempt >= policy.MaxAttempts {
+			return ErrUpdateConflict
+		}
+		time.Sleep(policy.backoff(attempt))
+	}
+}
@@ -0,0 +1,211 @@
+package gorocks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SstFileReport is the result of verifying one SST file with
+// VerifySstFiles.
+type SstFileReport struct {
+	Path string
+
+	// Entries and Deletions are the counts recorded in the file's table
+	// properties, and Keys the number of live keys read back from it.
+	Entries   uint64
+	Deletions uint64
+	Keys      uint64
+
+	// SmallestKey and LargestKey are the key range of the file.
+	SmallestKey []byte
+	LargestKey  []byte
+
+	// Problems describes everything found wrong with the file. The file
+	// passed verification if it is empty.
+	Problems []string
+}
+
+// OK reports whether the file passed verification.
+func (r *SstFileReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *SstFileReport) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// VerifySstOptions configures VerifySstFiles.
+type VerifySstOptions struct {
+	// Compare is the order keys must be in, which must agree with the
+	// comparator of the Options. It defaults to bytes.Compare.
+	Compare func(a, b []byte) int
+
+	// TempDir is where the scratch databases are made. It defaults to
+	// os.TempDir().
+	TempDir string
+}
+
+// VerifySstFiles checks SST files produced outside the database, typically
+// by external jobs using SstFileWriter, before they are ingested. RocksDB's
+// C API has no SST file reader, so each file is copied into a scratch
+// database opened with a copy of o, which must be the Options of the
+// database the files are meant for, and read back from there with checksum
+// verification. The reports, in the order of paths, cover:
+//
+//   - that RocksDB accepts the file for ingestion with these Options,
+//   - block checksums,
+//   - that keys are in strictly increasing order,
+//   - that the key count and range agree with the table properties,
+//   - that the files do not overlap each other.
+//
+// The returned error is only for failures to run the checks, such as being
+// unable to create a scratch database; problems with the files are in the
+// reports.
+func VerifySstFiles(o *Options, paths []string, opts VerifySstOptions) ([]SstFileReport, error) {
+	if opts.Compare == nil {
+		opts.Compare = bytes.Compare
+	}
+	tmp, err := os.MkdirTemp(opts.TempDir, "gorocks-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	reports := make([]SstFileReport, len(paths))
+	for i, p := range paths {
+		reports[i].Path = p
+		if err := verifySstFile(o, filepath.Join(tmp, fmt.Sprint(i)), &reports[i], opts.Compare); err != nil {
+			return nil, err
+		}
+	}
+	checkSstOverlaps(reports, opts.Compare)
+	return reports, nil
+}
+
+func verifySstFile(o *Options, dir string, r *SstFileReport, compare func(a, b []byte) int) error {
+	so := o.Clone()
+	defer so.Close()
+	so.SetCreateIfMissing(true)
+	so.SetErrorIfExists(true)
+	db, err := Open(dir, so)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}()
+
+	ingestOpts := NewIngestExternalFileOptions()
+	defer ingestOpts.Close()
+	if err := db.IngestExternalFile([]string{r.Path}, ingestOpts); err != nil {
+		r.problem("ingest: %v", err)
+		return nil
+	}
+	files := db.LiveFiles()
+	if len(files) != 1 {
+		r.problem("ingest produced %d files, want 1", len(files))
+		return nil
+	}
+	f := files[0]
+	r.Entries, r.Deletions = f.Entries, f.Deletions
+	r.SmallestKey, r.LargestKey = f.SmallestKey, f.LargestKey
+	if f.Entries == 0 {
+		r.problem("file has no entries")
+	}
+	if f.Deletions > f.Entries {
+		r.problem("%d deletions out of %d entries", f.Deletions, f.Entries)
+	}
+	if compare(f.SmallestKey, f.LargestKey) > 0 {
+		r.problem("smallest key %q sorts after largest key %q", f.SmallestKey, f.LargestKey)
+	}
+
+	ro := NewReadOptions()
+	defer ro.Close()
+	ro.SetVerifyChecksums(true)
+	ro.SetFillCache(false)
+	it := db.NewIterator(ro)
+	defer it.Close()
+	var prev []byte
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		k := it.Key()
+		if r.Keys == 0 && compare(k, f.SmallestKey) < 0 {
+			r.problem("key %q sorts before the smallest key %q", k, f.SmallestKey)
+		}
+		if r.Keys > 0 && compare(prev, k) >= 0 {
+			r.problem("key %q is out of order after %q", k, prev)
+			break
+		}
+		prev = k
+		r.Keys++
+	}
+	if err := it.GetError(); err != nil {
+		r.problem("read: %v", err)
+		return nil
+	}
+	if prev != nil && compare(prev, f.LargestKey) > 0 {
+		r.problem("key %q sorts after the largest key %q", prev, f.LargestKey)
+	}
+	if r.OK() && r.Keys != f.Entries-f.Deletions {
+		r.problem("read %d keys, table properties record %d entries and %d deletions", r.Keys, f.Entries, f.Deletions)
+	}
+	return nil
+}
+
+// checkSstOverlaps records a problem on each pair of files with
+// overlapping key ranges, which cannot be ingested together.
+func checkSstOverlaps(reports []SstFileReport, compare func(a, b []byte) int) {
+	for i := range reports {
+		a := &reports[i]
+		if a.SmallestKey == nil {
+			continue
+		}
+		for j := i + 1; j < len(reports); j++ {
+			b := &reports[j]
+			if b.SmallestKey == nil {
+				continue
+			}
+			if compare(a.SmallestKey, b.LargestKey) <= 0 && compare(b.SmallestKey, a.LargestKey) <= 0 {
+				a.problem("key range overlaps %s", b.Path)
+				b.problem("key range overlaps %s", a.Path)
+			}
+		}
+	}
+}
+
+// SstVerificationError is returned by DB.VerifyAndIngestExternalFile when
+// some of the files failed verification.
+type SstVerificationError struct {
+	Reports []SstFileReport
+}
+
+func (e *SstVerificationError) Error() string {
+	var failed []string
+	for _, r := range e.Reports {
+		if !r.OK() {
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Path, strings.Join(r.Problems, "; ")))
+		}
+	}
+	return "gorocks: SST files failed verification: " + strings.Join(failed, ", ")
+}
+
+// VerifyAndIngestExternalFile verifies the files with VerifySstFiles, using
+// o, which must be the Options the database was opened with, and ingests
+// them with IngestExternalFile if they all pass. Otherwise it returns an
+// *SstVerificationError and ingests nothing. The reports are returned
+// either way, unless the checks could not be run.
+func (db *DB) VerifyAndIngestExternalFile(o *Options, paths []string, ingestOpts *IngestExternalFileOptions, opts VerifySstOptions) ([]SstFileReport, error) {
+	reports, err := VerifySstFiles(o, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reports {
+		if !r.OK() {
+			return reports, &SstVerificationError{Reports: reports}
+		}
+	}
+	return reports, db.IngestExternalFile(paths, ingestOpts)
+}
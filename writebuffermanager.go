@@ -0,0 +1,39 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+// WriteBufferManager caps the total memory used by the memtables of all the
+// databases and column families whose Options it is set on.
+//
+// To prevent memory leaks, a WriteBufferManager must have Close called on it
+// when it is no longer needed by the program.
+type WriteBufferManager struct {
+	Manager *C.rocksdb_write_buffer_manager_t
+}
+
+// NewWriteBufferManager creates a WriteBufferManager limiting memtable
+// memory to bufferSize bytes. If allowStall is true, writes are stalled
+// once the limit is exceeded until flushes catch up; otherwise the limit
+// only triggers flushes.
+func NewWriteBufferManager(bufferSize int, allowStall bool) *WriteBufferManager {
+	wbm := C.rocksdb_write_buffer_manager_create(C.size_t(bufferSize), boolToUchar(allowStall))
+	return &WriteBufferManager{wbm}
+}
+
+// MemoryUsage returns the number of bytes currently used by the memtables
+// under this manager.
+func (m *WriteBufferManager) MemoryUsage() int {
+	return int(C.rocksdb_write_buffer_manager_memory_usage(m.Manager))
+}
+
+// BufferSize returns the limit the manager was created with.
+func (m *WriteBufferManager) BufferSize() int {
+	return int(C.rocksdb_write_buffer_manager_buffer_size(m.Manager))
+}
+
+// Close deallocates the WriteBufferManager. Databases using it keep their
+// own reference.
+func (m *WriteBufferManager) Close() {
+	C.rocksdb_write_buffer_manager_destroy(m.Manager)
+}
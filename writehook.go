@@ -0,0 +1,62 @@
+package gorocks
+
+// #include "rocksdb/c.h"
+import "C"
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WriteHook is called after a write made through a DB handle has been
+// committed. seq is the sequence number assigned to the last record of the
+// batch; earlier records of the batch occupy the sequence numbers just
+// before it.
+//
+// The batch is only valid for the duration of the call and must not be
+// modified. WriteBatch.NewIterator may be used to inspect its contents.
+type WriteHook func(seq uint64, batch *WriteBatch)
+
+type writeHookState struct {
+	// mu serializes writes while a hook is installed, so that hooks are
+	// called in commit order and seq can be read without racing another
+	// writer on this handle.
+	mu   sync.Mutex
+	hook atomic.Value // of writeHookHolder
+}
+
+type writeHookHolder struct {
+	hook WriteHook
+}
+
+func (s *writeHookState) load() WriteHook {
+	h, _ := s.hook.Load().(writeHookHolder)
+	return h.hook
+}
+
+// SetWriteHook installs hook to be called after every Put, Merge, Delete,
+// DeleteRange and Write made through this DB handle, and their column
+// family variants, in commit order. Passing nil removes the hook. It is
+// useful for in-process subscribers such as cache invalidation or index
+// maintenance that must observe writes in order without tailing the write
+// ahead log.
+//
+// While a hook is installed, writes through this handle are serialized and
+// single-key writes are turned into single-entry WriteBatches. Writes made by
+// other handles or processes are not seen by the hook, and a slow hook slows
+// down every writer.
+func (db *DB) SetWriteHook(hook WriteHook) {
+	db.writeHook.hook.Store(writeHookHolder{hook})
+}
+
+// writeHooked writes w and calls hook with the committed sequence number.
+func (db *DB) writeHooked(hook WriteHook, wo *WriteOptions, w *WriteBatch) error {
+	db.writeHook.mu.Lock()
+	defer db.writeHook.mu.Unlock()
+	if err := db.write(wo, w); err != nil {
+		return err
+	}
+	seq := uint64(C.rocksdb_get_latest_sequence_number(db.Ldb))
+	hook(seq, w)
+	return nil
+}
@@ -0,0 +1,89 @@
+package gorocks
+
+import (
+	"strconv"
+	"sync"
+)
+
+// WritePressure describes how close the default column family is to
+// running out of memtable space, at which point RocksDB stalls writes
+// until a flush completes.
+type WritePressure struct {
+	// ActiveMemtableBytes is the size of the memtable being written to,
+	// and WriteBufferSize the size at which it is switched out for flushing.
+	ActiveMemtableBytes uint64
+	WriteBufferSize     uint64
+	// ImmutableMemtables is the number of full memtables waiting to be
+	// flushed, and MaxWriteBuffers the number of memtables, including the
+	// active one, at which writes stop.
+	ImmutableMemtables uint64
+	MaxWriteBuffers    uint64
+	// Stopped is set while RocksDB is stopping writes for any reason.
+	Stopped bool
+}
+
+// Level returns the fraction of the memtable budget in use, from 0 when
+// the memtables are empty up to 1 when writes stop. It is meant for
+// application-level admission control, which should start slowing down
+// producers well before 1 is reached.
+func (p WritePressure) Level() float64 {
+	if p.Stopped {
+		return 1
+	}
+	if p.WriteBufferSize == 0 || p.MaxWriteBuffers == 0 {
+		return 0
+	}
+	active := float64(p.ActiveMemtableBytes) / float64(p.WriteBufferSize)
+	if active > 1 {
+		active = 1
+	}
+	level := (float64(p.ImmutableMemtables) + active) / float64(p.MaxWriteBuffers)
+	if level > 1 {
+		level = 1
+	}
+	return level
+}
+
+// memtableLimits caches the memtable options of the default column family,
+// read from the OPTIONS file, until they are changed with SetOptions.
+type memtableLimits struct {
+	mu                               sync.Mutex
+	loaded                           bool
+	writeBufferSize, maxWriteBuffers uint64
+}
+
+func (l *memtableLimits) reset() {
+	l.mu.Lock()
+	l.loaded = false
+	l.mu.Unlock()
+}
+
+func (l *memtableLimits) get(db *DB) (writeBufferSize, maxWriteBuffers uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.loaded {
+		contents, err := db.OptionsFile()
+		if err != nil {
+			return 0, 0
+		}
+		opts := parseOptionsFile(contents)
+		const section = `CFOptions "default"` + "\t"
+		l.writeBufferSize, _ = strconv.ParseUint(opts[section+"write_buffer_size"], 10, 64)
+		l.maxWriteBuffers, _ = strconv.ParseUint(opts[section+"max_write_buffer_number"], 10, 64)
+		l.loaded = true
+	}
+	return l.writeBufferSize, l.maxWriteBuffers
+}
+
+// WritePressure reports how full the memtables of the default column
+// family are. It only reads a few integer properties, and is cheap enough
+// to call before every write.
+func (db *DB) WritePressure() WritePressure {
+	var p WritePressure
+	p.WriteBufferSize, p.MaxWriteBuffers = db.memtableLimits.get(db)
+	p.ActiveMemtableBytes, _ = db.IntPropertyValue("rocksdb.cur-size-active-mem-table")
+	p.ImmutableMemtables, _ = db.IntPropertyValue("rocksdb.num-immutable-mem-table")
+	stopped, _ := db.IntPropertyValue("rocksdb.is-write-stopped")
+	p.Stopped = stopped != 0
+	return p
+}